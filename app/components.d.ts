@@ -24,6 +24,7 @@ declare module 'vue' {
     RichTextInput: typeof import('./src/pages/playground/components/ContentInput/RichTextInput.vue')['default']
     RouterLink: typeof import('vue-router')['RouterLink']
     RouterView: typeof import('vue-router')['RouterView']
+    SessionsDrawer: typeof import('./src/pages/playground/components/SessionsDrawer.vue')['default']
     TaskStatusWidget: typeof import('./src/pages/playground/components/TaskStatusWidget.vue')['default']
     TextPart: typeof import('./src/pages/playground/components/MessageParts/TextPart.vue')['default']
   }
@@ -3,9 +3,10 @@ import type { Interceptor } from '@connectrpc/connect'
 import { createConnectTransport } from '@connectrpc/connect-web'
 import { A2AService } from '@local/a2a-js'
 import { useAgentHeadersStore } from '@/store/agentHeaders'
+import { basePath } from '@/utils/basePath'
 
-// When empty, use same origin (BFF). Set VITE_API_URL for a custom API base.
-const baseUrl = import.meta.env.VITE_API_URL ?? ''
+// By default, the BFF that served the SPA. Set VITE_API_URL for a custom API base.
+const baseUrl = import.meta.env.VITE_API_URL ?? basePath
 
 const agentHeadersInterceptor: Interceptor = (next) => (req) => {
   const store = useAgentHeadersStore()
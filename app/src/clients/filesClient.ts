@@ -0,0 +1,46 @@
+import { basePath } from '@/utils/basePath'
+
+// By default, the BFF that served the SPA. Set VITE_API_URL for a custom API base.
+const baseUrl = import.meta.env.VITE_API_URL ?? basePath
+
+/** Prefix of the URI of an uploaded file, as in "playground-file:<id>". */
+export const FILE_REF_SCHEME = 'playground-file:'
+
+/** An uploaded file, as returned by POST /api/files. */
+export interface StoredFile {
+  id: string
+  name: string
+  mimeType: string
+  size: number
+  createdAt: string
+  /** The reference to send as a part's fileWithUri; the BFF resolves it for the agent. */
+  uri: string
+}
+
+/** Thrown when the BFF runs without a file store: POST /api/files then is not routed. */
+export class UploadsDisabledError extends Error {
+  constructor() {
+    super('File uploads are not enabled on this playground')
+  }
+}
+
+/** Uploads a file to the BFF, which keeps it for messages to reference by its uri. */
+export async function uploadFile(file: Blob, name: string): Promise<StoredFile> {
+  const form = new FormData()
+  form.append('file', file, name)
+  const res = await fetch(`${baseUrl}/api/files`, { method: 'POST', body: form })
+  const isJson = res.headers.get('Content-Type')?.startsWith('application/json') ?? false
+  if (res.status === 404 || res.status === 405 || (res.ok && !isJson)) {
+    throw new UploadsDisabledError()
+  }
+  const body = await res.json().catch(() => ({}))
+  if (!res.ok) {
+    throw new Error(body.error || `${res.status} ${res.statusText}`)
+  }
+  return body.files[0]
+}
+
+/** Returns the URL an uploaded-file reference is served at, or uri itself if it is not one. */
+export function fileRefUrl(uri: string): string {
+  return uri.startsWith(FILE_REF_SCHEME) ? `${baseUrl}/api/files/${encodeURIComponent(uri.slice(FILE_REF_SCHEME.length))}` : uri
+}
@@ -0,0 +1,74 @@
+import type { JsonValue } from '@bufbuild/protobuf'
+import { basePath } from '@/utils/basePath'
+
+// By default, the BFF that served the SPA. Set VITE_API_URL for a custom API base.
+const baseUrl = import.meta.env.VITE_API_URL ?? basePath
+
+/** A recorded conversation, as listed by GET /api/sessions. */
+export interface SessionSummary {
+  contextId: string
+  title: string
+  agentUrl?: string
+  createdAt: string
+  updatedAt: string
+  records: number
+}
+
+/** One recorded message, response, stream event, task snapshot or call. */
+export interface SessionRecord {
+  seq: number
+  time: string
+  kind: 'message' | 'response' | 'event' | 'task' | 'call'
+  procedure?: string
+  callId?: string
+  data: JsonValue
+}
+
+export interface SessionDetail {
+  session: SessionSummary
+  records: SessionRecord[]
+}
+
+/** Thrown when the BFF runs with --no-sessions: /api/sessions then falls through to the SPA. */
+export class SessionsDisabledError extends Error {
+  constructor() {
+    super('Sessions are not recorded by this playground')
+  }
+}
+
+async function request<T>(path: string, init?: RequestInit): Promise<T> {
+  const res = await fetch(`${baseUrl}/api/sessions${path}`, init)
+  const isJson = res.headers.get('Content-Type')?.startsWith('application/json') ?? false
+  if (!path.startsWith('/') && (res.status === 404 || (res.ok && !isJson))) {
+    throw new SessionsDisabledError()
+  }
+  if (!res.ok) {
+    const body = await res.json().catch(() => ({}))
+    throw new Error(body.error || `${res.status} ${res.statusText}`)
+  }
+  return res.status === 204 ? (undefined as T) : res.json()
+}
+
+/** Lists sessions, newest first; query searches titles, message text and metadata. */
+export async function listSessions(query = ''): Promise<SessionSummary[]> {
+  const q = query.trim() ? `?q=${encodeURIComponent(query.trim())}` : ''
+  const { sessions } = await request<{ sessions: SessionSummary[] }>(q)
+  return sessions
+}
+
+/** Gets a session with all of its records, to restore it into the playground. */
+export function getSession(contextId: string): Promise<SessionDetail> {
+  return request(`/${encodeURIComponent(contextId)}`)
+}
+
+export function renameSession(contextId: string, title: string): Promise<SessionSummary> {
+  return request(`/${encodeURIComponent(contextId)}`, {
+    method: 'PATCH',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ title }),
+  })
+}
+
+export function deleteSession(contextId: string): Promise<void> {
+  return request(`/${encodeURIComponent(contextId)}`, { method: 'DELETE' })
+}
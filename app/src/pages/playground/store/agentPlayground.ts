@@ -10,6 +10,7 @@ export type DetailsDrawerMode = 'message'
 export const useAgentPlaygroundStore = defineStore(STORE_ID, () => {
   const detailsDrawerOpen: Ref<boolean> = ref(false)
   const headersDrawerOpen: Ref<boolean> = ref(false)
+  const sessionsDrawerOpen: Ref<boolean> = ref(false)
   const detailsDrawerMode: Ref<DetailsDrawerMode> = ref('message')
   const detailsDrawerMessage: Ref<ConversationMessage | null> = ref(null)
 
@@ -21,6 +22,10 @@ export const useAgentPlaygroundStore = defineStore(STORE_ID, () => {
     headersDrawerOpen.value = !headersDrawerOpen.value
   }
 
+  const toggleSessionsDrawer = () => {
+    sessionsDrawerOpen.value = !sessionsDrawerOpen.value
+  }
+
   const openDetailsDrawer = () => {
     detailsDrawerOpen.value = true
   }
@@ -48,10 +53,12 @@ export const useAgentPlaygroundStore = defineStore(STORE_ID, () => {
   return {
     detailsDrawerOpen,
     headersDrawerOpen,
+    sessionsDrawerOpen,
     detailsDrawerMode,
     detailsDrawerMessage,
     toggleDetailsDrawer,
     toggleHeadersDrawer,
+    toggleSessionsDrawer,
     openDetailsDrawer,
     closeDetailsDrawer,
     setDetailsDrawerMessage,
@@ -1,4 +1,6 @@
 import { createA2AClient } from '@/clients/a2aClient'
+import { uploadFile, UploadsDisabledError } from '@/clients/filesClient'
+import type { SessionRecord } from '@/clients/sessionsClient'
 import {
   DataPartSchema,
   FilePartSchema,
@@ -6,6 +8,9 @@ import {
   PartSchema,
   Role,
   SendMessageRequestSchema,
+  SendMessageResponseSchema,
+  StreamResponseSchema,
+  TaskSchema,
   TaskState,
   type Message,
   type Part,
@@ -42,16 +47,25 @@ function stringToUtf8Bytes(str: string): Uint8Array {
   return new TextEncoder().encode(str)
 }
 
-async function fileToBase64Bytes(file: File): Promise<Uint8Array> {
-  const rawBytes = new Uint8Array(await file.arrayBuffer())
-  return stringToUtf8Bytes(bytesToBase64(rawBytes))
-}
-
 async function blobToBase64Bytes(blob: Blob): Promise<Uint8Array> {
   const rawBytes = new Uint8Array(await blob.arrayBuffer())
   return stringToUtf8Bytes(bytesToBase64(rawBytes))
 }
 
+// Helper: upload a file to the BFF and reference it by URI, so large files never travel
+// inline. The BFF inlines or links it for the agent (see --file-parts). Without uploads,
+// the file is inlined as UTF-8 bytes of a base64 string, as BFF/JSON-RPC expects.
+async function uploadedFilePart(blob: Blob, name: string, mimeType: string) {
+  try {
+    const stored = await uploadFile(blob, name)
+    return create(FilePartSchema, { file: { case: 'fileWithUri', value: stored.uri }, mimeType, name })
+  } catch (error) {
+    if (!(error instanceof UploadsDisabledError)) throw error
+  }
+  const fileBytes = await blobToBase64Bytes(blob)
+  return create(FilePartSchema, { file: { case: 'fileWithBytes', value: fileBytes }, mimeType, name })
+}
+
 function getStateText(state: TaskState): string {
   switch (state) {
     case TaskState.SUBMITTED:
@@ -145,6 +159,36 @@ export function streamResponseToAgentMessage(response: StreamResponse): UnifiedM
   }
 }
 
+/** Converts a recorded session record back into what the conversation shows. Calls are skipped. */
+export function sessionRecordToUnifiedMessage(record: SessionRecord): UnifiedMessage | null {
+  const options = { ignoreUnknownFields: true }
+  const timestamp = Date.parse(record.time) || Date.now()
+  let unified: UnifiedMessage | null = null
+  switch (record.kind) {
+    case 'message': {
+      const msg = fromJson(MessageSchema, record.data, options)
+      unified = { type: 'agent_message', agentMessage: msg, timestamp, messageId: msg.messageId || uuidv7() }
+      break
+    }
+    case 'event':
+      unified = streamResponseToAgentMessage(fromJson(StreamResponseSchema, record.data, options))
+      break
+    case 'response': {
+      const payload = fromJson(SendMessageResponseSchema, record.data, options).payload
+      if (payload.case === 'msg') {
+        unified = { type: 'agent_message', agentMessage: payload.value, timestamp, messageId: payload.value.messageId || uuidv7() }
+      } else if (payload.case === 'task') {
+        unified = { type: 'task', task: payload.value, timestamp, messageId: uuidv7() }
+      }
+      break
+    }
+    case 'task':
+      unified = { type: 'task', task: fromJson(TaskSchema, record.data, options), timestamp, messageId: uuidv7() }
+      break
+  }
+  return unified ? { ...unified, timestamp } : null
+}
+
 export async function userMessageToAgentMessage(
   parts: MessagePart[],
   messageId?: string,
@@ -341,6 +385,7 @@ interface MessagesStoreReturn {
   clearMessages: () => void
   getOrCreateSessionContextId: () => string
   clearSessionContextId: () => void
+  restoreSession: (contextId: string, records: SessionRecord[]) => void
 }
 
 export const useMessagesStore = defineStore(STORE_ID, (): MessagesStoreReturn => {
@@ -382,6 +427,19 @@ export const useMessagesStore = defineStore(STORE_ID, (): MessagesStoreReturn =>
     messages.value = []
   }
 
+  /** Replaces the conversation with a recorded session and continues it in the same context. */
+  const restoreSession = (contextId: string, records: SessionRecord[]) => {
+    const restored: UnifiedMessage[] = []
+    for (const record of records) {
+      const unified = sessionRecordToUnifiedMessage(record)
+      if (unified && !restored.some((m) => m.messageId === unified.messageId)) {
+        restored.push(unified)
+      }
+    }
+    messages.value = restored
+    sessionContextId.value = contextId
+  }
+
   const sendStreamingMessage = async (
     parts: MessagePart[],
     messageId: string,
@@ -396,22 +454,10 @@ export const useMessagesStore = defineStore(STORE_ID, (): MessagesStoreReturn =>
       if (part instanceof TextMessagePart) {
         agentParts.push(create(PartSchema, { part: { case: 'text', value: part.content } }))
       } else if (part instanceof FileMessagePart) {
-        // BFF/JSON-RPC expects UTF-8 bytes of base64 string (see Agent sendStreamingMessage)
-        const fileBytes = await fileToBase64Bytes(part.file)
-        const filePart = create(FilePartSchema, {
-          file: { case: 'fileWithBytes', value: fileBytes },
-          mimeType: part.mimeType || '',
-          name: part.fileName || '',
-        })
+        const filePart = await uploadedFilePart(part.file, part.fileName || '', part.mimeType || '')
         agentParts.push(create(PartSchema, { part: { case: 'file', value: filePart } }))
       } else if (part instanceof AudioMessagePart) {
-        // BFF/JSON-RPC expects UTF-8 bytes of base64 string (see Agent sendStreamingMessage)
-        const fileBytes = await blobToBase64Bytes(part.audioBlob)
-        const filePart = create(FilePartSchema, {
-          file: { case: 'fileWithBytes', value: fileBytes },
-          mimeType: part.mimeType || 'audio/mp4',
-          name: part.fileName || 'recording.mp4',
-        })
+        const filePart = await uploadedFilePart(part.audioBlob, part.fileName || 'recording.mp4', part.mimeType || 'audio/mp4')
         agentParts.push(create(PartSchema, { part: { case: 'file', value: filePart } }))
       }
     }
@@ -523,5 +569,6 @@ export const useMessagesStore = defineStore(STORE_ID, (): MessagesStoreReturn =>
     clearMessages,
     getOrCreateSessionContextId,
     clearSessionContextId,
+    restoreSession,
   }
 })
@@ -1,7 +1,8 @@
 import { createRouter, createWebHistory } from 'vue-router'
+import { basePath } from '@/utils/basePath'
 
 const router = createRouter({
-  history: createWebHistory(import.meta.env.BASE_URL),
+  history: createWebHistory(`${basePath}/`),
   routes: [
     {
       path: '/',
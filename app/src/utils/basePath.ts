@@ -0,0 +1,5 @@
+/**
+ * The path the playground is served under, such as "/playground", or "" at the root.
+ * The BFF puts it in the <base> element of index.html; the Vite dev server adds none.
+ */
+export const basePath = (document.querySelector('base')?.getAttribute('href') ?? '/').replace(/\/$/, '')
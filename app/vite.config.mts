@@ -11,6 +11,8 @@ import { defineConfig } from 'vite'
 
 // https://vitejs.dev/config/
 export default defineConfig({
+  // Relative, so the BFF can serve the build under any base path; see src/utils/basePath.ts.
+  base: './',
   plugins: [
     Vue({
       template: { transformAssetUrls },
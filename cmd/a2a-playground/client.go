@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/a2aproject/a2a-go/a2a"
+	"github.com/a2aproject/a2a-go/a2apb"
+	"github.com/a2aproject/a2a-go/a2apb/pbconv"
+	"github.com/alis-exchange/a2a-playground/internal/bff"
+	"github.com/spf13/cobra"
+)
+
+var (
+	agentHeaders []string
+	outputFormat string
+)
+
+// addClientFlags registers the flags shared by subcommands that call the agent directly.
+func addClientFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringArrayVarP(&agentHeaders, "header", "H", nil, "Header to forward to the agent as key=value (repeatable)")
+	cmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "table", "Output format: table, json or yaml")
+}
+
+// newAgentClient returns an in-process client for the configured agent. Calls go through
+// the same proxy as the browser, so headers and transport handling are identical.
+func newAgentClient() (*bff.LocalClient, error) {
+	agent, err := agentConfig()
+	if err != nil {
+		return nil, err
+	}
+	headers, err := parseHeaders(agentHeaders)
+	if err != nil {
+		return nil, err
+	}
+	return bff.NewLocalClient(bff.NewProxy(agent), headers)
+}
+
+// parseHeaders parses key=value pairs into a header map.
+func parseHeaders(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	headers := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		k, v, ok := strings.Cut(pair, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid header %q: expected key=value", pair)
+		}
+		headers[k] = v
+	}
+	return headers, nil
+}
+
+// taskName accepts a bare task ID or a "tasks/{id}" name and returns the resource name.
+func taskName(id string) string {
+	if strings.HasPrefix(id, "tasks/") {
+		return id
+	}
+	return pbconv.MakeTaskName(a2a.TaskID(id))
+}
+
+// pushConfigName returns the "tasks/{id}/pushNotificationConfigs/{config}" resource name.
+func pushConfigName(taskID, configID string) string {
+	return taskName(taskID) + "/pushNotificationConfigs/" + configID
+}
+
+// stateLabel returns the short form of a task state, e.g. "input-required".
+func stateLabel(state a2apb.TaskState) string {
+	name := strings.TrimPrefix(state.String(), "TASK_STATE_")
+	return strings.ReplaceAll(strings.ToLower(name), "_", "-")
+}
+
+// isTerminalState reports whether no further updates are expected for a task in this state.
+func isTerminalState(state a2apb.TaskState) bool {
+	switch state {
+	case a2apb.TaskState_TASK_STATE_COMPLETED,
+		a2apb.TaskState_TASK_STATE_FAILED,
+		a2apb.TaskState_TASK_STATE_CANCELLED,
+		a2apb.TaskState_TASK_STATE_REJECTED:
+		return true
+	}
+	return false
+}
+
+// isInterruptedState reports whether a task in this state is waiting on the client.
+func isInterruptedState(state a2apb.TaskState) bool {
+	return state == a2apb.TaskState_TASK_STATE_INPUT_REQUIRED || state == a2apb.TaskState_TASK_STATE_AUTH_REQUIRED
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/a2aproject/a2a-go/a2apb"
+	"github.com/alis-exchange/a2a-playground/internal/bff"
+	"github.com/spf13/cobra"
+)
+
+var (
+	compareStreaming bool
+	compareTolerance string
+	compareContextID string
+	compareTimeout   time.Duration
+)
+
+var compareCmd = &cobra.Command{
+	Use:   "compare <text>",
+	Short: "Send one message over gRPC and JSON-RPC at once and diff the responses",
+	Long: `Compare sends the same SendMessageRequest to the agent's gRPC and JSON-RPC
+bindings simultaneously, through the same proxies as the UI, and shows a
+structural diff of the final results and of the event sequences. IDs and
+timestamps are ignored. --agent-url (with --jsonrpc when it is the JSON-RPC
+endpoint) names one binding and --compare-url the other.
+
+The command exits non-zero when the bindings are not equivalent.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runCompare,
+}
+
+func init() {
+	addClientFlags(compareCmd)
+	compareCmd.Flags().StringVar(&compareURL, "compare-url", "", "The agent's endpoint on the other transport (required)")
+	compareCmd.Flags().BoolVar(&compareStreaming, "streaming", true, "Use SendStreamingMessage and compare event sequences; false for SendMessage")
+	compareCmd.Flags().StringVar(&compareTolerance, "tolerance", string(bff.ToleranceNormalized), "Comparison: exact, normalized or structure")
+	compareCmd.Flags().StringVar(&compareContextID, "context-id", "", "Context ID for the message (default: chosen by the agent)")
+	compareCmd.Flags().DurationVar(&compareTimeout, "timeout", 2*time.Minute, "Timeout for the comparison")
+	rootCmd.AddCommand(compareCmd)
+}
+
+// runCompare runs one cross-transport comparison and prints the result.
+func runCompare(cmd *cobra.Command, args []string) error {
+	if err := validateOutputFormat(); err != nil {
+		return err
+	}
+	if compareURL == "" {
+		return errors.New("--compare-url is required")
+	}
+	tol, err := bff.ParseTolerance(compareTolerance)
+	if err != nil {
+		return err
+	}
+	grpcURL, jsonrpcURL, err := compareAgents()
+	if err != nil {
+		return err
+	}
+	headers, err := parseHeaders(agentHeaders)
+	if err != nil {
+		return err
+	}
+	// --agent-url keeps its --a2a-version and --agent-card-url; the other side speaks A2A 0.3.
+	agent, err := agentConfig()
+	if err != nil {
+		return err
+	}
+	self := bff.ComparedBinding{URL: agent.URL, Proxy: bff.NewProxy(agent)}
+	grpc, jsonrpc := self, bff.ComparedBinding{URL: jsonrpcURL}
+	if agent.Protocol == bff.ProtocolJSONRPC {
+		grpc, jsonrpc = bff.ComparedBinding{URL: grpcURL}, self
+	}
+	for _, side := range []*bff.ComparedBinding{&grpc, &jsonrpc} {
+		if side.Proxy == nil {
+			side.Proxy = bff.NewProxy(agent.OtherTransport(side.URL))
+		}
+	}
+	comparer, err := bff.NewTransportComparer(grpc, jsonrpc)
+	if err != nil {
+		return err
+	}
+	defer comparer.Close()
+
+	ctx, cancel := context.WithTimeout(bff.WithAgentHeaders(cmd.Context(), headers), compareTimeout)
+	defer cancel()
+	req := &a2apb.SendMessageRequest{
+		Request: &a2apb.Message{
+			ContextId: compareContextID,
+			Role:      a2apb.Role_ROLE_USER,
+			Parts:     []*a2apb.Part{{Part: &a2apb.Part_Text{Text: args[0]}}},
+		},
+	}
+	if !compareStreaming {
+		req.Configuration = &a2apb.SendMessageConfiguration{Blocking: true}
+	}
+	result, err := comparer.Compare(ctx, req, compareStreaming, tol)
+	if err != nil {
+		return err
+	}
+	if err := printComparison(os.Stdout, result); err != nil {
+		return err
+	}
+	if !result.Equivalent {
+		return fmt.Errorf("gRPC and JSON-RPC responses differ (%d result, %d event differences)",
+			len(result.ResultDifferences), len(result.EventDifferences))
+	}
+	return nil
+}
+
+// printComparison writes a comparison in the selected output format.
+func printComparison(w io.Writer, c *bff.TransportComparison) error {
+	return printValue(w, c, func(tw *tabwriter.Writer) {
+		sides := []struct {
+			name string
+			run  bff.TransportRun
+		}{{"grpc", c.GRPC}, {"jsonrpc", c.JSONRPC}}
+		fmt.Fprintln(tw, "TRANSPORT\tURL\tCODE\tMS\tEVENTS")
+		for _, side := range sides {
+			events := strings.Join(side.run.Events, ", ")
+			if events == "" {
+				events = "-"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%.0f\t%s\n", side.name, side.run.URL, side.run.Code, side.run.DurationMs, events)
+		}
+		_ = tw.Flush()
+		for _, side := range sides {
+			if side.run.Error != "" {
+				fmt.Fprintf(tw, "\n%s error: %s\n", side.name, side.run.Error)
+			}
+		}
+		for _, section := range []struct {
+			title string
+			diffs []bff.Difference
+		}{{"Result differences (grpc != jsonrpc)", c.ResultDifferences}, {"Event differences (grpc != jsonrpc)", c.EventDifferences}} {
+			if len(section.diffs) == 0 {
+				continue
+			}
+			fmt.Fprintf(tw, "\n%s:\n", section.title)
+			for _, d := range section.diffs {
+				fmt.Fprintf(tw, "  %s\n", d)
+			}
+		}
+		if c.Equivalent {
+			fmt.Fprintf(tw, "\nEquivalent (tolerance: %s)\n", c.Tolerance)
+		}
+	})
+}
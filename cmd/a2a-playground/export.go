@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/alis-exchange/a2a-playground/internal/bff"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportFormat  string
+	exportOut     string
+	sessionServer string
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export <context-id>",
+	Short: "Export a recorded session as JSON, Markdown or HAR",
+	Long: `Export writes a recorded conversation with secrets redacted. By default the
+session database in --data-dir is read directly; use --server to export from a
+running playground instead, since the database is locked while it is serving.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runExport,
+}
+
+func init() {
+	exportCmd.Flags().StringVarP(&exportFormat, "format", "f", "json", "Export format: json, markdown or har")
+	exportCmd.Flags().StringVar(&exportOut, "out", "", "Write to this file instead of stdout; \"auto\" uses session-<context-id>.<ext>")
+	addSessionSourceFlags(exportCmd)
+	rootCmd.AddCommand(exportCmd)
+}
+
+// runExport exports one session to stdout or --out.
+func runExport(cmd *cobra.Command, args []string) error {
+	format, err := bff.ParseExportFormat(exportFormat)
+	if err != nil {
+		return err
+	}
+	contextID := args[0]
+
+	var data []byte
+	if sessionServer != "" {
+		data, err = fetchExport(sessionServer, contextID, format)
+	} else {
+		var sess *bff.Session
+		var recs []bff.SessionRecord
+		if sess, recs, err = readStoredSession(contextID); err == nil {
+			data, err = bff.ExportSession(sess, recs, format)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	switch exportOut {
+	case "":
+		_, err = cmd.OutOrStdout().Write(data)
+		return err
+	case "auto":
+		exportOut = format.Filename(contextID)
+	}
+	if err := os.WriteFile(exportOut, data, 0o600); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "Wrote %s\n", exportOut)
+	return nil
+}
+
+// addSessionSourceFlags registers the flags that choose where recorded sessions are read from.
+func addSessionSourceFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&sessionServer, "server", "", "Base URL of a running playground (e.g. http://localhost:3000)")
+	cmd.Flags().StringVar(&dataDir, "data-dir", "", "Directory containing the session database (default: per-user data dir)")
+}
+
+// readSession loads a recorded session from --server, or from the local database.
+func readSession(contextID string) (*bff.Session, []bff.SessionRecord, error) {
+	if sessionServer == "" {
+		return readStoredSession(contextID)
+	}
+	data, err := fetchExport(sessionServer, contextID, bff.ExportJSON)
+	if err != nil {
+		return nil, nil, err
+	}
+	return bff.ReadSessionExport(data)
+}
+
+// readStoredSession reads a session from the database in --data-dir.
+func readStoredSession(contextID string) (*bff.Session, []bff.SessionRecord, error) {
+	dir, err := resolveDataDir()
+	if err != nil {
+		return nil, nil, err
+	}
+	store, err := bff.OpenSessionStoreReadOnly(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w; to read from a running playground use --server", err)
+	}
+	defer store.Close()
+	sess, recs, err := store.Get(contextID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", contextID, err)
+	}
+	return sess, recs, nil
+}
+
+// fetchExport downloads the session from a running playground's export endpoint.
+func fetchExport(server, contextID string, format bff.ExportFormat) ([]byte, error) {
+	u := strings.TrimSuffix(server, "/") + "/api/sessions/" + url.PathEscape(contextID) +
+		"/export?format=" + url.QueryEscape(string(format))
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	// Basic auth can be given in the URL; a token comes from the environment.
+	if token := os.Getenv(tokenEnv); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Error != "" {
+			return nil, fmt.Errorf("%s: %s", contextID, apiErr.Error)
+		}
+		return nil, fmt.Errorf("export %s: %s", contextID, resp.Status)
+	}
+	return body, nil
+}
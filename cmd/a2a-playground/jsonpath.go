@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// evalJSONPath evaluates a JSONPath subset against a decoded JSON document: the root $,
+// .field, ['field'], [n] (negative counts from the end), and the wildcards .* and [*].
+func evalJSONPath(doc any, path string) ([]any, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("jsonpath %q: must start with $", path)
+	}
+	nodes := []any{doc}
+	rest := path[1:]
+	for rest != "" {
+		var step string
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			step, rest = rest[1:end+1], rest[end+1:]
+			if step == "" {
+				return nil, fmt.Errorf("jsonpath %q: empty field name", path)
+			}
+			nodes = selectField(nodes, step)
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("jsonpath %q: unclosed [", path)
+			}
+			step, rest = strings.TrimSpace(rest[1:end]), rest[end+1:]
+			switch {
+			case step == "*":
+				nodes = selectField(nodes, "*")
+			case len(step) >= 2 && (step[0] == '\'' || step[0] == '"') && step[len(step)-1] == step[0]:
+				nodes = selectField(nodes, step[1:len(step)-1])
+			default:
+				i, err := strconv.Atoi(step)
+				if err != nil {
+					return nil, fmt.Errorf("jsonpath %q: invalid index %q", path, step)
+				}
+				nodes = selectIndex(nodes, i)
+			}
+		default:
+			return nil, fmt.Errorf("jsonpath %q: unexpected %q", path, rest[:1])
+		}
+	}
+	return nodes, nil
+}
+
+// selectField returns the named field of every object in nodes; "*" selects every
+// field of objects and every element of arrays.
+func selectField(nodes []any, name string) []any {
+	var out []any
+	for _, n := range nodes {
+		switch t := n.(type) {
+		case map[string]any:
+			if name == "*" {
+				for _, v := range t {
+					out = append(out, v)
+				}
+			} else if v, ok := t[name]; ok {
+				out = append(out, v)
+			}
+		case []any:
+			if name == "*" {
+				out = append(out, t...)
+			}
+		}
+	}
+	return out
+}
+
+func selectIndex(nodes []any, i int) []any {
+	var out []any
+	for _, n := range nodes {
+		arr, ok := n.([]any)
+		if !ok {
+			continue
+		}
+		j := i
+		if j < 0 {
+			j += len(arr)
+		}
+		if j >= 0 && j < len(arr) {
+			out = append(out, arr[j])
+		}
+	}
+	return out
+}
+
+// assertion is a check on a JSON document: "<path>" (exists), "<path> == <value>",
+// "<path> != <value>" or "<path> =~ <regexp>". Values are JSON, or bare strings.
+type assertion struct {
+	raw  string
+	path string
+	op   string
+	want any
+	re   *regexp.Regexp
+}
+
+// parseAssertion parses an --assert expression.
+func parseAssertion(s string) (assertion, error) {
+	a := assertion{raw: s, path: strings.TrimSpace(s)}
+	if i := strings.IndexAny(s, "=!"); i >= 0 && i+1 < len(s) {
+		a.path, a.op = strings.TrimSpace(s[:i]), s[i:i+2]
+		value := strings.TrimSpace(s[i+2:])
+		if err := json.Unmarshal([]byte(value), &a.want); err != nil {
+			a.want = value
+		}
+		switch a.op {
+		case "==", "!=":
+		case "=~":
+			pattern, ok := a.want.(string)
+			if !ok {
+				pattern = value
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return a, fmt.Errorf("assertion %q: %w", s, err)
+			}
+			a.re = re
+		default:
+			return a, fmt.Errorf("assertion %q: operator must be ==, != or =~", s)
+		}
+	}
+	if _, err := evalJSONPath(nil, a.path); err != nil {
+		return a, fmt.Errorf("assertion %q: %w", s, err)
+	}
+	return a, nil
+}
+
+// check evaluates the assertion against doc. ==, =~ and exists pass when any selected
+// value matches; != passes when none is equal.
+func (a assertion) check(doc any) error {
+	got, err := evalJSONPath(doc, a.path)
+	if err != nil {
+		return err
+	}
+	if a.op == "" {
+		if len(got) == 0 {
+			return fmt.Errorf("%s: no match", a.raw)
+		}
+		return nil
+	}
+	want, _ := json.Marshal(a.want)
+	for _, v := range got {
+		b, _ := json.Marshal(v)
+		switch a.op {
+		case "==":
+			if string(b) == string(want) {
+				return nil
+			}
+		case "!=":
+			if string(b) == string(want) {
+				return fmt.Errorf("%s: got %s", a.raw, b)
+			}
+		case "=~":
+			s, ok := v.(string)
+			if !ok {
+				s = string(b)
+			}
+			if a.re.MatchString(s) {
+				return nil
+			}
+		}
+	}
+	if a.op == "!=" {
+		return nil
+	}
+	if len(got) == 0 {
+		return fmt.Errorf("%s: no match", a.raw)
+	}
+	b, _ := json.Marshal(got[0])
+	return fmt.Errorf("%s: got %s", a.raw, b)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testDoc decodes JSON the way documents reach evalJSONPath and validateSchema.
+func testDoc(t *testing.T, s string) any {
+	t.Helper()
+	var doc any
+	if err := json.Unmarshal([]byte(s), &doc); err != nil {
+		t.Fatalf("decode %s: %v", s, err)
+	}
+	return doc
+}
+
+func TestEvalJSONPath(t *testing.T) {
+	doc := `{"name": "a", "items": [{"id": 1}, {"id": 2}, {"id": 3}], "odd key": true, "nested": {"x": {"y": "z"}}}`
+	tests := []struct {
+		path    string
+		want    string // JSON array of the selected values
+		wantErr string
+	}{
+		{path: "$", want: `[` + doc + `]`},
+		{path: "$.name", want: `["a"]`},
+		{path: "$['name']", want: `["a"]`},
+		{path: `$["odd key"]`, want: `[true]`},
+		{path: "$.nested.x.y", want: `["z"]`},
+		{path: "$.items[0].id", want: `[1]`},
+		{path: "$.items[-1].id", want: `[3]`},
+		{path: "$.items[ 1 ].id", want: `[2]`},
+		{path: "$.items[*].id", want: `[1, 2, 3]`},
+		{path: "$.items.*.id", want: `[1, 2, 3]`},
+		{path: "$.missing", want: `[]`},
+		{path: "$.items[3]", want: `[]`},
+		{path: "$.items[-4]", want: `[]`},
+		{path: "$.name[0]", want: `[]`},
+		{path: "$.items.id", want: `[]`},
+		{path: "name", wantErr: "must start with $"},
+		{path: "$..name", wantErr: "empty field name"},
+		{path: "$.items[0", wantErr: "unclosed ["},
+		{path: "$.items[x]", wantErr: `invalid index "x"`},
+		{path: "$name", wantErr: `unexpected "n"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := evalJSONPath(testDoc(t, doc), tt.path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("evalJSONPath error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("evalJSONPath: %v", err)
+			}
+			if got == nil {
+				got = []any{}
+			}
+			if want := testDoc(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("evalJSONPath = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestParseAssertion(t *testing.T) {
+	tests := []struct {
+		expr    string
+		path    string
+		op      string
+		want    any
+		wantErr string
+	}{
+		{expr: "$.a", path: "$.a"},
+		{expr: "$.a == 1", path: "$.a", op: "==", want: 1.0},
+		{expr: `$.a == "x"`, path: "$.a", op: "==", want: "x"},
+		{expr: "$.a == bare words", path: "$.a", op: "==", want: "bare words"},
+		{expr: "$.a != null", path: "$.a", op: "!=", want: nil},
+		{expr: "$.a =~ ^ok", path: "$.a", op: "=~", want: "^ok"},
+		{expr: "$.a =~ (", wantErr: "error parsing regexp"},
+		{expr: "$.a !~ x", wantErr: "operator must be"},
+		{expr: "a == 1", wantErr: "must start with $"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := parseAssertion(tt.expr)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseAssertion error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseAssertion: %v", err)
+			}
+			if got.path != tt.path || got.op != tt.op || !reflect.DeepEqual(got.want, tt.want) {
+				t.Errorf("parseAssertion = {%q %q %#v}, want {%q %q %#v}", got.path, got.op, got.want, tt.path, tt.op, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssertionCheck(t *testing.T) {
+	doc := `{"state": "completed", "count": 2, "tags": ["a", "b"], "ok": true}`
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{expr: "$.state"},
+		{expr: "$.missing", wantErr: "no match"},
+		{expr: "$.state == completed"},
+		{expr: `$.state == "completed"`},
+		{expr: "$.state == failed", wantErr: `got "completed"`},
+		{expr: "$.count == 2"},
+		{expr: `$.count == "2"`, wantErr: "got 2"},
+		{expr: "$.ok == true"},
+		{expr: "$.tags[*] == b"},
+		{expr: "$.tags[*] != c"},
+		{expr: "$.tags[*] != a", wantErr: `got "a"`},
+		{expr: "$.missing != a"},
+		{expr: "$.state =~ ^comp"},
+		{expr: "$.count =~ ^2$"},
+		{expr: "$.state =~ ^fail", wantErr: `got "completed"`},
+		{expr: "$.missing == a", wantErr: "no match"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			a, err := parseAssertion(tt.expr)
+			if err != nil {
+				t.Fatalf("parseAssertion: %v", err)
+			}
+			err = a.check(testDoc(t, doc))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("check: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("check error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
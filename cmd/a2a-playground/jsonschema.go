@@ -0,0 +1,298 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// schemaTypes are the JSON Schema type names.
+var schemaTypes = []string{"object", "array", "string", "number", "integer", "boolean", "null"}
+
+// schemaKeywords are the keywords validateSchema enforces, and schemaAnnotations those
+// it may ignore because they never make a document invalid.
+var (
+	schemaKeywords = []string{
+		"type", "enum", "const", "properties", "required", "additionalProperties", "items",
+		"minItems", "maxItems", "minLength", "maxLength", "pattern", "minimum", "maximum",
+		"exclusiveMinimum", "exclusiveMaximum", "allOf", "anyOf", "oneOf", "not",
+	}
+	schemaAnnotations = []string{
+		"$schema", "$id", "$comment", "title", "description", "default", "examples",
+		"format", "deprecated", "readOnly", "writeOnly",
+	}
+)
+
+// checkSchemaDef reports the first error in a JSON Schema. A keyword validateSchema does
+// not enforce, such as $ref or if, is an error rather than silently passing every
+// document.
+func checkSchemaDef(s map[string]any, path string) error {
+	keys := make([]string, 0, len(s))
+	for key := range s {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !slices.Contains(schemaKeywords, key) && !slices.Contains(schemaAnnotations, key) {
+			return fmt.Errorf("%s: unsupported keyword %q", path, key)
+		}
+	}
+	if t, ok := s["type"]; ok {
+		names, ok := schemaTypeNames(t)
+		if !ok {
+			return fmt.Errorf("%s.type: must be a type name or a list of them", path)
+		}
+		for _, name := range names {
+			if !slices.Contains(schemaTypes, name) {
+				return fmt.Errorf("%s.type: unknown type %q", path, name)
+			}
+		}
+	}
+	if p, ok := s["pattern"]; ok {
+		pattern, ok := p.(string)
+		if !ok {
+			return fmt.Errorf("%s.pattern: must be a string", path)
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("%s.pattern: %w", path, err)
+		}
+	}
+	if props, ok := s["properties"].(map[string]any); ok {
+		for name, sub := range props {
+			if err := checkSubschema(sub, path+".properties."+name); err != nil {
+				return err
+			}
+		}
+	}
+	for _, key := range []string{"items", "additionalProperties", "not"} {
+		if sub, ok := s[key]; ok {
+			if _, isBool := sub.(bool); isBool && key == "additionalProperties" {
+				continue
+			}
+			if err := checkSubschema(sub, path+"."+key); err != nil {
+				return err
+			}
+		}
+	}
+	for _, key := range []string{"allOf", "anyOf", "oneOf"} {
+		if subs, ok := s[key]; ok {
+			list, ok := subs.([]any)
+			if !ok {
+				return fmt.Errorf("%s.%s: must be a list of schemas", path, key)
+			}
+			for i, sub := range list {
+				if err := checkSubschema(sub, fmt.Sprintf("%s.%s[%d]", path, key, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func checkSubschema(sub any, path string) error {
+	s, ok := sub.(map[string]any)
+	if !ok {
+		return fmt.Errorf("%s: must be a schema object", path)
+	}
+	return checkSchemaDef(s, path)
+}
+
+// validateSchema checks a decoded JSON document against a JSON Schema subset: type, enum,
+// const, properties, required, additionalProperties, items, minItems, maxItems,
+// minLength, maxLength, pattern, minimum, maximum, exclusiveMinimum, exclusiveMaximum,
+// allOf, anyOf, oneOf and not. It returns every violation, with the path to the value.
+func validateSchema(s map[string]any, doc any) []string {
+	var out []string
+	validateValue(s, doc, "$", &out)
+	return out
+}
+
+func validateValue(s map[string]any, v any, path string, out *[]string) {
+	fail := func(format string, args ...any) {
+		*out = append(*out, path+": "+fmt.Sprintf(format, args...))
+	}
+	if t, ok := s["type"]; ok {
+		names, _ := schemaTypeNames(t)
+		if !slices.ContainsFunc(names, func(name string) bool { return hasSchemaType(v, name) }) {
+			fail("want %s, got %s", strings.Join(names, " or "), jsonTypeName(v))
+			return
+		}
+	}
+	if enum, ok := s["enum"].([]any); ok && !slices.ContainsFunc(enum, func(e any) bool { return jsonEqual(e, v) }) {
+		fail("%s is not one of %s", compactJSON(v), compactJSON(enum))
+	}
+	if c, ok := s["const"]; ok && !jsonEqual(c, v) {
+		fail("want %s, got %s", compactJSON(c), compactJSON(v))
+	}
+
+	switch t := v.(type) {
+	case map[string]any:
+		props, _ := s["properties"].(map[string]any)
+		if required, ok := s["required"].([]any); ok {
+			for _, r := range required {
+				if name, ok := r.(string); ok {
+					if _, present := t[name]; !present {
+						fail("missing required property %q", name)
+					}
+				}
+			}
+		}
+		names := make([]string, 0, len(t))
+		for name := range t {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if sub, ok := props[name].(map[string]any); ok {
+				validateValue(sub, t[name], path+"."+name, out)
+				continue
+			}
+			switch extra := s["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					fail("unexpected property %q", name)
+				}
+			case map[string]any:
+				validateValue(extra, t[name], path+"."+name, out)
+			}
+		}
+	case []any:
+		if n, ok := schemaNumber(s, "minItems"); ok && float64(len(t)) < n {
+			fail("want at least %v items, got %d", n, len(t))
+		}
+		if n, ok := schemaNumber(s, "maxItems"); ok && float64(len(t)) > n {
+			fail("want at most %v items, got %d", n, len(t))
+		}
+		if items, ok := s["items"].(map[string]any); ok {
+			for i, item := range t {
+				validateValue(items, item, fmt.Sprintf("%s[%d]", path, i), out)
+			}
+		}
+	case string:
+		length := float64(utf8.RuneCountInString(t))
+		if n, ok := schemaNumber(s, "minLength"); ok && length < n {
+			fail("want at least %v characters, got %v", n, length)
+		}
+		if n, ok := schemaNumber(s, "maxLength"); ok && length > n {
+			fail("want at most %v characters, got %v", n, length)
+		}
+		if pattern, ok := s["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(t) {
+				fail("%q does not match %s", t, pattern)
+			}
+		}
+	case float64:
+		if n, ok := schemaNumber(s, "minimum"); ok && t < n {
+			fail("want at least %v, got %v", n, t)
+		}
+		if n, ok := schemaNumber(s, "maximum"); ok && t > n {
+			fail("want at most %v, got %v", n, t)
+		}
+		if n, ok := schemaNumber(s, "exclusiveMinimum"); ok && t <= n {
+			fail("want more than %v, got %v", n, t)
+		}
+		if n, ok := schemaNumber(s, "exclusiveMaximum"); ok && t >= n {
+			fail("want less than %v, got %v", n, t)
+		}
+	}
+
+	subschemas := func(key string) []map[string]any {
+		var subs []map[string]any
+		list, _ := s[key].([]any)
+		for _, sub := range list {
+			if m, ok := sub.(map[string]any); ok {
+				subs = append(subs, m)
+			}
+		}
+		return subs
+	}
+	for _, sub := range subschemas("allOf") {
+		validateValue(sub, v, path, out)
+	}
+	if subs := subschemas("anyOf"); len(subs) > 0 {
+		if !slices.ContainsFunc(subs, func(sub map[string]any) bool { return len(validateSchema(sub, v)) == 0 }) {
+			fail("matches none of anyOf")
+		}
+	}
+	if subs := subschemas("oneOf"); len(subs) > 0 {
+		matched := 0
+		for _, sub := range subs {
+			if len(validateSchema(sub, v)) == 0 {
+				matched++
+			}
+		}
+		if matched != 1 {
+			fail("matches %d of oneOf, want exactly 1", matched)
+		}
+	}
+	if not, ok := s["not"].(map[string]any); ok && len(validateSchema(not, v)) == 0 {
+		fail("matches the schema under not")
+	}
+}
+
+// schemaTypeNames returns the type names of a "type" keyword.
+func schemaTypeNames(t any) ([]string, bool) {
+	switch t := t.(type) {
+	case string:
+		return []string{t}, true
+	case []any:
+		names := make([]string, 0, len(t))
+		for _, e := range t {
+			name, ok := e.(string)
+			if !ok {
+				return nil, false
+			}
+			names = append(names, name)
+		}
+		return names, true
+	}
+	return nil, false
+}
+
+// hasSchemaType reports whether v is of the JSON Schema type name.
+func hasSchemaType(v any, name string) bool {
+	if name == "integer" {
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	}
+	return jsonTypeName(v) == name
+}
+
+// jsonTypeName names the JSON type of a decoded value.
+func jsonTypeName(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func schemaNumber(s map[string]any, key string) (float64, bool) {
+	n, ok := s[key].(float64)
+	return n, ok
+}
+
+func jsonEqual(a, b any) bool {
+	return compactJSON(a) == compactJSON(b)
+}
+
+func compactJSON(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
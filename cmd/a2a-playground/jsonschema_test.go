@@ -0,0 +1,104 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckSchemaDef(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		wantErr string
+	}{
+		{name: "empty", schema: `{}`},
+		{name: "annotations", schema: `{"$schema": "https://json-schema.org/draft/2020-12/schema", "title": "t", "description": "d", "format": "email", "examples": [1]}`},
+		{name: "nested", schema: `{"type": "object", "properties": {"a": {"type": ["string", "null"], "pattern": "^x"}}, "additionalProperties": false, "items": {"type": "integer"}}`},
+		{name: "combinators", schema: `{"allOf": [{"type": "object"}], "anyOf": [{}], "oneOf": [{}], "not": {"type": "null"}}`},
+		{name: "unknown type", schema: `{"type": "float"}`, wantErr: `$.type: unknown type "float"`},
+		{name: "type not a name", schema: `{"type": 1}`, wantErr: "$.type: must be a type name"},
+		{name: "bad pattern", schema: `{"pattern": "("}`, wantErr: "$.pattern:"},
+		{name: "pattern not a string", schema: `{"pattern": 1}`, wantErr: "$.pattern: must be a string"},
+		{name: "bad property", schema: `{"properties": {"a": {"type": "float"}}}`, wantErr: "$.properties.a.type"},
+		{name: "property not a schema", schema: `{"properties": {"a": 1}}`, wantErr: "$.properties.a: must be a schema object"},
+		{name: "bad items", schema: `{"items": true}`, wantErr: "$.items: must be a schema object"},
+		{name: "anyOf not a list", schema: `{"anyOf": {}}`, wantErr: "$.anyOf: must be a list of schemas"},
+		{name: "bad oneOf entry", schema: `{"oneOf": [{}, {"type": "x"}]}`, wantErr: "$.oneOf[1].type"},
+		{name: "$ref", schema: `{"$ref": "#/$defs/a", "$defs": {"a": {}}}`, wantErr: `$: unsupported keyword "$defs"`},
+		{name: "if", schema: `{"if": {}, "then": {}}`, wantErr: `$: unsupported keyword "if"`},
+		{name: "patternProperties", schema: `{"patternProperties": {"^a": {}}}`, wantErr: `unsupported keyword "patternProperties"`},
+		{name: "nested prefixItems", schema: `{"properties": {"a": {"prefixItems": [{}]}}}`, wantErr: `$.properties.a: unsupported keyword "prefixItems"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSchemaDef(testDoc(t, tt.schema).(map[string]any), "$")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkSchemaDef: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkSchemaDef error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		doc    string
+		want   []string
+	}{
+		{name: "type", schema: `{"type": "string"}`, doc: `"x"`},
+		{name: "type mismatch", schema: `{"type": "string"}`, doc: `1`, want: []string{"$: want string, got number"}},
+		{name: "type list", schema: `{"type": ["string", "null"]}`, doc: `null`},
+		{name: "integer", schema: `{"type": "integer"}`, doc: `2.0`},
+		{name: "not integer", schema: `{"type": "integer"}`, doc: `2.5`, want: []string{"$: want integer, got number"}},
+		{name: "enum", schema: `{"enum": ["a", 1]}`, doc: `1`},
+		{name: "not in enum", schema: `{"enum": ["a", 1]}`, doc: `"b"`, want: []string{`$: "b" is not one of ["a",1]`}},
+		{name: "const", schema: `{"const": {"a": 1}}`, doc: `{"a": 1}`},
+		{name: "not const", schema: `{"const": 1}`, doc: `2`, want: []string{"$: want 1, got 2"}},
+		{
+			name:   "object",
+			schema: `{"properties": {"a": {"type": "number"}, "b": {"type": "string"}}, "required": ["a", "c"], "additionalProperties": false}`,
+			doc:    `{"a": "x", "b": "y", "d": 1}`,
+			want:   []string{`$: missing required property "c"`, "$.a: want number, got string", `$: unexpected property "d"`},
+		},
+		{name: "additional schema", schema: `{"additionalProperties": {"type": "string"}}`, doc: `{"a": 1}`, want: []string{"$.a: want string, got number"}},
+		{
+			name:   "array",
+			schema: `{"items": {"minimum": 0}, "minItems": 3, "maxItems": 1}`,
+			doc:    `[1, -1]`,
+			want:   []string{"$: want at least 3 items, got 2", "$: want at most 1 items, got 2", "$[1]: want at least 0, got -1"},
+		},
+		{name: "string length counts runes", schema: `{"maxLength": 2}`, doc: `"éé"`},
+		{name: "string bounds", schema: `{"minLength": 3, "pattern": "^a"}`, doc: `"b"`, want: []string{"$: want at least 3 characters, got 1", `$: "b" does not match ^a`}},
+		{
+			name:   "number bounds",
+			schema: `{"maximum": 1, "exclusiveMinimum": 5, "exclusiveMaximum": 5}`,
+			doc:    `5`,
+			want:   []string{"$: want at most 1, got 5", "$: want more than 5, got 5", "$: want less than 5, got 5"},
+		},
+		{name: "allOf", schema: `{"allOf": [{"type": "number"}, {"minimum": 3}]}`, doc: `1`, want: []string{"$: want at least 3, got 1"}},
+		{name: "anyOf", schema: `{"anyOf": [{"type": "string"}, {"minimum": 3}]}`, doc: `4`},
+		{name: "anyOf none", schema: `{"anyOf": [{"type": "string"}, {"minimum": 3}]}`, doc: `1`, want: []string{"$: matches none of anyOf"}},
+		{name: "oneOf", schema: `{"oneOf": [{"type": "number"}, {"type": "string"}]}`, doc: `1`},
+		{name: "oneOf both", schema: `{"oneOf": [{"type": "number"}, {"minimum": 0}]}`, doc: `1`, want: []string{"$: matches 2 of oneOf, want exactly 1"}},
+		{name: "not", schema: `{"not": {"type": "null"}}`, doc: `null`, want: []string{"$: matches the schema under not"}},
+		{name: "annotations ignored", schema: `{"format": "email", "title": "t"}`, doc: `"not an email"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := testDoc(t, tt.schema).(map[string]any)
+			if err := checkSchemaDef(schema, "$"); err != nil {
+				t.Fatalf("checkSchemaDef: %v", err)
+			}
+			got := validateSchema(schema, testDoc(t, tt.doc))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateSchema = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/a2aproject/a2a-go/a2a"
+	"github.com/a2aproject/a2a-go/a2apb"
+	"github.com/alis-exchange/a2a-playground/gen/go/a2apbconnect"
+	"github.com/spf13/cobra"
+)
+
+var (
+	loadPromptsFile string
+	loadUsers       int
+	loadDuration    time.Duration
+	loadRequests    int
+	loadStreaming   bool
+	loadThinkTime   time.Duration
+	loadTimeout     time.Duration
+	loadReportFile  string
+)
+
+var loadCmd = &cobra.Command{
+	Use:   "load",
+	Short: "Drive concurrent virtual users against the agent and report latency and errors",
+	Long: `Load sends prompts from --prompts (one per line, # for comments) from --users
+concurrent virtual users until --duration elapses or --requests have been sent.
+Requests go through the same proxy as the UI, so gRPC and JSON-RPC agents are
+load-tested the same way.`,
+	Args: cobra.NoArgs,
+	RunE: runLoad,
+}
+
+func init() {
+	addClientFlags(loadCmd)
+	loadCmd.Flags().StringVar(&loadPromptsFile, "prompts", "", "File with one prompt per line (required)")
+	loadCmd.Flags().IntVar(&loadUsers, "users", 1, "Number of concurrent virtual users")
+	loadCmd.Flags().DurationVar(&loadDuration, "duration", 0, "Run for this long (default 30s when --requests is not set)")
+	loadCmd.Flags().IntVar(&loadRequests, "requests", 0, "Stop after this many requests in total")
+	loadCmd.Flags().BoolVar(&loadStreaming, "streaming", true, "Use SendStreamingMessage; set to false for blocking SendMessage")
+	loadCmd.Flags().DurationVar(&loadThinkTime, "think-time", 0, "Pause between requests of a single user")
+	loadCmd.Flags().DurationVar(&loadTimeout, "timeout", time.Minute, "Timeout for each request")
+	loadCmd.Flags().StringVar(&loadReportFile, "report", "", "Write the JSON report to this file")
+	_ = loadCmd.MarkFlagRequired("prompts")
+	rootCmd.AddCommand(loadCmd)
+}
+
+// loadResult is the outcome of a single request.
+type loadResult struct {
+	latency    time.Duration
+	firstEvent time.Duration
+	events     int
+	completed  bool
+	err        error
+}
+
+// runLoad runs the load test and prints the report.
+func runLoad(cmd *cobra.Command, args []string) error {
+	if err := validateOutputFormat(); err != nil {
+		return err
+	}
+	if loadUsers < 1 {
+		return fmt.Errorf("--users must be at least 1")
+	}
+	if loadDuration == 0 && loadRequests == 0 {
+		loadDuration = 30 * time.Second
+	}
+	prompts, err := readPrompts(loadPromptsFile)
+	if err != nil {
+		return err
+	}
+
+	client, err := newAgentClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer stop()
+
+	var deadline time.Time
+	if loadDuration > 0 {
+		deadline = time.Now().Add(loadDuration)
+	}
+	var issued atomic.Int64
+	// next reserves the next request number, or returns false once the run is over.
+	next := func() (int64, bool) {
+		if ctx.Err() != nil || (!deadline.IsZero() && time.Now().After(deadline)) {
+			return 0, false
+		}
+		n := issued.Add(1)
+		if loadRequests > 0 && n > int64(loadRequests) {
+			return 0, false
+		}
+		return n, true
+	}
+
+	results := make(chan loadResult, loadUsers)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for range loadUsers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				n, ok := next()
+				if !ok {
+					return
+				}
+				prompt := prompts[(n-1)%int64(len(prompts))]
+				results <- sendLoadRequest(ctx, client, prompt)
+				if loadThinkTime > 0 {
+					select {
+					case <-time.After(loadThinkTime):
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var all []loadResult
+	for r := range results {
+		all = append(all, r)
+	}
+	report := newLoadReport(all, time.Since(start))
+
+	if loadReportFile != "" {
+		if err := report.writeFile(loadReportFile); err != nil {
+			return err
+		}
+	}
+	return report.print(os.Stdout)
+}
+
+// sendLoadRequest sends one prompt and measures it.
+func sendLoadRequest(ctx context.Context, client a2apbconnect.A2AServiceClient, prompt string) loadResult {
+	ctx, cancel := context.WithTimeout(ctx, loadTimeout)
+	defer cancel()
+
+	req := &a2apb.SendMessageRequest{
+		Request: &a2apb.Message{
+			MessageId: a2a.NewMessageID(),
+			Role:      a2apb.Role_ROLE_USER,
+			Parts:     []*a2apb.Part{{Part: &a2apb.Part_Text{Text: prompt}}},
+		},
+	}
+	start := time.Now()
+	if !loadStreaming {
+		req.Configuration = &a2apb.SendMessageConfiguration{Blocking: true}
+		_, err := client.SendMessage(ctx, connect.NewRequest(req))
+		return loadResult{latency: time.Since(start), completed: err == nil, err: err}
+	}
+
+	var r loadResult
+	stream, err := client.SendStreamingMessage(ctx, connect.NewRequest(req))
+	if err != nil {
+		r.latency, r.err = time.Since(start), err
+		return r
+	}
+	defer stream.Close()
+	for stream.Receive() {
+		if r.events == 0 {
+			r.firstEvent = time.Since(start)
+		}
+		r.events++
+		r.completed = isFinalEvent(stream.Msg())
+	}
+	r.latency, r.err = time.Since(start), stream.Err()
+	if r.err != nil {
+		r.completed = false
+	}
+	return r
+}
+
+// isFinalEvent reports whether ev ends the stream: a final status update, a direct
+// message reply, or a task that is terminal or waiting for input.
+func isFinalEvent(ev *a2apb.StreamResponse) bool {
+	switch p := ev.GetPayload().(type) {
+	case *a2apb.StreamResponse_Msg:
+		return true
+	case *a2apb.StreamResponse_StatusUpdate:
+		return p.StatusUpdate.GetFinal() || isTerminalState(p.StatusUpdate.GetStatus().GetState()) ||
+			isInterruptedState(p.StatusUpdate.GetStatus().GetState())
+	case *a2apb.StreamResponse_Task:
+		state := p.Task.GetStatus().GetState()
+		return isTerminalState(state) || isInterruptedState(state)
+	}
+	return false
+}
+
+// readPrompts reads non-empty, non-comment lines from path.
+func readPrompts(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var prompts []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		prompts = append(prompts, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(prompts) == 0 {
+		return nil, errors.New("prompts file has no prompts")
+	}
+	return prompts, nil
+}
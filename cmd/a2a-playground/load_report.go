@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"connectrpc.com/connect"
+)
+
+// loadReport summarizes a load run. It is printed and optionally written as JSON.
+type loadReport struct {
+	AgentURL         string         `json:"agentUrl"`
+	Transport        string         `json:"transport"`
+	Streaming        bool           `json:"streaming"`
+	Users            int            `json:"users"`
+	DurationSeconds  float64        `json:"durationSeconds"`
+	Requests         int            `json:"requests"`
+	Succeeded        int            `json:"succeeded"`
+	Failed           int            `json:"failed"`
+	Throughput       float64        `json:"throughputPerSecond"`
+	Errors           map[string]int `json:"errors,omitempty"`
+	Latency          latencyStats   `json:"latencyMs"`
+	TimeToFirstEvent *latencyStats  `json:"timeToFirstEventMs,omitempty"`
+	Streams          *streamStats   `json:"streams,omitempty"`
+}
+
+// latencyStats holds latency percentiles in milliseconds.
+type latencyStats struct {
+	Min  float64 `json:"min"`
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P95  float64 `json:"p95"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+// streamStats counts how streaming requests ended.
+type streamStats struct {
+	Started        int     `json:"started"`
+	Completed      int     `json:"completed"`
+	Incomplete     int     `json:"incomplete"`
+	CompletionRate float64 `json:"completionRate"`
+	MeanEvents     float64 `json:"meanEvents"`
+}
+
+// newLoadReport aggregates results from a run that took elapsed.
+func newLoadReport(results []loadResult, elapsed time.Duration) *loadReport {
+	agent, _ := agentConfig()
+	r := &loadReport{
+		AgentURL:        agent.URL,
+		Transport:       string(agent.Protocol),
+		Streaming:       loadStreaming,
+		Users:           loadUsers,
+		DurationSeconds: elapsed.Seconds(),
+		Requests:        len(results),
+		Errors:          map[string]int{},
+	}
+	if elapsed > 0 {
+		r.Throughput = float64(len(results)) / elapsed.Seconds()
+	}
+
+	var latencies, firstEvents []time.Duration
+	streams := &streamStats{}
+	var events int
+	for _, res := range results {
+		if res.err != nil {
+			r.Failed++
+			r.Errors[connect.CodeOf(res.err).String()]++
+		} else {
+			r.Succeeded++
+			latencies = append(latencies, res.latency)
+		}
+		if !loadStreaming {
+			continue
+		}
+		if res.events > 0 {
+			streams.Started++
+			firstEvents = append(firstEvents, res.firstEvent)
+		}
+		events += res.events
+		if res.completed {
+			streams.Completed++
+		} else if res.err == nil {
+			streams.Incomplete++
+		}
+	}
+	r.Latency = newLatencyStats(latencies)
+	if loadStreaming {
+		ttfe := newLatencyStats(firstEvents)
+		r.TimeToFirstEvent = &ttfe
+		if len(results) > 0 {
+			streams.CompletionRate = float64(streams.Completed) / float64(len(results))
+			streams.MeanEvents = float64(events) / float64(len(results))
+		}
+		r.Streams = streams
+	}
+	return r
+}
+
+// newLatencyStats computes nearest-rank percentiles.
+func newLatencyStats(ds []time.Duration) latencyStats {
+	if len(ds) == 0 {
+		return latencyStats{}
+	}
+	sorted := slices.Clone(ds)
+	slices.Sort(sorted)
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+	pct := func(p float64) float64 {
+		i := int(p*float64(len(sorted))+0.5) - 1
+		i = max(0, min(i, len(sorted)-1))
+		return millis(sorted[i])
+	}
+	return latencyStats{
+		Min:  millis(sorted[0]),
+		Mean: millis(total / time.Duration(len(sorted))),
+		P50:  pct(0.50),
+		P90:  pct(0.90),
+		P95:  pct(0.95),
+		P99:  pct(0.99),
+		Max:  millis(sorted[len(sorted)-1]),
+	}
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// writeFile writes the report as indented JSON.
+func (r *loadReport) writeFile(path string) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// print writes the report in the selected output format.
+func (r *loadReport) print(w io.Writer) error {
+	return printValue(w, r, func(tw *tabwriter.Writer) {
+		mode := "unary"
+		if r.Streaming {
+			mode = "streaming"
+		}
+		fmt.Fprintf(tw, "Agent:\t%s (%s, %s)\n", r.AgentURL, r.Transport, mode)
+		fmt.Fprintf(tw, "Users:\t%d\n", r.Users)
+		fmt.Fprintf(tw, "Duration:\t%.1fs\n", r.DurationSeconds)
+		fmt.Fprintf(tw, "Requests:\t%d (%d ok, %d failed)\n", r.Requests, r.Succeeded, r.Failed)
+		fmt.Fprintf(tw, "Throughput:\t%.2f req/s\n", r.Throughput)
+		fmt.Fprintf(tw, "Latency (ms):\t%s\n", r.Latency)
+		if r.TimeToFirstEvent != nil {
+			fmt.Fprintf(tw, "First event (ms):\t%s\n", r.TimeToFirstEvent)
+		}
+		if r.Streams != nil {
+			fmt.Fprintf(tw, "Streams:\t%d completed, %d incomplete (%.1f%% completion, %.1f events avg)\n",
+				r.Streams.Completed, r.Streams.Incomplete, r.Streams.CompletionRate*100, r.Streams.MeanEvents)
+		}
+		if len(r.Errors) > 0 {
+			codes := make([]string, 0, len(r.Errors))
+			for code := range r.Errors {
+				codes = append(codes, code)
+			}
+			sort.Strings(codes)
+			fmt.Fprintln(tw, "Errors:\t")
+			for _, code := range codes {
+				fmt.Fprintf(tw, "  %s\t%d\n", code, r.Errors[code])
+			}
+		}
+	})
+}
+
+func (s latencyStats) String() string {
+	return fmt.Sprintf("min %.1f  mean %.1f  p50 %.1f  p90 %.1f  p95 %.1f  p99 %.1f  max %.1f",
+		s.Min, s.Mean, s.P50, s.P90, s.P95, s.P99, s.Max)
+}
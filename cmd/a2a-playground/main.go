@@ -4,11 +4,16 @@ package main
 import (
 	"context"
 	"fmt"
+	"net"
+	"net/netip"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"connectrpc.com/connect"
+	"github.com/alis-exchange/a2a-playground/gen/go/a2apbconnect"
 	"github.com/alis-exchange/a2a-playground/internal/bff"
 	"github.com/spf13/cobra"
 )
@@ -21,58 +26,203 @@ func main() {
 }
 
 var (
-	version   string // set via -ldflags at build
-	agentURL  string
+	version    string // set via -ldflags at build
+	agentURL   string
 	useJSONRPC bool
-	port      int
-	noOpen    bool
-	dev       bool
+	a2aVersion string
+	cardURL    string
+	port       int
+	listenAddr string
+	noOpen     bool
+	dev        bool
+	dataDir    string
+	noSessions bool
+	noTasks    bool
+	taskKeep   bff.TaskRetention
+	compareURL string
+	durable    bool
+	shareSubs  bool
+	extensions []string
+	fileParts  string
+	publicURL  string
+	faults     bff.FaultConfig
+	faultCodes []string
+	rpcTimeout map[string]string
+	cancelGone bool
+	retry      bff.RetryPolicy
+	retryCodes []string
+	breaker    bff.BreakerPolicy
+	fwdHeaders bff.ResponseHeaderPolicy
+	access     bff.AccessConfig
+	authMode   string
+	basicAuth  string
+	proxies    []string
+	tlsConfig  bff.TLSConfig
+)
+
+// Environment variables that keep secrets off the command line. tokenEnv is also read by
+// commands that call a running playground with --server.
+const (
+	tokenEnv     = "A2A_PLAYGROUND_TOKEN"
+	basicAuthEnv = "A2A_PLAYGROUND_AUTH_BASIC"
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "a2a-playground",
 	Short: "A2A agent playground - serves the frontend and proxies to an A2A agent",
 	RunE:  runServe,
+	// main prints the error; usage is only useful for flag errors, which cobra still reports.
+	SilenceUsage:  true,
+	SilenceErrors: true,
 }
 
 func init() {
-	rootCmd.Flags().StringVar(&agentURL, "agent-url", "localhost:8080", "Agent endpoint: for gRPC use host:port; for JSON-RPC use full URL (e.g. http://localhost:8080/jsonrpc)")
-	rootCmd.Flags().BoolVar(&useJSONRPC, "jsonrpc", false, "Use JSON-RPC transport instead of gRPC")
+	rootCmd.PersistentFlags().StringVar(&agentURL, "agent-url", "localhost:8080", "Agent endpoint: for gRPC use host:port; for JSON-RPC use full URL (e.g. http://localhost:8080/jsonrpc)")
+	rootCmd.PersistentFlags().BoolVar(&useJSONRPC, "jsonrpc", false, "Use JSON-RPC transport instead of gRPC")
+	rootCmd.PersistentFlags().StringVar(&cardURL, "agent-card-url", "", "URL of the agent's public card (default: /.well-known/agent-card.json at the agent URL's host)")
+	rootCmd.PersistentFlags().StringVar(&a2aVersion, "a2a-version", string(bff.Version03), "A2A version the gRPC agent speaks: 0.3, 1.0 or auto (ask the agent)")
 	if version != "" {
 		rootCmd.Version = version
 	}
-	rootCmd.Flags().IntVar(&port, "port", 3000, "HTTP port for the BFF")
+	rootCmd.Flags().IntVar(&port, "port", 3000, "HTTP port for the BFF on loopback; shorthand for --listen 127.0.0.1:<port>")
+	rootCmd.Flags().StringVar(&listenAddr, "listen", "", "Address to listen on: host:port (port 0 picks a free one), :port for every interface, or a unix socket path (default 127.0.0.1:3000)")
 	rootCmd.Flags().BoolVar(&noOpen, "no-open", false, "Do not open the browser on start")
 	rootCmd.Flags().BoolVar(&dev, "dev", false, "Serve from app/dist on disk instead of embedded files")
+	rootCmd.Flags().StringVar(&dataDir, "data-dir", "", "Directory for playground data such as recorded sessions (default: per-user data dir)")
+	rootCmd.Flags().BoolVar(&noSessions, "no-sessions", false, "Do not record conversations in the session store")
+	rootCmd.Flags().BoolVar(&noTasks, "no-task-cache", false, "Do not keep tasks in the searchable task cache")
+	rootCmd.Flags().DurationVar(&taskKeep.MaxAge, "task-retention", bff.DefaultTaskRetention.MaxAge, "Drop cached tasks not updated for this long; 0 keeps them")
+	rootCmd.Flags().IntVar(&taskKeep.MaxTasks, "task-max", bff.DefaultTaskRetention.MaxTasks, "Keep at most this many cached tasks, the most recently updated; 0 for no limit")
+	rootCmd.Flags().BoolVar(&durable, "durable-streams", false, "Keep streaming calls running when the browser disconnects so it can reattach")
+	rootCmd.Flags().BoolVar(&shareSubs, "share-subscriptions", false, "Share one upstream TaskSubscription between the clients watching a task")
+	rootCmd.Flags().StringSliceVar(&extensions, "extensions", nil, "A2A extension URIs activated on every call, besides those selected with X-A2A-Extensions")
+	rootCmd.Flags().StringVar(&compareURL, "compare-url", "", "The agent's endpoint on the other transport; enables /api/compare")
+	rootCmd.Flags().StringVar(&fileParts, "file-parts", string(bff.FilePartsBytes), "How uploaded files reach the agent: bytes (inlined up to 2 MiB per message, then by URI) or uri (served by the BFF at --public-url)")
+	rootCmd.Flags().StringToStringVar(&rpcTimeout, "rpc-timeout", nil, "Deadlines for agent calls as method=duration, e.g. SendMessage=30s,*=2m (default none)")
+	rootCmd.Flags().BoolVar(&cancelGone, "cancel-on-disconnect", false, "Cancel a streamed task upstream when its client disconnects before the task ends")
+	rootCmd.Flags().IntVar(&retry.MaxAttempts, "retry-max-attempts", 3, "Attempts for idempotent calls such as GetTask, including the first; 1 disables retries")
+	rootCmd.Flags().DurationVar(&retry.InitialBackoff, "retry-backoff", 100*time.Millisecond, "Wait before the first retry; doubles for each further retry")
+	rootCmd.Flags().DurationVar(&retry.MaxBackoff, "retry-max-backoff", 2*time.Second, "Longest wait between retries")
+	rootCmd.Flags().StringSliceVar(&retryCodes, "retry-codes", []string{"unavailable", "aborted"}, "Connect codes that are retried")
+	rootCmd.Flags().IntVar(&breaker.Threshold, "breaker-threshold", 5, "Consecutive unreachable-agent failures that open the circuit breaker; 0 disables it")
+	rootCmd.Flags().DurationVar(&breaker.Cooldown, "breaker-cooldown", 30*time.Second, "How long calls fail fast once the circuit breaker opens")
+	rootCmd.Flags().StringVar(&tlsConfig.CertFile, "tls-cert", "", "Serve HTTPS with this PEM certificate (with --tls-key)")
+	rootCmd.Flags().StringVar(&tlsConfig.KeyFile, "tls-key", "", "PEM private key for --tls-cert")
+	rootCmd.Flags().BoolVar(&tlsConfig.SelfSigned, "tls-self-signed", false, "Serve HTTPS with a certificate from a local CA kept in the data dir")
+	rootCmd.Flags().StringSliceVar(&tlsConfig.Hosts, "tls-hosts", nil, "Extra host names or IPs for the --tls-self-signed certificate")
+	rootCmd.Flags().StringVar(&authMode, "auth", string(bff.AuthNone), "Authenticate BFF users: none, token (random token in the startup URL), basic or proxy")
+	rootCmd.Flags().StringVar(&access.Token, "auth-token", "", "Token for --auth=token (default: $"+tokenEnv+", or random)")
+	rootCmd.Flags().StringVar(&basicAuth, "auth-basic", "", "user:password for --auth=basic (default: $"+basicAuthEnv+")")
+	rootCmd.Flags().StringVar(&access.ProxyHeader, "auth-proxy-header", bff.DefaultProxyAuthHeader, "Header holding the user authenticated by the proxy, for --auth=proxy")
+	rootCmd.Flags().StringSliceVar(&proxies, "trusted-proxies", nil, "IP addresses or CIDR ranges of the proxies trusted for --auth=proxy")
+	rootCmd.Flags().StringSliceVar(&access.AllowedHosts, "allowed-hosts", nil, "Host names the BFF answers to besides localhost and the listen and public URL hosts; * for any")
+	rootCmd.Flags().StringSliceVar(&access.TrustedOrigins, "trusted-origins", nil, "Origins allowed to call the BFF cross-origin, e.g. http://localhost:8000")
+	rootCmd.Flags().StringSliceVar(&fwdHeaders.Allow, "forward-headers", bff.DefaultForwardHeaders, "Agent response headers and trailers passed to the browser; * wildcards allowed, empty forwards none")
+	rootCmd.Flags().StringVar(&fwdHeaders.Prefix, "forward-header-prefix", bff.DefaultForwardHeaderPrefix, "Prefix for forwarded agent response headers")
+	rootCmd.Flags().DurationVar(&faults.Latency, "fault-latency", 0, "Fault injection: latency added to every call")
+	rootCmd.Flags().DurationVar(&faults.Jitter, "fault-jitter", 0, "Fault injection: random extra latency, up to this much")
+	rootCmd.Flags().Float64Var(&faults.ErrorRate, "fault-error-rate", 0, "Fault injection: percentage of calls that fail")
+	rootCmd.Flags().StringSliceVar(&faultCodes, "fault-error-codes", nil, "Fault injection: Connect codes for failed calls, picked at random (default unavailable)")
+	rootCmd.Flags().IntVar(&faults.TruncateAfter, "fault-truncate-after", 0, "Fault injection: end streams after this many events")
+	rootCmd.Flags().Float64Var(&faults.DuplicateRate, "fault-duplicate-rate", 0, "Fault injection: percentage of stream events sent twice")
+	rootCmd.Flags().Float64Var(&faults.ReorderRate, "fault-reorder-rate", 0, "Fault injection: percentage of stream events swapped with the next")
+	rootCmd.Flags().IntVar(&faults.BytesPerSecond, "fault-bandwidth", 0, "Fault injection: limit responses to this many bytes per second")
+	rootCmd.Flags().StringSliceVar(&faults.Procedures, "fault-procedures", nil, "Fault injection: only affect these RPCs, e.g. SendStreamingMessage (default all)")
+	rootCmd.Flags().StringVar(&publicURL, "public-url", "", "URL the agent can reach the BFF at, for --file-parts=uri (default: the URL the BFF listens on)")
 }
 
 // runServe starts the BFF server and blocks until interrupt.
 func runServe(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
-	// Validate and normalize agent-url by protocol
-	proto := bff.ProtocolGRPC
-	if useJSONRPC {
-		proto = bff.ProtocolJSONRPC
-	}
-	normalizedURL := normalizeAgentURL(agentURL, proto)
-	if normalizedURL == "" {
-		return fmt.Errorf("invalid agent-url %q for protocol %s: JSON-RPC requires http:// or https:// scheme", agentURL, proto)
+	agent, err := agentConfig()
+	if err != nil {
+		return err
 	}
+	proto, normalizedURL := agent.Protocol, agent.URL
 
 	appDir, err := findAppDir()
 	if err != nil {
 		return err
 	}
 
+	sessionsDir, err := resolveDataDir()
+	if err != nil {
+		return err
+	}
+	filesDir := filepath.Join(sessionsDir, "files")
+	tlsConfig.Dir = filepath.Join(sessionsDir, "tls")
+	tasksDir := sessionsDir
+	if noTasks {
+		tasksDir = ""
+	}
+	if noSessions {
+		sessionsDir = ""
+	}
+	filePartMode, err := bff.ParseFilePartMode(fileParts)
+	if err != nil {
+		return err
+	}
+	timeouts, err := bff.ParseRPCTimeouts(rpcTimeout)
+	if err != nil {
+		return fmt.Errorf("--rpc-timeout: %w", err)
+	}
+	if faults.ErrorCodes, err = parseCodes("--fault-error-codes", faultCodes); err != nil {
+		return err
+	}
+	if retry.Codes, err = parseCodes("--retry-codes", retryCodes); err != nil {
+		return err
+	}
+	if listenAddr == "" {
+		listenAddr = fmt.Sprintf("127.0.0.1:%d", port)
+	} else if cmd.Flags().Changed("port") {
+		return fmt.Errorf("--port and --listen cannot be used together")
+	}
+
+	if err := accessConfig(); err != nil {
+		return err
+	}
+
+	var otherURL string
+	if compareURL != "" {
+		grpcURL, jsonrpcURL, err := compareAgents()
+		if err != nil {
+			return err
+		}
+		otherURL = jsonrpcURL
+		if proto == bff.ProtocolJSONRPC {
+			otherURL = grpcURL
+		}
+	}
+
 	cfg := bff.ServerConfig{
-		Port:        port,
-		AgentURL:    normalizedURL,
-		Protocol:    proto,
-		Dev:         dev,
-		NoOpen:      noOpen,
-		AppDir:      appDir,
-		OpenBrowser: !noOpen,
+		Listen:             listenAddr,
+		AgentURL:           normalizedURL,
+		Protocol:           proto,
+		AgentVersion:       agent.Version,
+		AgentCardURL:       agent.CardURL,
+		Dev:                dev,
+		NoOpen:             noOpen,
+		AppDir:             appDir,
+		OpenBrowser:        !noOpen,
+		DataDir:            sessionsDir,
+		TasksDir:           tasksDir,
+		TaskRetention:      taskKeep,
+		CompareURL:         otherURL,
+		DurableStreams:     durable,
+		ShareSubscriptions: shareSubs,
+		Extensions:         extensions,
+		FilesDir:           filesDir,
+		FileParts:          filePartMode,
+		PublicURL:          publicURL,
+		Faults:             faults,
+		RPCTimeouts:        timeouts,
+		CancelOnDisconnect: cancelGone,
+		Retry:              retry,
+		Breaker:            breaker,
+		ForwardHeaders:     fwdHeaders,
+		Access:             access,
+		TLS:                tlsConfig,
 	}
 
 	srv, err := bff.NewServer(ctx, cfg)
@@ -84,10 +234,21 @@ func runServe(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	url := fmt.Sprintf("http://localhost:%d", port)
-	fmt.Printf("Serving at %s (agent: %s, protocol: %s)\n", url, normalizedURL, proto)
+	url := srv.LoginURL()
+	if url == "" {
+		fmt.Printf("Serving on unix socket %s (agent: %s, protocol: %s)\n", srv.Addr(), normalizedURL, proto)
+	} else {
+		fmt.Printf("Serving at %s (agent: %s, protocol: %s)\n", url, normalizedURL, proto)
+	}
+	fmt.Printf("A2A gRPC gateway at %s (%s)\n", srv.GatewayTarget(), a2apbconnect.A2AServiceName)
+	if tlsConfig.SelfSigned {
+		fmt.Printf("HTTPS certificate issued by the local CA %s; trust it to avoid browser warnings\n", bff.CAFile(tlsConfig.Dir))
+	}
+	if access.Auth == bff.AuthNone && exposed(srv.Addr()) {
+		fmt.Fprintf(os.Stderr, "Warning: listening on %s without --auth; anyone who can reach it can call the agent with your headers\n", srv.Addr())
+	}
 
-	if cfg.OpenBrowser {
+	if cfg.OpenBrowser && url != "" {
 		_ = bff.OpenBrowser(url)
 	}
 
@@ -100,6 +261,103 @@ func runServe(cmd *cobra.Command, args []string) error {
 	return srv.Shutdown(shutdownCtx)
 }
 
+// agentConfig validates --agent-url against the selected protocol.
+func agentConfig() (bff.AgentConfig, error) {
+	proto := bff.ProtocolGRPC
+	if useJSONRPC {
+		proto = bff.ProtocolJSONRPC
+	}
+	normalizedURL := normalizeAgentURL(agentURL, proto)
+	if normalizedURL == "" {
+		return bff.AgentConfig{}, fmt.Errorf("invalid agent-url %q for protocol %s: JSON-RPC requires http:// or https:// scheme", agentURL, proto)
+	}
+	v, err := bff.ParseProtocolVersion(a2aVersion)
+	if err != nil {
+		return bff.AgentConfig{}, fmt.Errorf("--a2a-version: %w", err)
+	}
+	if proto == bff.ProtocolJSONRPC && v == bff.Version1 {
+		return bff.AgentConfig{}, fmt.Errorf("--a2a-version %s is only supported for gRPC agents", v)
+	}
+	return bff.AgentConfig{URL: normalizedURL, Protocol: proto, Version: v, CardURL: cardURL}, nil
+}
+
+// compareAgents returns the gRPC and JSON-RPC endpoints of the agent from --agent-url and
+// --compare-url, which must name the same agent on the other transport.
+func compareAgents() (grpcURL, jsonrpcURL string, err error) {
+	agent, err := agentConfig()
+	if err != nil {
+		return "", "", err
+	}
+	if agent.Protocol == bff.ProtocolJSONRPC {
+		grpcURL = normalizeAgentURL(compareURL, bff.ProtocolGRPC)
+		if grpcURL == "" {
+			return "", "", fmt.Errorf("--compare-url is required: the agent's gRPC host:port")
+		}
+		return grpcURL, agent.URL, nil
+	}
+	jsonrpcURL = normalizeAgentURL(compareURL, bff.ProtocolJSONRPC)
+	if jsonrpcURL == "" {
+		return "", "", fmt.Errorf("invalid compare-url %q: the agent's JSON-RPC endpoint needs an http:// or https:// URL", compareURL)
+	}
+	return agent.URL, jsonrpcURL, nil
+}
+
+// accessConfig fills access from the --auth flags and their environment variables.
+func accessConfig() error {
+	var err error
+	if access.Auth, err = bff.ParseAuthMode(authMode); err != nil {
+		return fmt.Errorf("--auth: %w", err)
+	}
+	if access.Token == "" {
+		access.Token = os.Getenv(tokenEnv)
+	}
+	if basicAuth == "" {
+		basicAuth = os.Getenv(basicAuthEnv)
+	}
+	if access.Auth == bff.AuthBasic {
+		user, password, ok := strings.Cut(basicAuth, ":")
+		if !ok {
+			return fmt.Errorf("--auth=basic needs --auth-basic or $%s as user:password", basicAuthEnv)
+		}
+		access.BasicUser, access.BasicPassword = user, password
+	}
+	if access.TrustedProxies, err = bff.ParseTrustedProxies(proxies); err != nil {
+		return fmt.Errorf("--trusted-proxies: %w", err)
+	}
+	return nil
+}
+
+// exposed reports whether a listen address is reachable from other machines.
+func exposed(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false // a unix socket
+	}
+	ip, err := netip.ParseAddr(host)
+	return err != nil || !ip.IsLoopback()
+}
+
+// parseCodes parses Connect code names such as "unavailable" given to flag.
+func parseCodes(flag string, names []string) ([]connect.Code, error) {
+	var codes []connect.Code
+	for _, name := range names {
+		var code connect.Code
+		if err := code.UnmarshalText([]byte(strings.TrimSpace(name))); err != nil {
+			return nil, fmt.Errorf("%s: %w", flag, err)
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// resolveDataDir returns --data-dir, or the per-user default when it is not set.
+func resolveDataDir() (string, error) {
+	if dataDir != "" {
+		return dataDir, nil
+	}
+	return bff.DefaultDataDir()
+}
+
 // normalizeAgentURL validates and normalizes agent-url by protocol.
 // For gRPC: strips http(s):// so "http://localhost:8080" becomes "localhost:8080".
 // For JSON-RPC: requires http:// or https://; returns "" if invalid.
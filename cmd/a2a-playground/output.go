@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats accepted by --output.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// validateOutputFormat rejects unknown --output values before any RPC is made.
+func validateOutputFormat() error {
+	switch outputFormat {
+	case outputTable, outputJSON, outputYAML:
+		return nil
+	}
+	return fmt.Errorf("invalid output %q: must be table, json or yaml", outputFormat)
+}
+
+// printMessage writes msg in the selected output format. table renders the table form.
+func printMessage(w io.Writer, msg proto.Message, table func(tw *tabwriter.Writer)) error {
+	switch outputFormat {
+	case outputJSON:
+		b, err := protojson.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	case outputYAML:
+		b, err := protoYAML(msg)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	default:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		table(tw)
+		return tw.Flush()
+	}
+}
+
+// printStreamItem writes a single streamed message: one JSON object per line, one YAML
+// document per message, or one table row.
+func printStreamItem(w io.Writer, msg proto.Message, row func(w io.Writer)) error {
+	switch outputFormat {
+	case outputJSON:
+		b, err := protojson.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	case outputYAML:
+		b, err := protoYAML(msg)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "---\n%s", b)
+		return err
+	default:
+		row(w)
+		return nil
+	}
+}
+
+// printValue is printMessage for plain Go values with JSON tags.
+func printValue(w io.Writer, v any, table func(tw *tabwriter.Writer)) error {
+	switch outputFormat {
+	case outputJSON:
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	case outputYAML:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if b, err = jsonToYAML(b); err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	default:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		table(tw)
+		return tw.Flush()
+	}
+}
+
+// protoYAML renders msg as YAML using its canonical protojson field names.
+func protoYAML(msg proto.Message) ([]byte, error) {
+	b, err := protojson.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return jsonToYAML(b)
+}
+
+// jsonToYAML re-encodes a JSON document as YAML, keeping the JSON field names.
+func jsonToYAML(b []byte) ([]byte, error) {
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(v)
+}
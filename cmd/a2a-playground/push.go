@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"connectrpc.com/connect"
+	"github.com/a2aproject/a2a-go/a2apb"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pushPageSize        int32
+	pushPageToken       string
+	pushURL             string
+	pushConfigID        string
+	pushToken           string
+	pushAuthSchemes     []string
+	pushAuthCredentials string
+)
+
+var pushCmd = &cobra.Command{
+	Use:   "push",
+	Short: "Manage task push notification configs",
+}
+
+var pushListCmd = &cobra.Command{
+	Use:   "list <task-id>",
+	Short: "List push notification configs for a task",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runPushList,
+}
+
+var pushGetCmd = &cobra.Command{
+	Use:   "get <task-id> <config-id>",
+	Short: "Get a push notification config",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runPushGet,
+}
+
+var pushCreateCmd = &cobra.Command{
+	Use:   "create <task-id>",
+	Short: "Create a push notification config for a task",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runPushCreate,
+}
+
+var pushDeleteCmd = &cobra.Command{
+	Use:   "delete <task-id> <config-id>",
+	Short: "Delete a push notification config",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runPushDelete,
+}
+
+func init() {
+	addClientFlags(pushCmd)
+
+	pushListCmd.Flags().Int32Var(&pushPageSize, "page-size", 0, "Maximum configs per page (agent default when 0)")
+	pushListCmd.Flags().StringVar(&pushPageToken, "page-token", "", "Page token from a previous list")
+
+	pushCreateCmd.Flags().StringVar(&pushURL, "url", "", "Webhook URL that receives notifications (required)")
+	pushCreateCmd.Flags().StringVar(&pushConfigID, "config-id", "", "Config ID (agent-assigned when empty)")
+	pushCreateCmd.Flags().StringVar(&pushToken, "token", "", "Token the agent sends with each notification")
+	pushCreateCmd.Flags().StringArrayVar(&pushAuthSchemes, "auth-scheme", nil, "Authentication scheme for the webhook, e.g. Bearer (repeatable)")
+	pushCreateCmd.Flags().StringVar(&pushAuthCredentials, "auth-credentials", "", "Credentials for the webhook authentication scheme")
+	_ = pushCreateCmd.MarkFlagRequired("url")
+
+	pushCmd.AddCommand(pushListCmd, pushGetCmd, pushCreateCmd, pushDeleteCmd)
+	rootCmd.AddCommand(pushCmd)
+}
+
+// runPushList lists the push notification configs of a task.
+func runPushList(cmd *cobra.Command, args []string) error {
+	if err := validateOutputFormat(); err != nil {
+		return err
+	}
+	client, err := newAgentClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	resp, err := client.ListTaskPushNotificationConfig(cmd.Context(), connect.NewRequest(&a2apb.ListTaskPushNotificationConfigRequest{
+		Parent:    taskName(args[0]),
+		PageSize:  pushPageSize,
+		PageToken: pushPageToken,
+	}))
+	if err != nil {
+		return err
+	}
+	list := resp.Msg
+	return printMessage(os.Stdout, list, func(tw *tabwriter.Writer) {
+		fmt.Fprintln(tw, "NAME\tURL\tAUTH")
+		for _, c := range list.GetConfigs() {
+			printPushConfigRow(tw, c)
+		}
+		if list.GetNextPageToken() != "" {
+			fmt.Fprintf(tw, "\nnext page token: %s\n", list.GetNextPageToken())
+		}
+	})
+}
+
+// runPushGet prints a single push notification config.
+func runPushGet(cmd *cobra.Command, args []string) error {
+	if err := validateOutputFormat(); err != nil {
+		return err
+	}
+	client, err := newAgentClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	resp, err := client.GetTaskPushNotificationConfig(cmd.Context(), connect.NewRequest(&a2apb.GetTaskPushNotificationConfigRequest{
+		Name: pushConfigName(args[0], args[1]),
+	}))
+	if err != nil {
+		return err
+	}
+	return printPushConfig(resp.Msg)
+}
+
+// runPushCreate creates a push notification config and prints the agent's copy.
+func runPushCreate(cmd *cobra.Command, args []string) error {
+	if err := validateOutputFormat(); err != nil {
+		return err
+	}
+	config := &a2apb.PushNotificationConfig{
+		Id:    pushConfigID,
+		Url:   pushURL,
+		Token: pushToken,
+	}
+	if len(pushAuthSchemes) > 0 || pushAuthCredentials != "" {
+		config.Authentication = &a2apb.AuthenticationInfo{
+			Schemes:     pushAuthSchemes,
+			Credentials: pushAuthCredentials,
+		}
+	}
+	// Without an ID, the agent names the config.
+	var name string
+	if pushConfigID != "" {
+		name = pushConfigName(args[0], pushConfigID)
+	}
+
+	client, err := newAgentClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	resp, err := client.CreateTaskPushNotificationConfig(cmd.Context(), connect.NewRequest(&a2apb.CreateTaskPushNotificationConfigRequest{
+		Parent:   taskName(args[0]),
+		ConfigId: pushConfigID,
+		Config: &a2apb.TaskPushNotificationConfig{
+			Name:                   name,
+			PushNotificationConfig: config,
+		},
+	}))
+	if err != nil {
+		return err
+	}
+	return printPushConfig(resp.Msg)
+}
+
+// runPushDelete deletes a push notification config.
+func runPushDelete(cmd *cobra.Command, args []string) error {
+	client, err := newAgentClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	name := pushConfigName(args[0], args[1])
+	if _, err := client.DeleteTaskPushNotificationConfig(cmd.Context(), connect.NewRequest(&a2apb.DeleteTaskPushNotificationConfigRequest{
+		Name: name,
+	})); err != nil {
+		return err
+	}
+	fmt.Printf("Deleted %s\n", name)
+	return nil
+}
+
+// printPushConfig prints a push notification config in the selected output format.
+func printPushConfig(config *a2apb.TaskPushNotificationConfig) error {
+	return printMessage(os.Stdout, config, func(tw *tabwriter.Writer) {
+		fmt.Fprintln(tw, "NAME\tURL\tAUTH")
+		printPushConfigRow(tw, config)
+	})
+}
+
+// printPushConfigRow writes one table row; credentials and tokens are never printed.
+func printPushConfigRow(tw *tabwriter.Writer, c *a2apb.TaskPushNotificationConfig) {
+	auth := strings.Join(c.GetPushNotificationConfig().GetAuthentication().GetSchemes(), ",")
+	if auth == "" {
+		auth = "-"
+	}
+	fmt.Fprintf(tw, "%s\t%s\t%s\n", c.GetName(), c.GetPushNotificationConfig().GetUrl(), auth)
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/a2aproject/a2a-go/a2a"
+	"github.com/a2aproject/a2a-go/a2apb"
+	"github.com/alis-exchange/a2a-playground/gen/go/a2apbconnect"
+	"github.com/alis-exchange/a2a-playground/internal/bff"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// toleranceJSONPath skips response comparison and only evaluates --assert expressions.
+const toleranceJSONPath = "jsonpath"
+
+var (
+	replayTolerance  string
+	replayAsserts    []string
+	replayTimeout    time.Duration
+	replayReportFile string
+)
+
+var replayCmd = &cobra.Command{
+	Use:   "replay-transcript <export-file|context-id>",
+	Short: "Resend a recorded conversation to the agent and diff the responses",
+	Long: `Replay-transcript resends the user turns of a recorded conversation, in order and
+under a new contextId, and compares each result (the final task or message) with
+the original. Turns that continued an input-required task are sent to the
+replayed task instead.
+
+The source is a JSON file written by "export", or the contextId of a recorded
+session (read from --data-dir, or from --server while the playground runs).
+
+Tolerances:
+  exact       identical content; IDs and timestamps are ignored
+  normalized  also ignore case and whitespace in strings (default)
+  structure   compare only fields present, value types and list lengths
+  jsonpath    skip comparison; only evaluate --assert expressions
+
+Assertions run against every replayed result in protojson form, for example
+  --assert '$.status.state == TASK_STATE_COMPLETED'
+  --assert '$.artifacts[0].parts[*].text =~ (?i)invoice'`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReplay,
+}
+
+func init() {
+	addClientFlags(replayCmd)
+	addSessionSourceFlags(replayCmd)
+	replayCmd.Flags().StringVar(&replayTolerance, "tolerance", string(bff.ToleranceNormalized), "Comparison: exact, normalized, structure or jsonpath")
+	replayCmd.Flags().StringArrayVar(&replayAsserts, "assert", nil, "JSONPath assertion on every replayed result (repeatable)")
+	replayCmd.Flags().DurationVar(&replayTimeout, "timeout", 2*time.Minute, "Timeout for each turn")
+	replayCmd.Flags().StringVar(&replayReportFile, "report", "", "Write the JSON diff report to this file")
+	rootCmd.AddCommand(replayCmd)
+}
+
+// transcriptTurn is one user message and the result the agent originally returned.
+type transcriptTurn struct {
+	procedure string
+	request   *a2apb.SendMessageRequest
+	original  proto.Message
+	failed    string
+}
+
+// replayReport is the diff report of a replay run.
+type replayReport struct {
+	Source            string       `json:"source"`
+	OriginalContextID string       `json:"originalContextId"`
+	ContextID         string       `json:"contextId"`
+	AgentURL          string       `json:"agentUrl"`
+	Tolerance         string       `json:"tolerance"`
+	Passed            int          `json:"passed"`
+	Failed            int          `json:"failed"`
+	Turns             []replayTurn `json:"turns"`
+}
+
+// replayTurn is the outcome of one replayed turn.
+type replayTurn struct {
+	Turn             int              `json:"turn"`
+	Prompt           string           `json:"prompt"`
+	OriginalState    string           `json:"originalState"`
+	State            string           `json:"state"`
+	DurationMs       float64          `json:"durationMs"`
+	Passed           bool             `json:"passed"`
+	Error            string           `json:"error,omitempty"`
+	Note             string           `json:"note,omitempty"`
+	Differences      []bff.Difference `json:"differences,omitempty"`
+	FailedAssertions []string         `json:"failedAssertions,omitempty"`
+}
+
+// runReplay replays a transcript and prints the diff report. It fails when any turn differs.
+func runReplay(cmd *cobra.Command, args []string) error {
+	if err := validateOutputFormat(); err != nil {
+		return err
+	}
+	var tol bff.Tolerance
+	if replayTolerance != toleranceJSONPath {
+		var err error
+		if tol, err = bff.ParseTolerance(replayTolerance); err != nil {
+			return err
+		}
+	} else if len(replayAsserts) == 0 {
+		return errors.New("--tolerance=jsonpath needs at least one --assert")
+	}
+	asserts, err := parseAssertions(replayAsserts)
+	if err != nil {
+		return err
+	}
+
+	sess, recs, err := loadTranscript(args[0])
+	if err != nil {
+		return err
+	}
+	turns, err := transcriptTurns(recs)
+	if err != nil {
+		return err
+	}
+	if len(turns) == 0 {
+		return fmt.Errorf("session %s has no user turns to replay", sess.ContextID)
+	}
+
+	agent, err := agentConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAgentClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	report := &replayReport{
+		Source:            args[0],
+		OriginalContextID: sess.ContextID,
+		ContextID:         uuid.NewString(),
+		AgentURL:          agent.URL,
+		Tolerance:         replayTolerance,
+	}
+	// taskIDs maps original task IDs to their replayed counterparts so follow-up turns
+	// land on the replayed task.
+	taskIDs := map[string]string{}
+	for i, turn := range turns {
+		rt := replayTurn{
+			Turn:          i + 1,
+			Prompt:        messageText(turn.request.GetRequest()),
+			OriginalState: resultState(turn.original),
+		}
+		req := proto.Clone(turn.request).(*a2apb.SendMessageRequest)
+		msg := req.GetRequest()
+		msg.MessageId = a2a.NewMessageID()
+		msg.ContextId = report.ContextID
+		if msg.TaskId != "" {
+			if id, ok := taskIDs[msg.TaskId]; ok {
+				msg.TaskId = id
+			} else {
+				rt.Note = "original continued task " + msg.TaskId + ", which has no replayed counterpart"
+				msg.TaskId = ""
+			}
+		}
+		for j, ref := range msg.ReferenceTaskIds {
+			if id, ok := taskIDs[ref]; ok {
+				msg.ReferenceTaskIds[j] = id
+			}
+		}
+
+		start := time.Now()
+		result, err := replayTurnRequest(cmd.Context(), client, turn.procedure, req)
+		rt.DurationMs = millis(time.Since(start))
+		if err != nil {
+			rt.Error = err.Error()
+		} else {
+			rt.State = resultState(result)
+			if orig, ok := turn.original.(*a2apb.Task); ok {
+				if replayed, ok := result.(*a2apb.Task); ok && orig.GetId() != "" {
+					taskIDs[orig.GetId()] = replayed.GetId()
+				}
+			}
+			if err := compareTurn(&rt, turn, result, tol, asserts); err != nil {
+				rt.Error = err.Error()
+			}
+		}
+		rt.Passed = rt.Error == "" && len(rt.Differences) == 0 && len(rt.FailedAssertions) == 0
+		if rt.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+		report.Turns = append(report.Turns, rt)
+	}
+
+	if replayReportFile != "" {
+		b, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(replayReportFile, append(b, '\n'), 0o644); err != nil {
+			return err
+		}
+	}
+	if err := report.print(os.Stdout); err != nil {
+		return err
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d of %d turns differ from the transcript", report.Failed, len(report.Turns))
+	}
+	return nil
+}
+
+// compareTurn fills in the differences and failed assertions of a replayed turn.
+func compareTurn(rt *replayTurn, turn transcriptTurn, result proto.Message, tol bff.Tolerance, asserts []assertion) error {
+	if tol != "" {
+		if turn.original == nil {
+			rt.Note = strings.TrimSpace(rt.Note + " original call failed: " + turn.failed)
+			rt.Differences = append(rt.Differences, bff.Difference{Path: "$", Left: nil, Right: rt.State})
+		} else {
+			diffs, err := bff.CompareMessages(turn.original, result, tol)
+			if err != nil {
+				return err
+			}
+			rt.Differences = diffs
+		}
+	}
+	if len(asserts) == 0 {
+		return nil
+	}
+	b, err := protojson.Marshal(result)
+	if err != nil {
+		return err
+	}
+	var doc any
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return err
+	}
+	for _, a := range asserts {
+		if err := a.check(doc); err != nil {
+			rt.FailedAssertions = append(rt.FailedAssertions, err.Error())
+		}
+	}
+	return nil
+}
+
+// replayTurnRequest sends req with the turn's original procedure and returns the result.
+func replayTurnRequest(ctx context.Context, client a2apbconnect.A2AServiceClient, procedure string, req *a2apb.SendMessageRequest) (proto.Message, error) {
+	ctx, cancel := context.WithTimeout(ctx, replayTimeout)
+	defer cancel()
+	if procedure == a2apbconnect.A2AServiceSendMessageProcedure {
+		resp, err := client.SendMessage(ctx, connect.NewRequest(req))
+		if err != nil {
+			return nil, err
+		}
+		return bff.SendResult(resp.Msg), nil
+	}
+	stream, err := client.SendStreamingMessage(ctx, connect.NewRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+	var events []*a2apb.StreamResponse
+	for stream.Receive() {
+		events = append(events, stream.Msg())
+		if isFinalEvent(stream.Msg()) {
+			break
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, err
+	}
+	return bff.StreamResult(events), nil
+}
+
+// loadTranscript reads an export file, or a recorded session when src is not a file.
+func loadTranscript(src string) (*bff.Session, []bff.SessionRecord, error) {
+	data, err := os.ReadFile(src)
+	if err == nil {
+		return bff.ReadSessionExport(data)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, err
+	}
+	return readSession(src)
+}
+
+// transcriptTurns groups records into user turns. Records of one RPC share a CallID;
+// records from before call IDs were recorded belong to the preceding message.
+func transcriptTurns(recs []bff.SessionRecord) ([]transcriptTurn, error) {
+	var turns []transcriptTurn
+	events := map[int][]*a2apb.StreamResponse{}
+	byCall := map[string]int{}
+	current := -1
+	for _, rec := range recs {
+		i, ok := byCall[rec.CallID]
+		if !ok || rec.CallID == "" {
+			i = current
+		}
+		switch rec.Kind {
+		case bff.RecordKindMessage:
+			if rec.Procedure != a2apbconnect.A2AServiceSendMessageProcedure &&
+				rec.Procedure != a2apbconnect.A2AServiceSendStreamingMessageProcedure {
+				continue
+			}
+			msg := &a2apb.Message{}
+			if err := protojson.Unmarshal(rec.Data, msg); err != nil {
+				return nil, fmt.Errorf("record %d: %w", rec.Seq, err)
+			}
+			turns = append(turns, transcriptTurn{
+				procedure: rec.Procedure,
+				request:   &a2apb.SendMessageRequest{Request: msg},
+			})
+			current = len(turns) - 1
+			if rec.CallID != "" {
+				byCall[rec.CallID] = current
+			}
+		case bff.RecordKindResponse:
+			if i < 0 {
+				continue
+			}
+			resp := &a2apb.SendMessageResponse{}
+			if err := protojson.Unmarshal(rec.Data, resp); err != nil {
+				return nil, fmt.Errorf("record %d: %w", rec.Seq, err)
+			}
+			turns[i].original = bff.SendResult(resp)
+		case bff.RecordKindEvent:
+			if i < 0 {
+				continue
+			}
+			ev := &a2apb.StreamResponse{}
+			if err := protojson.Unmarshal(rec.Data, ev); err != nil {
+				return nil, fmt.Errorf("record %d: %w", rec.Seq, err)
+			}
+			events[i] = append(events[i], ev)
+		case bff.RecordKindCall:
+			if !ok {
+				continue
+			}
+			var info bff.CallInfo
+			if err := json.Unmarshal(rec.Data, &info); err != nil {
+				return nil, fmt.Errorf("record %d: %w", rec.Seq, err)
+			}
+			if info.Error != "" {
+				turns[i].failed = info.Error
+			}
+			// Keep the original configuration and metadata; the message comes from its own record.
+			orig := &a2apb.SendMessageRequest{}
+			if len(info.Request) > 0 && protojson.Unmarshal(info.Request, orig) == nil {
+				orig.Request = turns[i].request.GetRequest()
+				turns[i].request = orig
+			}
+		}
+	}
+	for i, evs := range events {
+		if turns[i].original == nil {
+			turns[i].original = bff.StreamResult(evs)
+		}
+	}
+	return turns, nil
+}
+
+// resultState labels a result with its task state, or "message" for a direct reply.
+func resultState(result proto.Message) string {
+	switch r := result.(type) {
+	case *a2apb.Task:
+		return stateLabel(r.GetStatus().GetState())
+	case *a2apb.Message:
+		return "message"
+	}
+	return "-"
+}
+
+// print writes the report in the selected output format.
+func (r *replayReport) print(w io.Writer) error {
+	return printValue(w, r, func(tw *tabwriter.Writer) {
+		fmt.Fprintf(tw, "Replayed %s as %s against %s (tolerance: %s)\n\n", r.OriginalContextID, r.ContextID, r.AgentURL, r.Tolerance)
+		fmt.Fprintln(tw, "TURN\tPROMPT\tORIGINAL\tREPLAYED\tMS\tRESULT")
+		for _, t := range r.Turns {
+			result := "pass"
+			switch {
+			case t.Error != "":
+				result = "error"
+			case !t.Passed:
+				result = fmt.Sprintf("%d differences, %d failed assertions", len(t.Differences), len(t.FailedAssertions))
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%.0f\t%s\n", t.Turn, t.Prompt, t.OriginalState, t.State, t.DurationMs, result)
+		}
+		_ = tw.Flush()
+		for _, t := range r.Turns {
+			if t.Passed && t.Note == "" {
+				continue
+			}
+			fmt.Fprintf(tw, "\nTurn %d:\n", t.Turn)
+			if t.Error != "" {
+				fmt.Fprintf(tw, "  error: %s\n", t.Error)
+			}
+			if t.Note != "" {
+				fmt.Fprintf(tw, "  note: %s\n", t.Note)
+			}
+			for _, d := range t.Differences {
+				fmt.Fprintf(tw, "  %s\n", d)
+			}
+			for _, a := range t.FailedAssertions {
+				fmt.Fprintf(tw, "  assert %s\n", a)
+			}
+		}
+		fmt.Fprintf(tw, "\n%d passed, %d failed\n", r.Passed, r.Failed)
+	})
+}
@@ -0,0 +1,465 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/a2aproject/a2a-go/a2a"
+	"github.com/a2aproject/a2a-go/a2apb"
+	"github.com/alis-exchange/a2a-playground/internal/bff"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/types/known/structpb"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	runStreaming  bool
+	runTimeout    time.Duration
+	runReportFile string
+)
+
+var runCmd = &cobra.Command{
+	Use:   "run <scenario.yaml>...",
+	Short: "Run scripted multi-turn scenarios against the agent and report pass/fail",
+	Long: `Run executes YAML scenarios: user turns sent in order in one context, each with
+expectations on the result. Turns go through the same proxy as the UI. The command
+exits non-zero when any scenario fails, so it can gate CI.
+
+  name: Refund
+  headers: {Authorization: Bearer test}
+  turns:
+    - send:
+        text: Refund order 42
+        files: [{path: receipt.pdf}]
+        data: [{orderId: 42}]
+      expect:
+        state: input-required
+        events: [task:submitted, status-update:input-required]
+        latency: {firstEvent: 2s, total: 10s}
+      inputRequired:
+        - when: (?i)confirm
+          turns:
+            - send: {text: "yes"}
+              expect:
+                state: completed
+                reply: (?i)refunded
+                artifacts:
+                  - name: receipt
+                    text: "Order 42"
+                    jsonpath: ['$.parts[0].data.amount == 19.99']
+                    schema: {type: object, required: [amount]}
+                assert: ['$.status.state == TASK_STATE_COMPLETED']
+
+A turn sent after one that left its task input-required or auth-required continues
+that task. inputRequired branches are tried in order when the turn ends waiting for
+input: the first whose when regexp matches the agent's prompt runs, and a branch
+without when matches any prompt.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runScenarios,
+}
+
+func init() {
+	addClientFlags(runCmd)
+	runCmd.Flags().BoolVar(&runStreaming, "streaming", true, "Use SendStreamingMessage unless a scenario or turn sets streaming")
+	runCmd.Flags().DurationVar(&runTimeout, "timeout", 2*time.Minute, "Timeout for each turn unless a scenario or turn sets timeout")
+	runCmd.Flags().StringVar(&runReportFile, "report", "", "Write the JSON report to this file")
+	rootCmd.AddCommand(runCmd)
+}
+
+// scenario is a scripted conversation read from a YAML file.
+type scenario struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	// Headers are forwarded to the agent, like -H, which overrides them.
+	Headers   map[string]string `yaml:"headers"`
+	Streaming *bool             `yaml:"streaming"`
+	Timeout   time.Duration     `yaml:"timeout"`
+	Turns     []scenarioTurn    `yaml:"turns"`
+
+	file string
+}
+
+// scenarioTurn is one user message and what the agent should do with it.
+type scenarioTurn struct {
+	Name          string           `yaml:"name"`
+	Send          scenarioMessage  `yaml:"send"`
+	Streaming     *bool            `yaml:"streaming"`
+	Timeout       time.Duration    `yaml:"timeout"`
+	Expect        scenarioExpect   `yaml:"expect"`
+	InputRequired []scenarioBranch `yaml:"inputRequired"`
+}
+
+// scenarioMessage is the content of a user turn.
+type scenarioMessage struct {
+	Text     string           `yaml:"text"`
+	Files    []scenarioFile   `yaml:"files"`
+	Data     []map[string]any `yaml:"data"`
+	Metadata map[string]any   `yaml:"metadata"`
+}
+
+// scenarioFile is a file part: a local path, read relative to the scenario, or a URI.
+type scenarioFile struct {
+	Path     string `yaml:"path"`
+	URI      string `yaml:"uri"`
+	Name     string `yaml:"name"`
+	MimeType string `yaml:"mimeType"`
+}
+
+// scenarioBranch continues a turn that ended waiting for input.
+type scenarioBranch struct {
+	When  string         `yaml:"when"`
+	Turns []scenarioTurn `yaml:"turns"`
+
+	when *regexp.Regexp
+}
+
+// scenarioExpect are the checks on the result of a turn. Unset fields are not checked.
+type scenarioExpect struct {
+	// State is the final task state, or "message" for a direct reply; a list allows any.
+	State stringList `yaml:"state"`
+	// Reply matches the text of the reply message, or of the task's status message.
+	Reply string `yaml:"reply"`
+	// Events must occur in this order, possibly with others in between, as kind or
+	// kind:state, e.g. task, status-update:working or artifact-update.
+	Events    []string         `yaml:"events"`
+	Artifacts []artifactExpect `yaml:"artifacts"`
+	// Assert are JSONPath assertions on the result in protojson form.
+	Assert  []string      `yaml:"assert"`
+	Latency latencyBudget `yaml:"latency"`
+
+	states  []string
+	reply   *regexp.Regexp
+	asserts []assertion
+}
+
+// latencyBudget bounds the time to the first stream event and to the result.
+type latencyBudget struct {
+	FirstEvent time.Duration `yaml:"firstEvent"`
+	Total      time.Duration `yaml:"total"`
+}
+
+// artifactExpect checks one artifact, selected by name or index, or any artifact when
+// neither is set.
+type artifactExpect struct {
+	Name  string `yaml:"name"`
+	Index *int   `yaml:"index"`
+	// Text matches the artifact's text parts, joined by newlines.
+	Text string `yaml:"text"`
+	// JSONPath are assertions on the artifact in protojson form.
+	JSONPath []string `yaml:"jsonpath"`
+	// Schema, or the schema in SchemaFile, validates the artifact's data parts, or its
+	// text as JSON when it has no data parts.
+	Schema     map[string]any `yaml:"schema"`
+	SchemaFile string         `yaml:"schemaFile"`
+
+	text    *regexp.Regexp
+	asserts []assertion
+	schema  map[string]any
+}
+
+// stringList is a YAML scalar or sequence of strings.
+type stringList []string
+
+func (l *stringList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = stringList{node.Value}
+		return nil
+	}
+	var list []string
+	if err := node.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// eventKinds are the kinds an events expectation can name.
+var eventKinds = []string{"task", "message", "status-update", "artifact-update"}
+
+// loadScenario reads and validates a scenario file.
+func loadScenario(path string) (*scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	sc := &scenario{file: path}
+	if err := dec.Decode(sc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if sc.Name == "" {
+		sc.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if len(sc.Turns) == 0 {
+		return nil, fmt.Errorf("%s: no turns", path)
+	}
+	if err := sc.prepareTurns(sc.Turns, "turns"); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return sc, nil
+}
+
+// prepareTurns validates turns and compiles their expectations.
+func (sc *scenario) prepareTurns(turns []scenarioTurn, path string) error {
+	for i := range turns {
+		t := &turns[i]
+		at := fmt.Sprintf("%s[%d]", path, i)
+		if t.Send.Text == "" && len(t.Send.Files) == 0 && len(t.Send.Data) == 0 {
+			return fmt.Errorf("%s.send: needs text, files or data", at)
+		}
+		for j, f := range t.Send.Files {
+			if (f.Path == "") == (f.URI == "") {
+				return fmt.Errorf("%s.send.files[%d]: needs exactly one of path and uri", at, j)
+			}
+		}
+		if len(t.Expect.Events) > 0 && !sc.streaming(t) {
+			return fmt.Errorf("%s.expect.events: needs a streaming turn", at)
+		}
+		if err := sc.prepareExpect(&t.Expect, at+".expect"); err != nil {
+			return err
+		}
+		for j := range t.InputRequired {
+			b := &t.InputRequired[j]
+			bat := fmt.Sprintf("%s.inputRequired[%d]", at, j)
+			if b.When != "" {
+				re, err := regexp.Compile(b.When)
+				if err != nil {
+					return fmt.Errorf("%s.when: %w", bat, err)
+				}
+				b.when = re
+			}
+			if len(b.Turns) == 0 {
+				return fmt.Errorf("%s: no turns", bat)
+			}
+			if err := sc.prepareTurns(b.Turns, bat+".turns"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (sc *scenario) prepareExpect(e *scenarioExpect, path string) error {
+	for _, s := range e.State {
+		if s == "message" {
+			e.states = append(e.states, s)
+			continue
+		}
+		state, err := bff.ParseTaskState(s)
+		if err != nil {
+			return fmt.Errorf("%s.state: %w", path, err)
+		}
+		e.states = append(e.states, stateLabel(state))
+	}
+	if e.Reply != "" {
+		re, err := regexp.Compile(e.Reply)
+		if err != nil {
+			return fmt.Errorf("%s.reply: %w", path, err)
+		}
+		e.reply = re
+	}
+	for _, ev := range e.Events {
+		kind, state, hasState := strings.Cut(ev, ":")
+		if !slices.Contains(eventKinds, kind) {
+			return fmt.Errorf("%s.events: unknown event kind %q (want %s)", path, kind, strings.Join(eventKinds, ", "))
+		}
+		if hasState {
+			if _, err := bff.ParseTaskState(state); err != nil {
+				return fmt.Errorf("%s.events: %w", path, err)
+			}
+		}
+	}
+	var err error
+	if e.asserts, err = parseAssertions(e.Assert); err != nil {
+		return fmt.Errorf("%s.assert: %w", path, err)
+	}
+	for i := range e.Artifacts {
+		a := &e.Artifacts[i]
+		at := fmt.Sprintf("%s.artifacts[%d]", path, i)
+		if a.Name != "" && a.Index != nil {
+			return fmt.Errorf("%s: name and index are mutually exclusive", at)
+		}
+		if a.Text != "" {
+			if a.text, err = regexp.Compile(a.Text); err != nil {
+				return fmt.Errorf("%s.text: %w", at, err)
+			}
+		}
+		if a.asserts, err = parseAssertions(a.JSONPath); err != nil {
+			return fmt.Errorf("%s.jsonpath: %w", at, err)
+		}
+		if a.Schema != nil && a.SchemaFile != "" {
+			return fmt.Errorf("%s: schema and schemaFile are mutually exclusive", at)
+		}
+		var schema any
+		if a.Schema != nil {
+			schema = a.Schema
+		}
+		if a.SchemaFile != "" {
+			data, err := os.ReadFile(sc.resolve(a.SchemaFile))
+			if err != nil {
+				return fmt.Errorf("%s.schemaFile: %w", at, err)
+			}
+			if err := yaml.Unmarshal(data, &schema); err != nil {
+				return fmt.Errorf("%s.schemaFile: %w", at, err)
+			}
+		}
+		if schema != nil {
+			// Through JSON, so numbers are float64 and maps map[string]any, like documents.
+			doc, err := jsonValue(schema)
+			if err != nil {
+				return fmt.Errorf("%s.schema: %w", at, err)
+			}
+			s, ok := doc.(map[string]any)
+			if !ok {
+				return fmt.Errorf("%s.schema: must be an object", at)
+			}
+			if err := checkSchemaDef(s, "$"); err != nil {
+				return fmt.Errorf("%s.schema: %w", at, err)
+			}
+			a.schema = s
+		}
+	}
+	return nil
+}
+
+// streaming reports whether t uses SendStreamingMessage.
+func (sc *scenario) streaming(t *scenarioTurn) bool {
+	switch {
+	case t.Streaming != nil:
+		return *t.Streaming
+	case sc.Streaming != nil:
+		return *sc.Streaming
+	}
+	return runStreaming
+}
+
+// timeout returns the deadline for t.
+func (sc *scenario) timeout(t *scenarioTurn) time.Duration {
+	switch {
+	case t.Timeout > 0:
+		return t.Timeout
+	case sc.Timeout > 0:
+		return sc.Timeout
+	}
+	return runTimeout
+}
+
+// headers returns the scenario's headers overridden by -H.
+func (sc *scenario) headers() (map[string]string, error) {
+	flags, err := parseHeaders(agentHeaders)
+	if err != nil {
+		return nil, err
+	}
+	headers := maps.Clone(sc.Headers)
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	maps.Copy(headers, flags)
+	return headers, nil
+}
+
+// resolve returns path relative to the scenario file.
+func (sc *scenario) resolve(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(sc.file), path)
+}
+
+// message builds the user message of a turn.
+func (sc *scenario) message(m scenarioMessage) (*a2apb.Message, error) {
+	msg := &a2apb.Message{
+		MessageId: a2a.NewMessageID(),
+		Role:      a2apb.Role_ROLE_USER,
+	}
+	if m.Text != "" {
+		msg.Parts = append(msg.Parts, &a2apb.Part{Part: &a2apb.Part_Text{Text: m.Text}})
+	}
+	for _, f := range m.Files {
+		file := &a2apb.FilePart{Name: f.Name, MimeType: f.MimeType}
+		if f.URI != "" {
+			file.File = &a2apb.FilePart_FileWithUri{FileWithUri: f.URI}
+		} else {
+			data, err := os.ReadFile(sc.resolve(f.Path))
+			if err != nil {
+				return nil, err
+			}
+			// file_with_bytes carries base64 text, as the JSON binding does.
+			file.File = &a2apb.FilePart_FileWithBytes{FileWithBytes: []byte(base64.StdEncoding.EncodeToString(data))}
+			if file.Name == "" {
+				file.Name = filepath.Base(f.Path)
+			}
+			if file.MimeType == "" {
+				file.MimeType = mime.TypeByExtension(filepath.Ext(f.Path))
+			}
+		}
+		if file.MimeType == "" {
+			file.MimeType = "application/octet-stream"
+		}
+		msg.Parts = append(msg.Parts, &a2apb.Part{Part: &a2apb.Part_File{File: file}})
+	}
+	for _, d := range m.Data {
+		s, err := structValue(d)
+		if err != nil {
+			return nil, fmt.Errorf("data part: %w", err)
+		}
+		msg.Parts = append(msg.Parts, &a2apb.Part{Part: &a2apb.Part_Data{Data: &a2apb.DataPart{Data: s}}})
+	}
+	if m.Metadata != nil {
+		s, err := structValue(m.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("metadata: %w", err)
+		}
+		msg.Metadata = s
+	}
+	return msg, nil
+}
+
+// structValue converts a YAML mapping to a Struct.
+func structValue(m map[string]any) (*structpb.Struct, error) {
+	v, err := jsonValue(m)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return nil, errors.New("must be a mapping")
+	}
+	return structpb.NewStruct(obj)
+}
+
+// jsonValue round-trips a YAML value through JSON.
+func jsonValue(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	err = json.Unmarshal(b, &out)
+	return out, err
+}
+
+// parseAssertions parses JSONPath assertions.
+func parseAssertions(exprs []string) ([]assertion, error) {
+	asserts := make([]assertion, 0, len(exprs))
+	for _, s := range exprs {
+		a, err := parseAssertion(s)
+		if err != nil {
+			return nil, err
+		}
+		asserts = append(asserts, a)
+	}
+	return asserts, nil
+}
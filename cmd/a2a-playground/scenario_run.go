@@ -0,0 +1,511 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/a2aproject/a2a-go/a2apb"
+	"github.com/alis-exchange/a2a-playground/gen/go/a2apbconnect"
+	"github.com/alis-exchange/a2a-playground/internal/bff"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// scenarioReport is the outcome of a run.
+type scenarioReport struct {
+	AgentURL  string           `json:"agentUrl"`
+	Passed    int              `json:"passed"`
+	Failed    int              `json:"failed"`
+	Scenarios []scenarioResult `json:"scenarios"`
+}
+
+// scenarioResult is the outcome of one scenario.
+type scenarioResult struct {
+	File      string               `json:"file"`
+	Name      string               `json:"name"`
+	ContextID string               `json:"contextId"`
+	Passed    bool                 `json:"passed"`
+	Error     string               `json:"error,omitempty"`
+	Turns     []scenarioTurnResult `json:"turns"`
+}
+
+// scenarioTurnResult is the outcome of one turn. Turns of a branch are numbered under the
+// turn they continue, e.g. 2.1.
+type scenarioTurnResult struct {
+	Turn         string   `json:"turn"`
+	Name         string   `json:"name,omitempty"`
+	Prompt       string   `json:"prompt"`
+	TaskID       string   `json:"taskId,omitempty"`
+	State        string   `json:"state"`
+	Branch       string   `json:"branch,omitempty"`
+	DurationMs   float64  `json:"durationMs"`
+	FirstEventMs float64  `json:"firstEventMs,omitempty"`
+	Events       []string `json:"events,omitempty"`
+	Passed       bool     `json:"passed"`
+	Error        string   `json:"error,omitempty"`
+	Failures     []string `json:"failures,omitempty"`
+}
+
+// runScenarios runs every scenario file and prints the report. It fails when any
+// scenario does.
+func runScenarios(cmd *cobra.Command, args []string) error {
+	if err := validateOutputFormat(); err != nil {
+		return err
+	}
+	scenarios := make([]*scenario, 0, len(args))
+	for _, path := range args {
+		sc, err := loadScenario(path)
+		if err != nil {
+			return err
+		}
+		scenarios = append(scenarios, sc)
+	}
+
+	agent, err := agentConfig()
+	if err != nil {
+		return err
+	}
+	proxy := bff.NewProxy(agent)
+	report := &scenarioReport{AgentURL: agent.URL}
+	for _, sc := range scenarios {
+		result := scenarioResult{File: sc.file, Name: sc.Name, ContextID: uuid.NewString()}
+		if err := runScenario(cmd.Context(), proxy, sc, &result); err != nil {
+			result.Error = err.Error()
+		}
+		result.Passed = result.Error == ""
+		for _, t := range result.Turns {
+			result.Passed = result.Passed && t.Passed
+		}
+		if result.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+		report.Scenarios = append(report.Scenarios, result)
+	}
+
+	if runReportFile != "" {
+		b, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(runReportFile, append(b, '\n'), 0o644); err != nil {
+			return err
+		}
+	}
+	if err := report.print(os.Stdout); err != nil {
+		return err
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d of %d scenarios failed", report.Failed, len(report.Scenarios))
+	}
+	return nil
+}
+
+// runScenario runs the turns of sc in one context, with its own headers.
+func runScenario(ctx context.Context, proxy bff.A2AServiceHandler, sc *scenario, result *scenarioResult) error {
+	headers, err := sc.headers()
+	if err != nil {
+		return err
+	}
+	client, err := bff.NewLocalClient(proxy, headers)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	r := &scenarioRunner{client: client, sc: sc, result: result}
+	r.runTurns(ctx, sc.Turns, "")
+	return nil
+}
+
+// scenarioRunner runs the turns of a scenario, keeping the task the last turn left.
+type scenarioRunner struct {
+	client a2apbconnect.A2AServiceClient
+	sc     *scenario
+	result *scenarioResult
+	task   *a2apb.Task
+	// stopped is set when a call fails; the turns after it are not sent.
+	stopped bool
+}
+
+// runTurns runs turns and the branches they take. prefix numbers them under their parent.
+func (r *scenarioRunner) runTurns(ctx context.Context, turns []scenarioTurn, prefix string) {
+	for i := range turns {
+		if r.stopped {
+			return
+		}
+		t := &turns[i]
+		tr := r.runTurn(ctx, t, fmt.Sprintf("%s%d", prefix, i+1))
+		var branch *scenarioBranch
+		if !r.stopped && len(t.InputRequired) > 0 && r.task != nil && isInterruptedState(r.task.GetStatus().GetState()) {
+			prompt := agentText(r.task)
+			for j := range t.InputRequired {
+				if b := &t.InputRequired[j]; b.when == nil || b.when.MatchString(prompt) {
+					branch = b
+					tr.Branch = b.When
+					if tr.Branch == "" {
+						tr.Branch = "default"
+					}
+					break
+				}
+			}
+			if branch == nil {
+				tr.Failures = append(tr.Failures, fmt.Sprintf("no inputRequired branch matches the prompt %q", prompt))
+			}
+		}
+		tr.Passed = tr.Error == "" && len(tr.Failures) == 0
+		r.result.Turns = append(r.result.Turns, *tr)
+		if branch != nil {
+			r.runTurns(ctx, branch.Turns, tr.Turn+".")
+		}
+	}
+}
+
+// runTurn sends one turn and checks its result.
+func (r *scenarioRunner) runTurn(ctx context.Context, t *scenarioTurn, label string) *scenarioTurnResult {
+	tr := &scenarioTurnResult{Turn: label, Name: t.Name, Prompt: t.Send.Text}
+	msg, err := r.sc.message(t.Send)
+	if err != nil {
+		tr.Error = err.Error()
+		r.stopped = true
+		return tr
+	}
+	msg.ContextId = r.result.ContextID
+	if r.task != nil && isInterruptedState(r.task.GetStatus().GetState()) {
+		msg.TaskId = r.task.GetId()
+	}
+	if tr.Prompt == "" {
+		tr.Prompt = fmt.Sprintf("(%d parts)", len(msg.GetParts()))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.sc.timeout(t))
+	defer cancel()
+	req := &a2apb.SendMessageRequest{Request: msg}
+	start := time.Now()
+	var result proto.Message
+	var events []*a2apb.StreamResponse
+	var firstEvent time.Duration
+	if r.sc.streaming(t) {
+		events, firstEvent, err = r.stream(ctx, req, start)
+		if err == nil {
+			result = bff.StreamResult(events)
+		}
+	} else {
+		req.Configuration = &a2apb.SendMessageConfiguration{Blocking: true}
+		var resp *connect.Response[a2apb.SendMessageResponse]
+		if resp, err = r.client.SendMessage(ctx, connect.NewRequest(req)); err == nil {
+			result = bff.SendResult(resp.Msg)
+		}
+	}
+	total := time.Since(start)
+	tr.DurationMs = millis(total)
+	if len(events) > 0 {
+		tr.FirstEventMs = millis(firstEvent)
+	} else {
+		firstEvent = total
+	}
+	for _, ev := range events {
+		tr.Events = append(tr.Events, eventLabel(ev))
+	}
+	if err != nil {
+		tr.Error = err.Error()
+		r.stopped = true
+		return tr
+	}
+	tr.State = resultState(result)
+	r.task, _ = result.(*a2apb.Task)
+	tr.TaskID = r.task.GetId()
+	tr.Failures = checkExpect(&t.Expect, result, tr, firstEvent, total)
+	return tr
+}
+
+// stream sends req with SendStreamingMessage and collects its events until the final one.
+func (r *scenarioRunner) stream(ctx context.Context, req *a2apb.SendMessageRequest, start time.Time) ([]*a2apb.StreamResponse, time.Duration, error) {
+	stream, err := r.client.SendStreamingMessage(ctx, connect.NewRequest(req))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer stream.Close()
+	var events []*a2apb.StreamResponse
+	var firstEvent time.Duration
+	for stream.Receive() {
+		if len(events) == 0 {
+			firstEvent = time.Since(start)
+		}
+		events = append(events, stream.Msg())
+		if isFinalEvent(stream.Msg()) {
+			break
+		}
+	}
+	return events, firstEvent, stream.Err()
+}
+
+// checkExpect returns the expectations of e that result does not meet.
+func checkExpect(e *scenarioExpect, result proto.Message, tr *scenarioTurnResult, firstEvent, total time.Duration) []string {
+	var failures []string
+	if len(e.states) > 0 && !slices.Contains(e.states, tr.State) {
+		failures = append(failures, fmt.Sprintf("state: want %s, got %s", strings.Join(e.states, " or "), tr.State))
+	}
+	if e.reply != nil {
+		if text := agentText(result); !e.reply.MatchString(text) {
+			failures = append(failures, fmt.Sprintf("reply: %q does not match %s", text, e.Reply))
+		}
+	}
+	if missing := missingEvent(e.Events, tr.Events); missing != "" {
+		failures = append(failures, fmt.Sprintf("events: no %s in order in [%s]", missing, strings.Join(tr.Events, " ")))
+	}
+	if b := e.Latency.FirstEvent; b > 0 && firstEvent > b {
+		failures = append(failures, fmt.Sprintf("latency: first event after %s, budget %s", firstEvent.Round(time.Millisecond), b))
+	}
+	if b := e.Latency.Total; b > 0 && total > b {
+		failures = append(failures, fmt.Sprintf("latency: result after %s, budget %s", total.Round(time.Millisecond), b))
+	}
+	if len(e.asserts) > 0 {
+		doc, err := protoDoc(result)
+		if err != nil {
+			return append(failures, err.Error())
+		}
+		for _, a := range e.asserts {
+			if err := a.check(doc); err != nil {
+				failures = append(failures, "assert "+err.Error())
+			}
+		}
+	}
+	task, _ := result.(*a2apb.Task)
+	for i := range e.Artifacts {
+		failures = append(failures, checkArtifacts(&e.Artifacts[i], task.GetArtifacts())...)
+	}
+	return failures
+}
+
+// missingEvent returns the first pattern that does not occur, in order, in events.
+func missingEvent(patterns, events []string) string {
+	next := 0
+	for _, p := range patterns {
+		found := false
+		for next < len(events) {
+			ev := events[next]
+			next++
+			if eventMatches(p, ev) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return p
+		}
+	}
+	return ""
+}
+
+// eventMatches reports whether the event label ev matches the pattern p: a kind matches
+// events of any state, and a state may be written as TASK_STATE_WORKING.
+func eventMatches(p, ev string) bool {
+	kind, state, ok := strings.Cut(p, ":")
+	evKind, _, _ := strings.Cut(ev, ":")
+	if !ok {
+		return evKind == kind
+	}
+	s, err := bff.ParseTaskState(state)
+	return err == nil && ev == kind+":"+stateLabel(s)
+}
+
+// checkArtifacts checks the artifact a selects, or that some artifact passes a.
+func checkArtifacts(a *artifactExpect, artifacts []*a2apb.Artifact) []string {
+	label := "artifact"
+	var selected *a2apb.Artifact
+	switch {
+	case a.Name != "":
+		label = fmt.Sprintf("artifact %q", a.Name)
+		for _, art := range artifacts {
+			if art.GetName() == a.Name {
+				selected = art
+				break
+			}
+		}
+	case a.Index != nil:
+		label = fmt.Sprintf("artifact %d", *a.Index)
+		if i := *a.Index; i >= 0 && i < len(artifacts) {
+			selected = artifacts[i]
+		}
+	default:
+		if len(artifacts) == 0 {
+			return []string{"artifacts: the result has none"}
+		}
+		// Any artifact will do; report the one that came closest.
+		var best []string
+		for i, art := range artifacts {
+			failures := checkArtifact(a, art, fmt.Sprintf("artifact %d", i))
+			if len(failures) == 0 {
+				return nil
+			}
+			if best == nil || len(failures) < len(best) {
+				best = failures
+			}
+		}
+		return best
+	}
+	if selected == nil {
+		return []string{label + ": not in the result"}
+	}
+	return checkArtifact(a, selected, label)
+}
+
+// checkArtifact returns the checks of a that art fails.
+func checkArtifact(a *artifactExpect, art *a2apb.Artifact, label string) []string {
+	var failures []string
+	var texts []string
+	var data []any
+	for _, p := range art.GetParts() {
+		if t := p.GetText(); t != "" {
+			texts = append(texts, t)
+		}
+		if d := p.GetData(); d != nil {
+			data = append(data, d.GetData().AsMap())
+		}
+	}
+	text := strings.Join(texts, "\n")
+	if a.text != nil && !a.text.MatchString(text) {
+		failures = append(failures, fmt.Sprintf("%s: text does not match %s", label, a.Text))
+	}
+	if len(a.asserts) > 0 {
+		doc, err := protoDoc(art)
+		if err != nil {
+			return append(failures, err.Error())
+		}
+		for _, as := range a.asserts {
+			if err := as.check(doc); err != nil {
+				failures = append(failures, label+": "+err.Error())
+			}
+		}
+	}
+	if a.schema != nil {
+		if len(data) == 0 && text != "" {
+			var doc any
+			if err := json.Unmarshal([]byte(text), &doc); err != nil {
+				return append(failures, fmt.Sprintf("%s: schema: no data parts, and the text is not JSON", label))
+			}
+			data = append(data, doc)
+		}
+		if len(data) == 0 {
+			return append(failures, fmt.Sprintf("%s: schema: no data parts", label))
+		}
+		for _, d := range data {
+			// AsMap numbers are already float64, as validateSchema expects.
+			for _, v := range validateSchema(a.schema, d) {
+				failures = append(failures, fmt.Sprintf("%s: schema: %s", label, v))
+			}
+		}
+	}
+	return failures
+}
+
+// agentText returns the text of a reply message, or of the task's status message, or
+// else of the agent's last message in the task history.
+func agentText(result proto.Message) string {
+	var msg *a2apb.Message
+	switch r := result.(type) {
+	case *a2apb.Message:
+		msg = r
+	case *a2apb.Task:
+		msg = r.GetStatus().GetUpdate()
+		if len(msg.GetParts()) == 0 {
+			for _, m := range r.GetHistory() {
+				if m.GetRole() == a2apb.Role_ROLE_AGENT {
+					msg = m
+				}
+			}
+		}
+	}
+	var texts []string
+	for _, p := range msg.GetParts() {
+		if t := p.GetText(); t != "" {
+			texts = append(texts, t)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// eventLabel names a stream event as an events expectation does: its kind, and for tasks
+// and status updates its state, e.g. status-update:working.
+func eventLabel(ev *a2apb.StreamResponse) string {
+	kind := bff.EventKind(ev)
+	switch p := ev.GetPayload().(type) {
+	case *a2apb.StreamResponse_Task:
+		return kind + ":" + stateLabel(p.Task.GetStatus().GetState())
+	case *a2apb.StreamResponse_StatusUpdate:
+		return kind + ":" + stateLabel(p.StatusUpdate.GetStatus().GetState())
+	}
+	return kind
+}
+
+// protoDoc decodes msg's protojson form for JSONPath assertions.
+func protoDoc(msg proto.Message) (any, error) {
+	b, err := protojson.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	var doc any
+	err = json.Unmarshal(b, &doc)
+	return doc, err
+}
+
+// print writes the report in the selected output format.
+func (r *scenarioReport) print(w io.Writer) error {
+	return printValue(w, r, func(tw *tabwriter.Writer) {
+		for i, sc := range r.Scenarios {
+			if i > 0 {
+				fmt.Fprintln(tw)
+			}
+			result := "PASS"
+			if !sc.Passed {
+				result = "FAIL"
+			}
+			fmt.Fprintf(tw, "%s %s (%s, context %s)\n", result, sc.Name, sc.File, sc.ContextID)
+			fmt.Fprintln(tw, "TURN\tPROMPT\tSTATE\tEVENTS\tMS\tRESULT")
+			for _, t := range sc.Turns {
+				result := "pass"
+				switch {
+				case t.Error != "":
+					result = "error"
+				case !t.Passed:
+					result = fmt.Sprintf("%d failed", len(t.Failures))
+				}
+				prompt := t.Prompt
+				if t.Name != "" {
+					prompt = t.Name
+				}
+				if len(prompt) > 40 {
+					prompt = prompt[:37] + "..."
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%.0f\t%s\n", t.Turn, prompt, t.State, len(t.Events), t.DurationMs, result)
+			}
+			_ = tw.Flush()
+			if sc.Error != "" {
+				fmt.Fprintf(tw, "  error: %s\n", sc.Error)
+			}
+			for _, t := range sc.Turns {
+				if t.Branch != "" {
+					fmt.Fprintf(tw, "  turn %s: took inputRequired branch %s\n", t.Turn, t.Branch)
+				}
+				if t.Error != "" {
+					fmt.Fprintf(tw, "  turn %s: error: %s\n", t.Turn, t.Error)
+				}
+				for _, f := range t.Failures {
+					fmt.Fprintf(tw, "  turn %s: %s\n", t.Turn, f)
+				}
+			}
+		}
+		fmt.Fprintf(tw, "\n%d passed, %d failed\n", r.Passed, r.Failed)
+	})
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/a2aproject/a2a-go/a2apb"
+	"github.com/alis-exchange/a2a-playground/gen/go/a2apbconnect"
+	"github.com/alis-exchange/a2a-playground/internal/bff"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	taskContextID        string
+	taskState            string
+	taskPageSize         int32
+	taskPageToken        string
+	taskAllPages         bool
+	taskHistoryLength    int32
+	taskUpdatedAfter     string
+	taskIncludeArtifacts bool
+	taskCancelDryRun     bool
+)
+
+var tasksCmd = &cobra.Command{
+	Use:   "tasks",
+	Short: "List, inspect, cancel and watch agent tasks",
+}
+
+var tasksListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List tasks (requires an agent that implements ListTasks)",
+	Args:  cobra.NoArgs,
+	RunE:  runTasksList,
+}
+
+var tasksGetCmd = &cobra.Command{
+	Use:   "get <task-id>",
+	Short: "Get a task",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTasksGet,
+}
+
+var tasksCancelCmd = &cobra.Command{
+	Use:   "cancel [task-id...]",
+	Short: "Cancel tasks by ID, or every non-terminal task in a context with --context-id",
+	RunE:  runTasksCancel,
+}
+
+var tasksWatchCmd = &cobra.Command{
+	Use:   "watch <task-id>",
+	Short: "Subscribe to a task and print its events until the stream ends",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTasksWatch,
+}
+
+func init() {
+	addClientFlags(tasksCmd)
+
+	tasksListCmd.Flags().StringVar(&taskContextID, "context-id", "", "Only list tasks in this context")
+	tasksListCmd.Flags().StringVar(&taskState, "state", "", "Only list tasks in this state (e.g. working, input-required)")
+	tasksListCmd.Flags().Int32Var(&taskPageSize, "page-size", 0, "Maximum tasks per page (agent default when 0)")
+	tasksListCmd.Flags().StringVar(&taskPageToken, "page-token", "", "Page token from a previous list")
+	tasksListCmd.Flags().BoolVar(&taskAllPages, "all", false, "Follow next-page tokens and list every matching task")
+	tasksListCmd.Flags().Int32Var(&taskHistoryLength, "history-length", 0, "Number of recent messages to include per task")
+	tasksListCmd.Flags().StringVar(&taskUpdatedAfter, "updated-after", "", "Only list tasks updated at or after this RFC 3339 time")
+	tasksListCmd.Flags().BoolVar(&taskIncludeArtifacts, "include-artifacts", false, "Include artifacts in the listed tasks")
+
+	tasksGetCmd.Flags().Int32Var(&taskHistoryLength, "history-length", 0, "Number of recent messages to include")
+
+	tasksCancelCmd.Flags().StringVar(&taskContextID, "context-id", "", "Cancel every non-terminal task in this context")
+	tasksCancelCmd.Flags().BoolVar(&taskCancelDryRun, "dry-run", false, "Print the tasks that would be cancelled without cancelling them")
+
+	tasksCmd.AddCommand(tasksListCmd, tasksGetCmd, tasksCancelCmd, tasksWatchCmd)
+	rootCmd.AddCommand(tasksCmd)
+}
+
+// runTasksList lists tasks, optionally following every page.
+func runTasksList(cmd *cobra.Command, args []string) error {
+	if err := validateOutputFormat(); err != nil {
+		return err
+	}
+	req := &a2apb.ListTasksRequest{
+		ContextId:        taskContextID,
+		PageSize:         taskPageSize,
+		PageToken:        taskPageToken,
+		HistoryLength:    taskHistoryLength,
+		IncludeArtifacts: taskIncludeArtifacts,
+	}
+	if taskState != "" {
+		state, err := bff.ParseTaskState(taskState)
+		if err != nil {
+			return err
+		}
+		req.Status = state
+	}
+	if taskUpdatedAfter != "" {
+		t, err := time.Parse(time.RFC3339, taskUpdatedAfter)
+		if err != nil {
+			return fmt.Errorf("invalid --updated-after: %w", err)
+		}
+		req.LastUpdatedTime = timestamppb.New(t)
+	}
+
+	client, err := newAgentClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	resp, err := listTasks(cmd, client, req, taskAllPages)
+	if err != nil {
+		return err
+	}
+	return printMessage(os.Stdout, resp, func(tw *tabwriter.Writer) {
+		fmt.Fprintln(tw, "TASK\tCONTEXT\tSTATE\tUPDATED")
+		for _, t := range resp.GetTasks() {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", t.GetId(), t.GetContextId(), stateLabel(t.GetStatus().GetState()), formatTimestamp(t.GetStatus().GetTimestamp()))
+		}
+		if resp.GetNextPageToken() != "" {
+			fmt.Fprintf(tw, "\nnext page token: %s\n", resp.GetNextPageToken())
+		}
+	})
+}
+
+// listTasks calls ListTasks, merging every page into one response when all is set.
+func listTasks(cmd *cobra.Command, client a2apbconnect.A2AServiceClient, req *a2apb.ListTasksRequest, all bool) (*a2apb.ListTasksResponse, error) {
+	merged := &a2apb.ListTasksResponse{}
+	for {
+		resp, err := client.ListTasks(cmd.Context(), connect.NewRequest(req))
+		if err != nil {
+			return nil, err
+		}
+		merged.Tasks = append(merged.Tasks, resp.Msg.GetTasks()...)
+		merged.TotalSize = resp.Msg.GetTotalSize()
+		merged.NextPageToken = resp.Msg.GetNextPageToken()
+		if !all || merged.NextPageToken == "" {
+			return merged, nil
+		}
+		req.PageToken = merged.NextPageToken
+	}
+}
+
+// runTasksGet prints a single task.
+func runTasksGet(cmd *cobra.Command, args []string) error {
+	if err := validateOutputFormat(); err != nil {
+		return err
+	}
+	client, err := newAgentClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	resp, err := client.GetTask(cmd.Context(), connect.NewRequest(&a2apb.GetTaskRequest{
+		Name:          taskName(args[0]),
+		HistoryLength: taskHistoryLength,
+	}))
+	if err != nil {
+		return err
+	}
+	task := resp.Msg
+	return printMessage(os.Stdout, task, func(tw *tabwriter.Writer) {
+		fmt.Fprintf(tw, "Task:\t%s\n", task.GetId())
+		fmt.Fprintf(tw, "Context:\t%s\n", task.GetContextId())
+		fmt.Fprintf(tw, "State:\t%s\n", stateLabel(task.GetStatus().GetState()))
+		fmt.Fprintf(tw, "Updated:\t%s\n", formatTimestamp(task.GetStatus().GetTimestamp()))
+		if text := messageText(task.GetStatus().GetUpdate()); text != "" {
+			fmt.Fprintf(tw, "Status message:\t%s\n", text)
+		}
+		fmt.Fprintf(tw, "Artifacts:\t%d\n", len(task.GetArtifacts()))
+		fmt.Fprintf(tw, "History:\t%d messages\n", len(task.GetHistory()))
+	})
+}
+
+// runTasksCancel cancels the given tasks, or every non-terminal task in --context-id.
+func runTasksCancel(cmd *cobra.Command, args []string) error {
+	if err := validateOutputFormat(); err != nil {
+		return err
+	}
+	if taskContextID == "" && len(args) == 0 {
+		return fmt.Errorf("specify task IDs or --context-id")
+	}
+	if taskContextID != "" && len(args) > 0 {
+		return fmt.Errorf("task IDs and --context-id are mutually exclusive")
+	}
+
+	client, err := newAgentClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	names := make([]string, 0, len(args))
+	for _, id := range args {
+		names = append(names, taskName(id))
+	}
+	if taskContextID != "" {
+		listed, err := listTasks(cmd, client, &a2apb.ListTasksRequest{ContextId: taskContextID}, true)
+		if err != nil {
+			return fmt.Errorf("list tasks in context %s: %w", taskContextID, err)
+		}
+		for _, t := range listed.GetTasks() {
+			if !isTerminalState(t.GetStatus().GetState()) {
+				names = append(names, taskName(t.GetId()))
+			}
+		}
+	}
+	if taskCancelDryRun {
+		for _, name := range names {
+			fmt.Println(name)
+		}
+		return nil
+	}
+
+	cancelled := &a2apb.ListTasksResponse{}
+	var failed int
+	for _, name := range names {
+		resp, err := client.CancelTask(cmd.Context(), connect.NewRequest(&a2apb.CancelTaskRequest{Name: name}))
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "cancel %s: %v\n", name, err)
+			continue
+		}
+		cancelled.Tasks = append(cancelled.Tasks, resp.Msg)
+	}
+	cancelled.TotalSize = int32(len(cancelled.Tasks))
+
+	if err := printMessage(os.Stdout, cancelled, func(tw *tabwriter.Writer) {
+		fmt.Fprintln(tw, "TASK\tCONTEXT\tSTATE")
+		for _, t := range cancelled.GetTasks() {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", t.GetId(), t.GetContextId(), stateLabel(t.GetStatus().GetState()))
+		}
+	}); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d cancellations failed", failed, len(names))
+	}
+	return nil
+}
+
+// runTasksWatch streams task events until the agent closes the subscription.
+func runTasksWatch(cmd *cobra.Command, args []string) error {
+	if err := validateOutputFormat(); err != nil {
+		return err
+	}
+	client, err := newAgentClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	stream, err := client.TaskSubscription(cmd.Context(), connect.NewRequest(&a2apb.TaskSubscriptionRequest{
+		Name: taskName(args[0]),
+	}))
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	if outputFormat == outputTable {
+		fmt.Fprintln(tw, "TIME\tEVENT\tTASK\tSTATE\tDETAIL")
+	}
+	for stream.Receive() {
+		ev := stream.Msg()
+		if err := printStreamItem(os.Stdout, ev, func(w io.Writer) {
+			kind, taskID, state, detail := describeEvent(ev)
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", time.Now().Format(time.TimeOnly), kind, taskID, state, detail)
+			_ = tw.Flush()
+		}); err != nil {
+			return err
+		}
+	}
+	return stream.Err()
+}
+
+// describeEvent summarizes a stream event for table output.
+func describeEvent(ev *a2apb.StreamResponse) (kind, taskID, state, detail string) {
+	switch p := ev.GetPayload().(type) {
+	case *a2apb.StreamResponse_Task:
+		return "task", p.Task.GetId(), stateLabel(p.Task.GetStatus().GetState()), messageText(p.Task.GetStatus().GetUpdate())
+	case *a2apb.StreamResponse_Msg:
+		return "message", p.Msg.GetTaskId(), "", messageText(p.Msg)
+	case *a2apb.StreamResponse_StatusUpdate:
+		detail := messageText(p.StatusUpdate.GetStatus().GetUpdate())
+		if p.StatusUpdate.GetFinal() {
+			detail = strings.TrimSpace("(final) " + detail)
+		}
+		return "status", p.StatusUpdate.GetTaskId(), stateLabel(p.StatusUpdate.GetStatus().GetState()), detail
+	case *a2apb.StreamResponse_ArtifactUpdate:
+		a := p.ArtifactUpdate.GetArtifact()
+		return "artifact", p.ArtifactUpdate.GetTaskId(), "", fmt.Sprintf("%s (%d parts)", a.GetArtifactId(), len(a.GetParts()))
+	}
+	return "unknown", "", "", ""
+}
+
+// messageText joins the text parts of msg, truncated for single-line display.
+func messageText(msg *a2apb.Message) string {
+	var parts []string
+	for _, p := range msg.GetParts() {
+		if t := p.GetText(); t != "" {
+			parts = append(parts, t)
+		}
+	}
+	text := strings.Join(strings.Fields(strings.Join(parts, " ")), " ")
+	if r := []rune(text); len(r) > 80 {
+		text = string(r[:77]) + "..."
+	}
+	return text
+}
+
+// formatTimestamp formats ts in local time, or "-" when unset.
+func formatTimestamp(ts *timestamppb.Timestamp) string {
+	if ts == nil {
+		return "-"
+	}
+	return ts.AsTime().Local().Format(time.RFC3339)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alis-exchange/a2a-playground/internal/bff"
+	"github.com/spf13/cobra"
+)
+
+var (
+	searchStates []string
+	searchSkill  string
+	searchSince  string
+	searchUntil  string
+	searchText   string
+	searchLimit  int
+)
+
+var tasksSearchCmd = &cobra.Command{
+	Use:   "search",
+	Short: "Search the tasks the playground has seen, without asking the agent",
+	Long: `Search the playground's task cache: every task that went through the BFF, across
+sessions, as of its latest event. The agent does not need to implement ListTasks.
+
+  a2a-playground tasks search --state failed --since 24h
+  a2a-playground tasks search -q "invoice" --server http://localhost:3000`,
+	Args: cobra.NoArgs,
+	RunE: runTasksSearch,
+}
+
+func init() {
+	tasksSearchCmd.Flags().StringVar(&taskContextID, "context-id", "", "Only tasks in this context")
+	tasksSearchCmd.Flags().StringSliceVar(&searchStates, "state", nil, "Only tasks in these states (e.g. failed,canceled)")
+	tasksSearchCmd.Flags().StringVar(&searchSkill, "skill", "", "Only tasks for this skill")
+	tasksSearchCmd.Flags().StringVar(&searchSince, "since", "", "Only tasks updated since this RFC 3339 time or duration ago (e.g. 24h)")
+	tasksSearchCmd.Flags().StringVar(&searchUntil, "until", "", "Only tasks created until this RFC 3339 time or duration ago")
+	tasksSearchCmd.Flags().StringVarP(&searchText, "query", "q", "", "Only tasks whose messages or artifacts contain this text")
+	tasksSearchCmd.Flags().IntVar(&searchLimit, "limit", 100, "Maximum tasks to print; 0 for all")
+	addSessionSourceFlags(tasksSearchCmd)
+	tasksCmd.AddCommand(tasksSearchCmd)
+}
+
+// runTasksSearch searches the task cache of --server, or the local one.
+func runTasksSearch(cmd *cobra.Command, args []string) error {
+	if err := validateOutputFormat(); err != nil {
+		return err
+	}
+	params := url.Values{}
+	for name, v := range map[string]string{
+		"contextId": taskContextID,
+		"state":     strings.Join(searchStates, ","),
+		"skill":     searchSkill,
+		"since":     searchSince,
+		"until":     searchUntil,
+		"q":         searchText,
+		"limit":     strconv.Itoa(searchLimit),
+	} {
+		if v != "" {
+			params.Set(name, v)
+		}
+	}
+	// Task IDs are only unique within an agent; an explicit --agent-url narrows the search.
+	if cmd.Flags().Changed("agent-url") {
+		params.Set("agentUrl", agentURL)
+	}
+	var tasks []bff.CachedTask
+	var err error
+	if sessionServer != "" {
+		tasks, err = fetchTasks(sessionServer, params)
+	} else {
+		tasks, err = searchStoredTasks(params)
+	}
+	if err != nil {
+		return err
+	}
+	return printValue(os.Stdout, tasks, func(tw *tabwriter.Writer) {
+		fmt.Fprintln(tw, "TASK\tCONTEXT\tSTATE\tSKILL\tUPDATED")
+		for _, t := range tasks {
+			state, _ := bff.ParseTaskState(t.State)
+			skill := t.Skill
+			if skill == "" {
+				skill = "-"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", t.ID, t.ContextID, stateLabel(state), skill, t.UpdatedAt.Local().Format(time.RFC3339))
+		}
+	})
+}
+
+// searchStoredTasks searches the task cache in --data-dir.
+func searchStoredTasks(params url.Values) ([]bff.CachedTask, error) {
+	q, err := bff.ParseTaskQuery(params, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	dir, err := resolveDataDir()
+	if err != nil {
+		return nil, err
+	}
+	// Read-only: searching must not drop what the playground keeps, nor lock it out.
+	cache, err := bff.OpenTaskCacheReadOnly(dir)
+	if err != nil {
+		return nil, fmt.Errorf("%w; to search a running playground use --server", err)
+	}
+	defer cache.Close()
+	return cache.Search(q)
+}
+
+// fetchTasks searches the task cache of a running playground.
+func fetchTasks(server string, params url.Values) ([]bff.CachedTask, error) {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(server, "/")+"/api/tasks?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	// Basic auth can be given in the URL; a token comes from the environment.
+	if token := os.Getenv(tokenEnv); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var out struct {
+		Tasks []bff.CachedTask `json:"tasks"`
+		Error string           `json:"error"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil || resp.StatusCode != http.StatusOK {
+		if out.Error != "" {
+			return nil, fmt.Errorf("search tasks: %s", out.Error)
+		}
+		return nil, fmt.Errorf("search tasks: %s", resp.Status)
+	}
+	return out.Tasks, nil
+}
@@ -0,0 +1,481 @@
+package bff
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+
+	"github.com/a2aproject/a2a-go/a2a"
+	"github.com/a2aproject/a2a-go/a2apb"
+	"github.com/a2aproject/a2a-go/a2apb/pbconv"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// v1Codec encodes the generated v0.3 messages in the A2A v1 wire format and decodes v1
+// messages into them, so the proxies and the gateway can speak v1 without v1 types. Most
+// messages kept their field numbers; v1 changed parts, the agent card, push notification
+// configs and task IDs, which this rewrites.
+//
+// It is binary only, and stands in for the "proto" codec of both gRPC and Connect.
+type v1Codec struct{}
+
+func (v1Codec) Name() string {
+	return "proto"
+}
+
+func (v1Codec) Marshal(v any) ([]byte, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("v1 codec: %T is not a proto message", v)
+	}
+	msg = proto.Clone(msg)
+	walkMessages(msg.ProtoReflect(), toV1)
+	return proto.Marshal(msg)
+}
+
+func (v1Codec) Unmarshal(b []byte, v any) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("v1 codec: %T is not a proto message", v)
+	}
+	b, err := partsFromV1(b, msg.ProtoReflect().Descriptor())
+	if err != nil {
+		return err
+	}
+	if err := proto.Unmarshal(b, msg); err != nil {
+		return err
+	}
+	walkMessages(msg.ProtoReflect(), fromV1)
+	return nil
+}
+
+// walkMessages calls fn for m and every message nested in it, parents first. Maps and
+// well-known types, which hold no A2A messages, are skipped.
+func walkMessages(m protoreflect.Message, fn func(protoreflect.Message)) {
+	fn(m)
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.Message() == nil || fd.IsMap() || isWellKnown(fd.Message()) {
+			return true
+		}
+		if fd.IsList() {
+			for i := range v.List().Len() {
+				walkMessages(v.List().Get(i).Message(), fn)
+			}
+			return true
+		}
+		walkMessages(v.Message(), fn)
+		return true
+	})
+}
+
+func isWellKnown(md protoreflect.MessageDescriptor) bool {
+	return strings.HasPrefix(string(md.FullName()), "google.protobuf.")
+}
+
+// toV1 rewrites a v0.3 message in place so that it marshals as its v1 counterpart. Fields
+// v1 added are carried as unknown fields.
+func toV1(m protoreflect.Message) {
+	switch x := m.Interface().(type) {
+	case *a2apb.Part:
+		b := partToV1(x)
+		proto.Reset(x)
+		m.SetUnknown(b)
+	case *a2apb.GetTaskRequest:
+		x.Name = bareTaskID(x.Name)
+	case *a2apb.CancelTaskRequest:
+		x.Name = bareTaskID(x.Name)
+	case *a2apb.TaskSubscriptionRequest:
+		x.Name = bareTaskID(x.Name)
+	case *a2apb.ListTaskPushNotificationConfigRequest:
+		x.Parent = bareTaskID(x.Parent)
+	case *a2apb.GetTaskPushNotificationConfigRequest:
+		x.Name = configNameToV1(m, x.Name)
+	case *a2apb.DeleteTaskPushNotificationConfigRequest:
+		x.Name = configNameToV1(m, x.Name)
+	case *a2apb.TaskPushNotificationConfig:
+		x.Name = configNameToV1(m, x.Name)
+	case *a2apb.CreateTaskPushNotificationConfigRequest:
+		// v1 takes the PushNotificationConfig itself as field 5.
+		x.Parent = bareTaskID(x.Parent)
+		if c := x.GetConfig().GetPushNotificationConfig(); c != nil {
+			b, _ := proto.Marshal(c)
+			addUnknownBytes(m, 5, b)
+		}
+		x.Config = nil
+	case *a2apb.TaskStatusUpdateEvent:
+		// v1 dropped final: the stream ends instead.
+		x.Final = false
+	case *a2apb.ListTasksResponse:
+		// total_size moved to make room for page_size.
+		if x.TotalSize != 0 {
+			addUnknownVarint(m, 4, uint64(x.TotalSize))
+			x.TotalSize = 0
+		}
+	case *a2apb.AgentCard:
+		cardToV1(x)
+	}
+}
+
+// fromV1 rewrites a message decoded from its v1 encoding in place, moving what was left in
+// unknown fields to where v0.3 keeps it.
+func fromV1(m protoreflect.Message) {
+	switch x := m.Interface().(type) {
+	case *a2apb.GetTaskRequest:
+		x.Name = taskName(x.Name)
+	case *a2apb.CancelTaskRequest:
+		x.Name = taskName(x.Name)
+	case *a2apb.TaskSubscriptionRequest:
+		x.Name = taskName(x.Name)
+	case *a2apb.ListTaskPushNotificationConfigRequest:
+		x.Parent = taskName(x.Parent)
+	case *a2apb.GetTaskPushNotificationConfigRequest:
+		x.Name = configNameFromV1(m, x.Name)
+	case *a2apb.DeleteTaskPushNotificationConfigRequest:
+		x.Name = configNameFromV1(m, x.Name)
+	case *a2apb.TaskPushNotificationConfig:
+		x.Name = configNameFromV1(m, x.Name)
+	case *a2apb.CreateTaskPushNotificationConfigRequest:
+		x.Parent = taskName(x.Parent)
+		for _, f := range takeUnknown(m, 5) {
+			c := &a2apb.PushNotificationConfig{}
+			if proto.Unmarshal(f.bytes, c) != nil {
+				continue
+			}
+			id := cmp.Or(x.ConfigId, c.Id)
+			x.Config = &a2apb.TaskPushNotificationConfig{Name: configName(x.Parent, id), PushNotificationConfig: c}
+		}
+	case *a2apb.TaskStatusUpdateEvent:
+		x.Final = finalState(x.GetStatus().GetState())
+	case *a2apb.ListTasksResponse:
+		// Field 3 decoded as total_size is v1's page_size.
+		x.TotalSize = 0
+		for _, f := range takeUnknown(m, 4) {
+			x.TotalSize = int32(f.varint)
+		}
+	case *a2apb.AgentCard:
+		cardFromV1(x)
+	}
+}
+
+// finalState reports whether a task in state waits for no more events: it is done or
+// needs input.
+func finalState(state a2apb.TaskState) bool {
+	switch state {
+	case a2apb.TaskState_TASK_STATE_COMPLETED, a2apb.TaskState_TASK_STATE_FAILED, a2apb.TaskState_TASK_STATE_CANCELLED,
+		a2apb.TaskState_TASK_STATE_REJECTED, a2apb.TaskState_TASK_STATE_INPUT_REQUIRED, a2apb.TaskState_TASK_STATE_AUTH_REQUIRED:
+		return true
+	}
+	return false
+}
+
+// bareTaskID returns the task ID of a "tasks/{id}" name, which is what v1 requests carry.
+func bareTaskID(name string) string {
+	if id, err := pbconv.ExtractTaskID(name); err == nil {
+		return string(id)
+	}
+	return name
+}
+
+// taskName returns the "tasks/{id}" name for a task ID from a v1 request.
+func taskName(id string) string {
+	if id == "" || strings.HasPrefix(id, "tasks/") {
+		return id
+	}
+	return pbconv.MakeTaskName(a2a.TaskID(id))
+}
+
+func configName(task, id string) string {
+	return pbconv.MakeConfigName(a2a.TaskID(bareTaskID(task)), id)
+}
+
+// configNameToV1 splits a push notification config name into the config ID, which it
+// returns, and the task ID, which v1 keeps in field 3 of m.
+func configNameToV1(m protoreflect.Message, name string) string {
+	id, err := pbconv.ExtractConfigID(name)
+	if err != nil {
+		return name
+	}
+	if task, err := pbconv.ExtractTaskID(name); err == nil {
+		addUnknownBytes(m, 3, []byte(task))
+	}
+	return id
+}
+
+// configNameFromV1 is the inverse of configNameToV1.
+func configNameFromV1(m protoreflect.Message, id string) string {
+	task := takeUnknown(m, 3)
+	if len(task) == 0 {
+		return id
+	}
+	return configName(string(task[0].bytes), id)
+}
+
+// partToV1 encodes p as a v1 Part, which has the file and data fields inline.
+func partToV1(p *a2apb.Part) []byte {
+	var b []byte
+	switch {
+	case p.GetFile() != nil:
+		f := p.GetFile()
+		if f.GetFileWithUri() != "" {
+			b = appendString(b, 3, f.GetFileWithUri())
+		} else {
+			b = protowire.AppendTag(b, 2, protowire.BytesType)
+			b = protowire.AppendBytes(b, f.GetFileWithBytes())
+		}
+		if f.GetName() != "" {
+			b = appendString(b, 6, f.GetName())
+		}
+		if f.GetMimeType() != "" {
+			b = appendString(b, 7, f.GetMimeType())
+		}
+	case p.GetData() != nil:
+		v, _ := proto.Marshal(structpb.NewStructValue(p.GetData().GetData()))
+		b = protowire.AppendTag(b, 4, protowire.BytesType)
+		b = protowire.AppendBytes(b, v)
+	default:
+		b = appendString(b, 1, p.GetText())
+	}
+	if p.GetMetadata() != nil {
+		md, _ := proto.Marshal(p.GetMetadata())
+		b = protowire.AppendTag(b, 5, protowire.BytesType)
+		b = protowire.AppendBytes(b, md)
+	}
+	return b
+}
+
+// partFromV1 re-encodes a v1 Part as a v0.3 one. Data that is not a JSON object is kept
+// under "value", as v0.3 data parts are objects.
+func partFromV1(b []byte) ([]byte, error) {
+	p := &a2apb.Part{}
+	var file *a2apb.FilePart
+	fileOf := func() *a2apb.FilePart {
+		if file == nil {
+			file = &a2apb.FilePart{}
+		}
+		return file
+	}
+	err := rangeFields(b, func(num protowire.Number, v []byte) error {
+		switch num {
+		case 1:
+			p.Part = &a2apb.Part_Text{Text: string(v)}
+		case 2:
+			fileOf().File = &a2apb.FilePart_FileWithBytes{FileWithBytes: v}
+		case 3:
+			fileOf().File = &a2apb.FilePart_FileWithUri{FileWithUri: string(v)}
+		case 4:
+			value := &structpb.Value{}
+			if err := proto.Unmarshal(v, value); err != nil {
+				return err
+			}
+			data := value.GetStructValue()
+			if data == nil {
+				data = &structpb.Struct{Fields: map[string]*structpb.Value{"value": value}}
+			}
+			p.Part = &a2apb.Part_Data{Data: &a2apb.DataPart{Data: data}}
+		case 5:
+			p.Metadata = &structpb.Struct{}
+			return proto.Unmarshal(v, p.Metadata)
+		case 6:
+			fileOf().Name = string(v)
+		case 7:
+			fileOf().MimeType = string(v)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("v1 part: %w", err)
+	}
+	if file != nil {
+		p.Part = &a2apb.Part_File{File: file}
+	}
+	return proto.Marshal(p)
+}
+
+var partDescriptor = (&a2apb.Part{}).ProtoReflect().Descriptor()
+
+// partsFromV1 re-encodes the v1 Parts in b, a v1 message of type md, as v0.3 Parts. They
+// have to be rewritten before decoding: v1 reuses the v0.3 field numbers with other types.
+// The messages that contain parts kept their field numbers.
+func partsFromV1(b []byte, md protoreflect.MessageDescriptor) ([]byte, error) {
+	if md.FullName() == partDescriptor.FullName() {
+		return partFromV1(b)
+	}
+	var out []byte
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		m := protowire.ConsumeFieldValue(num, typ, b[n:])
+		if m < 0 {
+			return nil, protowire.ParseError(m)
+		}
+		fd := md.Fields().ByNumber(num)
+		if typ != protowire.BytesType || fd == nil || fd.Message() == nil || fd.IsMap() || isWellKnown(fd.Message()) {
+			out = append(out, b[:n+m]...)
+			b = b[n+m:]
+			continue
+		}
+		v, _ := protowire.ConsumeBytes(b[n:])
+		v, err := partsFromV1(v, fd.Message())
+		if err != nil {
+			return nil, err
+		}
+		out = protowire.AppendTag(out, num, typ)
+		out = protowire.AppendBytes(out, v)
+		b = b[n+m:]
+	}
+	return out, nil
+}
+
+// cardToV1 moves the interfaces and security requirements of c to their v1 fields, and
+// the extended card flag into its capabilities.
+func cardToV1(c *a2apb.AgentCard) {
+	m := c.ProtoReflect()
+	ifaces := c.AdditionalInterfaces
+	if c.Url != "" && !containsInterface(ifaces, c.Url, c.PreferredTransport) {
+		ifaces = append([]*a2apb.AgentInterface{{Url: c.Url, Transport: c.PreferredTransport}}, ifaces...)
+	}
+	for _, i := range ifaces {
+		var b []byte
+		b = appendString(b, 1, i.Url)
+		b = appendString(b, 2, cmp.Or(i.Transport, string(a2a.TransportProtocolJSONRPC)))
+		b = appendString(b, 4, c.ProtocolVersion)
+		addUnknownBytes(m, 19, b)
+	}
+	for _, s := range c.Security {
+		b, _ := proto.Marshal(s)
+		addUnknownBytes(m, 13, b)
+	}
+	if c.SupportsAuthenticatedExtendedCard {
+		if c.Capabilities == nil {
+			c.Capabilities = &a2apb.AgentCapabilities{}
+		}
+		addUnknownVarint(c.Capabilities.ProtoReflect(), 5, 1)
+	}
+	if c.Capabilities != nil {
+		c.Capabilities.StateTransitionHistory = false
+	}
+	c.Url, c.PreferredTransport, c.ProtocolVersion = "", "", ""
+	c.AdditionalInterfaces, c.Security, c.SupportsAuthenticatedExtendedCard = nil, nil, false
+}
+
+// cardFromV1 is the inverse of cardToV1. The first supported interface, the agent's
+// preferred one in v1, becomes the card's URL.
+func cardFromV1(c *a2apb.AgentCard) {
+	m := c.ProtoReflect()
+	for _, f := range takeUnknown(m, 19) {
+		i := &a2apb.AgentInterface{}
+		if proto.Unmarshal(f.bytes, i) != nil {
+			continue
+		}
+		var version string
+		for _, v := range takeUnknown(i.ProtoReflect(), 4) {
+			version = string(v.bytes)
+		}
+		// Tenants have no place in v0.3.
+		takeUnknown(i.ProtoReflect(), 3)
+		if c.Url == "" {
+			c.Url, c.PreferredTransport, c.ProtocolVersion = i.Url, i.Transport, version
+		}
+		c.AdditionalInterfaces = append(c.AdditionalInterfaces, i)
+	}
+	// v0.3 has a bool in field 13, so v1 security requirements decode as unknown fields.
+	for _, f := range takeUnknown(m, 13) {
+		s := &a2apb.Security{}
+		if proto.Unmarshal(f.bytes, s) == nil {
+			c.Security = append(c.Security, s)
+		}
+	}
+	if c.Capabilities != nil {
+		for _, f := range takeUnknown(c.Capabilities.ProtoReflect(), 5) {
+			c.SupportsAuthenticatedExtendedCard = f.varint != 0
+		}
+	}
+}
+
+func containsInterface(ifaces []*a2apb.AgentInterface, url, transport string) bool {
+	for _, i := range ifaces {
+		if i.Url == url && i.Transport == transport {
+			return true
+		}
+	}
+	return false
+}
+
+// unknownField is the value of an unknown field: the contents of a length-delimited
+// field, or a varint.
+type unknownField struct {
+	bytes  []byte
+	varint uint64
+}
+
+// takeUnknown removes the unknown fields numbered num from m and returns their values.
+func takeUnknown(m protoreflect.Message, num protowire.Number) []unknownField {
+	var out []unknownField
+	var kept []byte
+	b := m.GetUnknown()
+	for len(b) > 0 {
+		n, typ, tagLen := protowire.ConsumeTag(b)
+		if tagLen < 0 {
+			return out
+		}
+		valLen := protowire.ConsumeFieldValue(n, typ, b[tagLen:])
+		if valLen < 0 {
+			return out
+		}
+		if n != num {
+			kept = append(kept, b[:tagLen+valLen]...)
+		} else if typ == protowire.BytesType {
+			v, _ := protowire.ConsumeBytes(b[tagLen:])
+			out = append(out, unknownField{bytes: v})
+		} else if typ == protowire.VarintType {
+			v, _ := protowire.ConsumeVarint(b[tagLen:])
+			out = append(out, unknownField{varint: v})
+		}
+		b = b[tagLen+valLen:]
+	}
+	m.SetUnknown(kept)
+	return out
+}
+
+// rangeFields calls fn with the number and contents of each length-delimited field in b,
+// skipping other fields.
+func rangeFields(b []byte, fn func(protowire.Number, []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		m := protowire.ConsumeFieldValue(num, typ, b[n:])
+		if m < 0 {
+			return protowire.ParseError(m)
+		}
+		if typ == protowire.BytesType {
+			v, _ := protowire.ConsumeBytes(b[n:])
+			if err := fn(num, v); err != nil {
+				return err
+			}
+		}
+		b = b[n+m:]
+	}
+	return nil
+}
+
+func addUnknownBytes(m protoreflect.Message, num protowire.Number, v []byte) {
+	b := protowire.AppendTag(m.GetUnknown(), num, protowire.BytesType)
+	m.SetUnknown(protowire.AppendBytes(b, v))
+}
+
+func addUnknownVarint(m protoreflect.Message, num protowire.Number, v uint64) {
+	b := protowire.AppendTag(m.GetUnknown(), num, protowire.VarintType)
+	m.SetUnknown(protowire.AppendVarint(b, v))
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
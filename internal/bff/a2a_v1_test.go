@@ -0,0 +1,337 @@
+package bff
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/a2aproject/a2a-go/a2apb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// v1Messages are the messages of packages/a2a/proto/a2a.proto that the codec translates,
+// with the fields the tests use. Enums are int32s and oneofs are left out: neither
+// changes the encoding.
+var v1Messages = map[string][]string{
+	"Part":                                 {"text 1", "bytes raw 2", "url 3", ".google.protobuf.Value data 4", ".google.protobuf.Struct metadata 5", "filename 6", "media_type 7"},
+	"Message":                              {"message_id 1", "context_id 2", "task_id 3", "int32 role 4", "repeated .a2a.v1.Part parts 5"},
+	"TaskStatus":                           {"int32 state 1", ".a2a.v1.Message message 2"},
+	"Task":                                 {"id 1", "context_id 2", ".a2a.v1.TaskStatus status 3", "repeated .a2a.v1.Message history 5"},
+	"TaskStatusUpdateEvent":                {"task_id 1", "context_id 2", ".a2a.v1.TaskStatus status 3"},
+	"StreamResponse":                       {".a2a.v1.Task task 1", ".a2a.v1.Message message 2", ".a2a.v1.TaskStatusUpdateEvent status_update 3"},
+	"SendMessageRequest":                   {".a2a.v1.Message message 1"},
+	"GetTaskRequest":                       {"id 1", "int32 history_length 2"},
+	"CancelTaskRequest":                    {"id 1"},
+	"SubscribeToTaskRequest":               {"id 1"},
+	"ListTasksResponse":                    {"repeated .a2a.v1.Task tasks 1", "next_page_token 2", "int32 page_size 3", "int32 total_size 4"},
+	"PushNotificationConfig":               {"id 1", "url 2", "token 3"},
+	"TaskPushNotificationConfig":           {"id 1", ".a2a.v1.PushNotificationConfig push_notification_config 2", "task_id 3"},
+	"GetTaskPushNotificationConfigRequest": {"id 1", "task_id 3"},
+	"DeleteTaskPushNotificationConfigRequest": {"id 1", "task_id 3"},
+	"CreateTaskPushNotificationConfigRequest": {"task_id 1", "config_id 2", ".a2a.v1.PushNotificationConfig config 5"},
+	"ListTaskPushNotificationConfigRequest":   {"task_id 1"},
+	"AgentInterface":                          {"url 1", "protocol_binding 2", "tenant 3", "protocol_version 4"},
+	"AgentCapabilities":                       {"bool streaming 1", "bool push_notifications 2", "bool extended_agent_card 5"},
+	"StringList":                              {"repeated list 1"},
+	"SecurityRequirement":                     {"map schemes 1"},
+	"AgentCard":                               {"name 1", "description 2", ".a2a.v1.AgentCapabilities capabilities 7", "repeated .a2a.v1.SecurityRequirement security_requirements 13", "repeated .a2a.v1.AgentInterface supported_interfaces 19"},
+}
+
+// v1Files builds the descriptors of v1Messages. A field is "[repeated] [type] name
+// number", where the type is a scalar or a message name and defaults to string; "map" is
+// the map<string, StringList> of SecurityRequirement.
+func v1Files(t *testing.T) protoreflect.FileDescriptor {
+	t.Helper()
+	scalars := map[string]descriptorpb.FieldDescriptorProto_Type{
+		"string": descriptorpb.FieldDescriptorProto_TYPE_STRING,
+		"bytes":  descriptorpb.FieldDescriptorProto_TYPE_BYTES,
+		"int32":  descriptorpb.FieldDescriptorProto_TYPE_INT32,
+		"bool":   descriptorpb.FieldDescriptorProto_TYPE_BOOL,
+	}
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("a2a_v1_test.proto"),
+		Package:    proto.String("a2a.v1"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/struct.proto"},
+	}
+	for name, fields := range v1Messages {
+		msg := &descriptorpb.DescriptorProto{Name: proto.String(name)}
+		for _, f := range fields {
+			words := strings.Fields(f)
+			field := &descriptorpb.FieldDescriptorProto{Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()}
+			if words[0] == "repeated" {
+				field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+				words = words[1:]
+			}
+			if len(words) == 2 {
+				words = append([]string{"string"}, words...)
+			}
+			switch typ := words[0]; {
+			case typ == "map":
+				msg.NestedType = append(msg.NestedType, &descriptorpb.DescriptorProto{
+					Name: proto.String("SchemesEntry"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						{Name: proto.String("key"), JsonName: proto.String("key"), Number: proto.Int32(1), Label: field.Label.Enum(), Type: scalars["string"].Enum()},
+						{Name: proto.String("value"), JsonName: proto.String("value"), Number: proto.Int32(2), Label: field.Label.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(), TypeName: proto.String(".a2a.v1.StringList")},
+					},
+					Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+				})
+				field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+				field.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+				field.TypeName = proto.String(".a2a.v1.SecurityRequirement.SchemesEntry")
+			case strings.HasPrefix(typ, "."):
+				field.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+				field.TypeName = proto.String(typ)
+			default:
+				field.Type = scalars[typ].Enum()
+			}
+			num, err := strconv.Atoi(words[2])
+			if err != nil {
+				t.Fatalf("field %q: %v", f, err)
+			}
+			field.Name = proto.String(words[1])
+			field.JsonName = proto.String(protojsonName(words[1]))
+			field.Number = proto.Int32(int32(num))
+			msg.Field = append(msg.Field, field)
+		}
+		file.MessageType = append(file.MessageType, msg)
+	}
+	fd, err := protodesc.NewFile(file, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("build v1 descriptors: %v", err)
+	}
+	return fd
+}
+
+// protojsonName is the JSON name protoc gives a field: snake_case in lowerCamelCase.
+func protojsonName(name string) string {
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+	}
+	return strings.Join(parts, "")
+}
+
+// v1Message returns the v1 message typ with the fields in the JSON of v1JSON.
+func v1Message(t *testing.T, fd protoreflect.FileDescriptor, typ, v1JSON string) proto.Message {
+	t.Helper()
+	md := fd.Messages().ByName(protoreflect.Name(typ))
+	if md == nil {
+		t.Fatalf("no v1 message %s", typ)
+	}
+	msg := dynamicpb.NewMessage(md)
+	if err := protojson.Unmarshal([]byte(v1JSON), msg); err != nil {
+		t.Fatalf("v1 %s: %v", typ, err)
+	}
+	return msg
+}
+
+func TestV1CodecRoundTrip(t *testing.T) {
+	fd := v1Files(t)
+	metadata, _ := structpb.NewStruct(map[string]any{"k": "v"})
+	data, _ := structpb.NewStruct(map[string]any{"n": 1.0})
+	values, _ := structpb.NewStruct(map[string]any{"value": []any{1.0, 2.0}})
+	const configName = "tasks/t1/pushNotificationConfigs/c1"
+
+	tests := []struct {
+		name   string
+		v03    proto.Message
+		v1Type string
+		v1JSON string
+		// decodeOnly cases lose information on the way to v0.3, so they do not encode back
+		// to the same v1 message.
+		decodeOnly bool
+	}{
+		{
+			name: "text part",
+			v03: &a2apb.SendMessageRequest{Request: &a2apb.Message{MessageId: "m1", Role: a2apb.Role_ROLE_USER, Parts: []*a2apb.Part{
+				{Part: &a2apb.Part_Text{Text: "hi"}, Metadata: metadata},
+			}}},
+			v1Type: "SendMessageRequest",
+			v1JSON: `{"message": {"messageId": "m1", "role": 1, "parts": [{"text": "hi", "metadata": {"k": "v"}}]}}`,
+		},
+		{
+			name: "file part by URL",
+			v03: &a2apb.Task{Id: "t1", Status: &a2apb.TaskStatus{State: a2apb.TaskState_TASK_STATE_COMPLETED}, History: []*a2apb.Message{
+				{MessageId: "m2", Role: a2apb.Role_ROLE_AGENT, Parts: []*a2apb.Part{{Part: &a2apb.Part_File{File: &a2apb.FilePart{
+					File: &a2apb.FilePart_FileWithUri{FileWithUri: "https://files/a.png"}, Name: "a.png", MimeType: "image/png",
+				}}}}},
+			}},
+			v1Type: "Task",
+			v1JSON: `{"id": "t1", "status": {"state": 3}, "history": [{"messageId": "m2", "role": 2, "parts": [{"url": "https://files/a.png", "filename": "a.png", "mediaType": "image/png"}]}]}`,
+		},
+		{
+			name: "file part with bytes",
+			v03: &a2apb.StreamResponse{Payload: &a2apb.StreamResponse_Msg{Msg: &a2apb.Message{MessageId: "m3", Parts: []*a2apb.Part{
+				{Part: &a2apb.Part_File{File: &a2apb.FilePart{File: &a2apb.FilePart_FileWithBytes{FileWithBytes: []byte("abc")}, MimeType: "text/plain"}}},
+			}}}},
+			v1Type: "StreamResponse",
+			v1JSON: `{"message": {"messageId": "m3", "parts": [{"raw": "YWJj", "mediaType": "text/plain"}]}}`,
+		},
+		{
+			name: "data part",
+			v03: &a2apb.SendMessageRequest{Request: &a2apb.Message{MessageId: "m4", Parts: []*a2apb.Part{
+				{Part: &a2apb.Part_Data{Data: &a2apb.DataPart{Data: data}}},
+			}}},
+			v1Type: "SendMessageRequest",
+			v1JSON: `{"message": {"messageId": "m4", "parts": [{"data": {"n": 1}}]}}`,
+		},
+		{
+			name: "data part that is not an object",
+			v03: &a2apb.SendMessageRequest{Request: &a2apb.Message{MessageId: "m5", Parts: []*a2apb.Part{
+				{Part: &a2apb.Part_Data{Data: &a2apb.DataPart{Data: values}}},
+			}}},
+			v1Type:     "SendMessageRequest",
+			v1JSON:     `{"message": {"messageId": "m5", "parts": [{"data": [1, 2]}]}}`,
+			decodeOnly: true,
+		},
+		{
+			name: "final status update",
+			v03: &a2apb.StreamResponse{Payload: &a2apb.StreamResponse_StatusUpdate{StatusUpdate: &a2apb.TaskStatusUpdateEvent{
+				TaskId: "t1", Status: &a2apb.TaskStatus{State: a2apb.TaskState_TASK_STATE_CANCELLED}, Final: true,
+			}}},
+			v1Type: "StreamResponse",
+			v1JSON: `{"statusUpdate": {"taskId": "t1", "status": {"state": 5}}}`,
+		},
+		{
+			name: "working status update",
+			v03: &a2apb.StreamResponse{Payload: &a2apb.StreamResponse_StatusUpdate{StatusUpdate: &a2apb.TaskStatusUpdateEvent{
+				TaskId: "t1", Status: &a2apb.TaskStatus{State: a2apb.TaskState_TASK_STATE_WORKING},
+			}}},
+			v1Type: "StreamResponse",
+			v1JSON: `{"statusUpdate": {"taskId": "t1", "status": {"state": 2}}}`,
+		},
+		{
+			name:   "get task",
+			v03:    &a2apb.GetTaskRequest{Name: "tasks/t1", HistoryLength: 3},
+			v1Type: "GetTaskRequest",
+			v1JSON: `{"id": "t1", "historyLength": 3}`,
+		},
+		{
+			name:   "cancel task",
+			v03:    &a2apb.CancelTaskRequest{Name: "tasks/t1"},
+			v1Type: "CancelTaskRequest",
+			v1JSON: `{"id": "t1"}`,
+		},
+		{
+			name:   "subscribe to task",
+			v03:    &a2apb.TaskSubscriptionRequest{Name: "tasks/t1"},
+			v1Type: "SubscribeToTaskRequest",
+			v1JSON: `{"id": "t1"}`,
+		},
+		{
+			name:   "list tasks response",
+			v03:    &a2apb.ListTasksResponse{Tasks: []*a2apb.Task{{Id: "t1"}}, NextPageToken: "next", TotalSize: 7},
+			v1Type: "ListTasksResponse",
+			v1JSON: `{"tasks": [{"id": "t1"}], "nextPageToken": "next", "totalSize": 7}`,
+		},
+		{
+			name:   "list push configs",
+			v03:    &a2apb.ListTaskPushNotificationConfigRequest{Parent: "tasks/t1"},
+			v1Type: "ListTaskPushNotificationConfigRequest",
+			v1JSON: `{"taskId": "t1"}`,
+		},
+		{
+			name:   "get push config",
+			v03:    &a2apb.GetTaskPushNotificationConfigRequest{Name: configName},
+			v1Type: "GetTaskPushNotificationConfigRequest",
+			v1JSON: `{"id": "c1", "taskId": "t1"}`,
+		},
+		{
+			name:   "delete push config",
+			v03:    &a2apb.DeleteTaskPushNotificationConfigRequest{Name: configName},
+			v1Type: "DeleteTaskPushNotificationConfigRequest",
+			v1JSON: `{"id": "c1", "taskId": "t1"}`,
+		},
+		{
+			name: "push config",
+			v03: &a2apb.TaskPushNotificationConfig{Name: configName, PushNotificationConfig: &a2apb.PushNotificationConfig{
+				Id: "c1", Url: "https://hook", Token: "secret",
+			}},
+			v1Type: "TaskPushNotificationConfig",
+			v1JSON: `{"id": "c1", "taskId": "t1", "pushNotificationConfig": {"id": "c1", "url": "https://hook", "token": "secret"}}`,
+		},
+		{
+			name: "create push config",
+			v03: &a2apb.CreateTaskPushNotificationConfigRequest{Parent: "tasks/t1", ConfigId: "c1", Config: &a2apb.TaskPushNotificationConfig{
+				Name: configName, PushNotificationConfig: &a2apb.PushNotificationConfig{Id: "c1", Url: "https://hook"},
+			}},
+			v1Type: "CreateTaskPushNotificationConfigRequest",
+			v1JSON: `{"taskId": "t1", "configId": "c1", "config": {"id": "c1", "url": "https://hook"}}`,
+		},
+		{
+			name: "agent card",
+			v03: &a2apb.AgentCard{
+				Name:               "agent",
+				Url:                "https://agent",
+				PreferredTransport: "GRPC",
+				ProtocolVersion:    "1.0",
+				AdditionalInterfaces: []*a2apb.AgentInterface{
+					{Url: "https://agent", Transport: "GRPC"},
+					{Url: "https://agent/jsonrpc", Transport: "JSONRPC"},
+				},
+				Capabilities:                      &a2apb.AgentCapabilities{Streaming: true},
+				SupportsAuthenticatedExtendedCard: true,
+				Security:                          []*a2apb.Security{{Schemes: map[string]*a2apb.StringList{"bearer": {List: []string{"read"}}}}},
+			},
+			v1Type: "AgentCard",
+			v1JSON: `{
+				"name": "agent",
+				"supportedInterfaces": [
+					{"url": "https://agent", "protocolBinding": "GRPC", "protocolVersion": "1.0"},
+					{"url": "https://agent/jsonrpc", "protocolBinding": "JSONRPC", "protocolVersion": "1.0"}
+				],
+				"capabilities": {"streaming": true, "extendedAgentCard": true},
+				"securityRequirements": [{"schemes": {"bearer": {"list": ["read"]}}}]
+			}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v1 := v1Message(t, fd, tt.v1Type, tt.v1JSON)
+			b, err := proto.Marshal(v1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := tt.v03.ProtoReflect().New().Interface()
+			if err := (v1Codec{}).Unmarshal(b, got); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			if !proto.Equal(got, tt.v03) {
+				t.Errorf("Unmarshal = %v, want %v", got, tt.v03)
+			}
+			if tt.decodeOnly {
+				return
+			}
+
+			b, err = (v1Codec{}).Marshal(tt.v03)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			gotV1 := v1.ProtoReflect().New().Interface()
+			if err := proto.Unmarshal(b, gotV1); err != nil {
+				t.Fatalf("Marshal wrote an invalid v1 %s: %v", tt.v1Type, err)
+			}
+			if !proto.Equal(gotV1, v1) {
+				t.Errorf("Marshal = %v, want %v", gotV1, v1)
+			}
+		})
+	}
+}
+
+func TestV1CodecMarshalLeavesMessage(t *testing.T) {
+	msg := &a2apb.GetTaskRequest{Name: "tasks/t1"}
+	if _, err := (v1Codec{}).Marshal(msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Name != "tasks/t1" {
+		t.Errorf("Marshal changed the request name to %q", msg.Name)
+	}
+}
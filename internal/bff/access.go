@@ -0,0 +1,316 @@
+package bff
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+
+	"connectrpc.com/connect"
+)
+
+// AuthMode selects how the BFF authenticates browsers and scripts.
+type AuthMode string
+
+const (
+	// AuthNone lets anyone who can reach the BFF use it.
+	AuthNone AuthMode = "none"
+	// AuthToken requires a secret token: from the startup URL, swapped for a cookie, or
+	// as "Authorization: Bearer <token>".
+	AuthToken AuthMode = "token"
+	// AuthBasic requires HTTP basic auth.
+	AuthBasic AuthMode = "basic"
+	// AuthProxy trusts a header set by an authenticating reverse proxy.
+	AuthProxy AuthMode = "proxy"
+)
+
+// ParseAuthMode validates an --auth value; "" means AuthNone.
+func ParseAuthMode(s string) (AuthMode, error) {
+	switch m := AuthMode(strings.ToLower(strings.TrimSpace(s))); m {
+	case "":
+		return AuthNone, nil
+	case AuthNone, AuthToken, AuthBasic, AuthProxy:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown auth mode %q: want none, token, basic or proxy", s)
+}
+
+// DefaultProxyAuthHeader is the header AuthProxy reads the user from by default.
+const DefaultProxyAuthHeader = "X-Forwarded-User"
+
+// tokenParam is the query parameter that carries the token in the startup URL.
+const tokenParam = "token"
+
+// AccessConfig controls who may use the BFF and from which pages.
+type AccessConfig struct {
+	Auth AuthMode
+	// Token is the AuthToken secret; a random one is generated when empty.
+	Token string
+	// BasicUser and BasicPassword are the AuthBasic credentials.
+	BasicUser, BasicPassword string
+	// ProxyHeader holds the authenticated user for AuthProxy, and is only believed on
+	// requests from TrustedProxies.
+	ProxyHeader    string
+	TrustedProxies []netip.Prefix
+	// AllowedHosts are Host header values accepted besides loopback names, the listen
+	// host and the PublicURL host, e.g. "playground.example.com". "*" accepts any Host.
+	AllowedHosts []string
+	// TrustedOrigins may send state-changing requests cross-origin, e.g. a frontend dev
+	// server at "http://localhost:8000".
+	TrustedOrigins []string
+}
+
+// ParseTrustedProxies parses IP addresses and CIDR ranges such as "10.0.0.0/8".
+func ParseTrustedProxies(values []string) ([]netip.Prefix, error) {
+	var out []netip.Prefix
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if p, err := netip.ParsePrefix(v); err == nil {
+			out = append(out, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(v)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q: want an IP address or CIDR range", v)
+		}
+		out = append(out, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return out, nil
+}
+
+// accessControl authenticates requests, rejects Host headers the BFF does not answer to
+// and cross-origin state-changing requests.
+type accessControl struct {
+	cfg AccessConfig
+	// hosts are the accepted Host names; nil accepts any.
+	hosts  []string
+	cookie string
+	csrf   *http.CrossOriginProtection
+	// basePath is the path the BFF is served under, if not the root.
+	basePath string
+}
+
+// newAccessControl validates cfg. listen and publicURL add their hosts to the accepted
+// ones; no Host is checked on a unix socket, which browsers cannot reach.
+func newAccessControl(cfg AccessConfig, listen, publicURL string) (*accessControl, error) {
+	a := &accessControl{cfg: cfg, csrf: http.NewCrossOriginProtection()}
+	switch cfg.Auth {
+	case AuthNone, "":
+	case AuthToken:
+		if a.cfg.Token == "" {
+			a.cfg.Token = rand.Text()
+		}
+		// Named after the token, so playgrounds on other ports of the same host, which
+		// share cookies, keep theirs.
+		sum := sha256.Sum256([]byte(a.cfg.Token))
+		a.cookie = "a2a-playground-" + hex.EncodeToString(sum[:4])
+	case AuthBasic:
+		if cfg.BasicUser == "" || cfg.BasicPassword == "" {
+			return nil, errors.New("basic auth needs a user and a password")
+		}
+	case AuthProxy:
+		if len(cfg.TrustedProxies) == 0 {
+			return nil, errors.New("proxy auth needs the addresses of the trusted proxies")
+		}
+		if a.cfg.ProxyHeader == "" {
+			a.cfg.ProxyHeader = DefaultProxyAuthHeader
+		}
+	default:
+		return nil, fmt.Errorf("unknown auth mode %q", cfg.Auth)
+	}
+	for _, origin := range cfg.TrustedOrigins {
+		if err := a.csrf.AddTrustedOrigin(origin); err != nil {
+			return nil, fmt.Errorf("trusted origin: %w", err)
+		}
+	}
+	a.csrf.SetDenyHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "cross-origin request rejected; add the page's origin with --trusted-origins if it should be allowed"})
+	}))
+
+	if network, _ := splitListenAddr(listen); network == "unix" || slices.Contains(cfg.AllowedHosts, "*") {
+		return a, nil
+	}
+	a.hosts = []string{"localhost", "127.0.0.1", "::1"}
+	if host, _, err := net.SplitHostPort(listen); err == nil && host != "" {
+		if ip, err := netip.ParseAddr(host); err != nil || !ip.IsUnspecified() {
+			a.hosts = append(a.hosts, strings.ToLower(host))
+		}
+	}
+	if u, err := url.Parse(publicURL); err == nil && u.Hostname() != "" {
+		a.hosts = append(a.hosts, strings.ToLower(u.Hostname()))
+	}
+	for _, h := range cfg.AllowedHosts {
+		a.hosts = append(a.hosts, strings.ToLower(strings.TrimSpace(h)))
+	}
+	return a, nil
+}
+
+// certHosts are the names a self-signed certificate is issued for: those the BFF answers
+// to, or loopback names and the allowed hosts when any Host is accepted.
+func (a *accessControl) certHosts() []string {
+	hosts := a.hosts
+	if hosts == nil {
+		hosts = []string{"localhost", "127.0.0.1", "::1"}
+		for _, h := range a.cfg.AllowedHosts {
+			if h != "*" {
+				hosts = append(hosts, h)
+			}
+		}
+	}
+	var out []string
+	for _, h := range hosts {
+		if !slices.Contains(out, h) {
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+// Token returns the AuthToken secret, or "" for other modes.
+func (a *accessControl) Token() string {
+	if a.cfg.Auth != AuthToken {
+		return ""
+	}
+	return a.cfg.Token
+}
+
+// Handler applies the Host check, authentication and CSRF protection to next.
+func (a *accessControl) Handler(next http.Handler) http.Handler {
+	next = a.csrf.Handler(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.hostAllowed(r.Host) {
+			// Most likely DNS rebinding: a page on another site resolving its own name to us.
+			denyRequest(w, r, http.StatusForbidden, fmt.Sprintf("host %q is not served here; add it with --allowed-hosts", r.Host))
+			return
+		}
+		if a.exempt(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		switch a.cfg.Auth {
+		case AuthToken:
+			a.serveToken(w, r, next)
+			return
+		case AuthBasic:
+			user, password, ok := r.BasicAuth()
+			if !ok || !secretEqual(user, a.cfg.BasicUser) || !secretEqual(password, a.cfg.BasicPassword) {
+				w.Header().Set("WWW-Authenticate", `Basic realm="a2a-playground", charset="UTF-8"`)
+				denyRequest(w, r, http.StatusUnauthorized, "basic auth required")
+				return
+			}
+		case AuthProxy:
+			if !a.fromTrustedProxy(r) {
+				denyRequest(w, r, http.StatusForbidden, "requests must come through the authenticating proxy")
+				return
+			}
+			if r.Header.Get(a.cfg.ProxyHeader) == "" {
+				denyRequest(w, r, http.StatusUnauthorized, "the proxy did not authenticate this request")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// hostAllowed reports whether the BFF answers to the Host header.
+func (a *accessControl) hostAllowed(host string) bool {
+	if a.hosts == nil {
+		return true
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.Trim(host, "[]"))
+	return slices.Contains(a.hosts, host)
+}
+
+// exempt reports whether r needs no authentication. Uploaded files are fetched by their
+// random ID, which the agent gets in place of credentials for --file-parts=uri.
+func (a *accessControl) exempt(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	id, ok := strings.CutPrefix(r.URL.Path, a.basePath+filesAPIPath+"/")
+	return ok && id != "" && !strings.Contains(id, "/")
+}
+
+// serveToken authenticates r by bearer token, cookie or the token query parameter. A
+// page opened with the token gets the cookie and is redirected to the same URL without
+// it, so the token does not linger in the address bar or history.
+func (a *accessControl) serveToken(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && secretEqual(token, a.cfg.Token) {
+		next.ServeHTTP(w, r)
+		return
+	}
+	if c, err := r.Cookie(a.cookie); err == nil && secretEqual(c.Value, a.cfg.Token) {
+		next.ServeHTTP(w, r)
+		return
+	}
+	q := r.URL.Query()
+	if !q.Has(tokenParam) || !secretEqual(q.Get(tokenParam), a.cfg.Token) {
+		denyRequest(w, r, http.StatusUnauthorized, "access token required: open the URL a2a-playground printed at startup")
+		return
+	}
+	if r.Method != http.MethodGet {
+		next.ServeHTTP(w, r)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     a.cookie,
+		Value:    a.cfg.Token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		// Lax, so the OAuth callback, a navigation from another site, is still signed in.
+		SameSite: http.SameSiteLaxMode,
+	})
+	q.Del(tokenParam)
+	u := *r.URL
+	u.RawQuery = q.Encode()
+	http.Redirect(w, r, u.RequestURI(), http.StatusSeeOther)
+}
+
+// fromTrustedProxy reports whether r came directly from one of the trusted proxies.
+func (a *accessControl) fromTrustedProxy(r *http.Request) bool {
+	addr, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := addr.Addr().Unmap()
+	return slices.ContainsFunc(a.cfg.TrustedProxies, func(p netip.Prefix) bool { return p.Contains(ip) })
+}
+
+// secretEqual compares secrets in constant time.
+func secretEqual(got, want string) bool {
+	return subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+// grpcErrors writes errors in the gRPC and gRPC-Web protocols.
+var grpcErrors = connect.NewErrorWriter()
+
+// denyRequest answers gRPC calls with a gRPC status, other API calls with a JSON error
+// and page loads with plain text.
+func denyRequest(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") && grpcErrors.IsSupported(r) {
+		code := connect.CodePermissionDenied
+		if status == http.StatusUnauthorized {
+			code = connect.CodeUnauthenticated
+		}
+		_ = grpcErrors.Write(w, r, connect.NewError(code, errors.New(msg)))
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, "/api/") || strings.HasPrefix(r.URL.Path, "/a2a/") || r.URL.Path == agentCardPath ||
+		strings.HasPrefix(r.Header.Get("Content-Type"), "application/") {
+		writeJSON(w, status, map[string]string{"error": msg})
+		return
+	}
+	http.Error(w, msg, status)
+}
@@ -0,0 +1,191 @@
+package bff
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestHostAllowed(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       AccessConfig
+		listen    string
+		publicURL string
+		host      string
+		want      bool
+	}{
+		{name: "localhost", listen: "127.0.0.1:8080", host: "localhost:8080", want: true},
+		{name: "loopback IPv4", listen: "127.0.0.1:8080", host: "127.0.0.1:8080", want: true},
+		{name: "loopback IPv6", listen: "127.0.0.1:8080", host: "[::1]:8080", want: true},
+		{name: "case", listen: "127.0.0.1:8080", host: "LocalHost", want: true},
+		{name: "other host", listen: "127.0.0.1:8080", host: "evil.example.com", want: false},
+		{name: "listen host", listen: "playground.lan:8080", host: "playground.lan:8080", want: true},
+		{name: "unspecified listen host", listen: "0.0.0.0:8080", host: "0.0.0.0:8080", want: false},
+		{name: "public URL host", listen: ":8080", publicURL: "https://pg.example.com/base", host: "pg.example.com", want: true},
+		{name: "allowed host", cfg: AccessConfig{AllowedHosts: []string{" Pg.Example.com "}}, listen: ":8080", host: "pg.example.com:443", want: true},
+		{name: "any host", cfg: AccessConfig{AllowedHosts: []string{"*"}}, listen: ":8080", host: "evil.example.com", want: true},
+		{name: "unix socket", listen: "/tmp/pg.sock", host: "evil.example.com", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := newAccessControl(tt.cfg, tt.listen, tt.publicURL)
+			if err != nil {
+				t.Fatalf("newAccessControl: %v", err)
+			}
+			if got := a.hostAllowed(tt.host); got != tt.want {
+				t.Errorf("hostAllowed(%q) = %v, want %v", tt.host, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExempt(t *testing.T) {
+	tests := []struct {
+		name     string
+		basePath string
+		method   string
+		path     string
+		want     bool
+	}{
+		{name: "file", method: http.MethodGet, path: "/api/files/abc", want: true},
+		{name: "head", method: http.MethodHead, path: "/api/files/abc", want: true},
+		{name: "delete", method: http.MethodDelete, path: "/api/files/abc", want: false},
+		{name: "upload", method: http.MethodPost, path: "/api/files", want: false},
+		{name: "list", method: http.MethodGet, path: "/api/files", want: false},
+		{name: "empty id", method: http.MethodGet, path: "/api/files/", want: false},
+		{name: "nested", method: http.MethodGet, path: "/api/files/abc/def", want: false},
+		{name: "other API", method: http.MethodGet, path: "/api/sessions/abc", want: false},
+		{name: "under base path", basePath: "/pg", method: http.MethodGet, path: "/pg/api/files/abc", want: true},
+		{name: "outside base path", basePath: "/pg", method: http.MethodGet, path: "/api/files/abc", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &accessControl{basePath: tt.basePath}
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if got := a.exempt(r); got != tt.want {
+				t.Errorf("exempt(%s %s) = %v, want %v", tt.method, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServeToken(t *testing.T) {
+	a, err := newAccessControl(AccessConfig{Auth: AuthToken, Token: "secret"}, "127.0.0.1:8080", "")
+	if err != nil {
+		t.Fatalf("newAccessControl: %v", err)
+	}
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		header     http.Header
+		cookie     string
+		tls        bool
+		wantStatus int
+		wantNext   bool
+		// wantLocation and wantCookie are set for the redirect that swaps the token for
+		// a cookie.
+		wantLocation string
+		wantCookie   bool
+	}{
+		{name: "bearer", method: http.MethodPost, target: "/api/sessions", header: http.Header{"Authorization": {"Bearer secret"}}, wantStatus: http.StatusOK, wantNext: true},
+		{name: "wrong bearer", method: http.MethodPost, target: "/api/sessions", header: http.Header{"Authorization": {"Bearer other"}}, wantStatus: http.StatusUnauthorized},
+		{name: "basic scheme", method: http.MethodPost, target: "/api/sessions", header: http.Header{"Authorization": {"Basic secret"}}, wantStatus: http.StatusUnauthorized},
+		{name: "cookie", method: http.MethodGet, target: "/", cookie: "secret", wantStatus: http.StatusOK, wantNext: true},
+		{name: "wrong cookie", method: http.MethodGet, target: "/", cookie: "other", wantStatus: http.StatusUnauthorized},
+		{name: "none", method: http.MethodGet, target: "/", wantStatus: http.StatusUnauthorized},
+		{name: "wrong query token", method: http.MethodGet, target: "/?token=other", wantStatus: http.StatusUnauthorized},
+		{name: "query token", method: http.MethodGet, target: "/?token=secret", wantStatus: http.StatusSeeOther, wantLocation: "/", wantCookie: true},
+		{name: "query token keeps other params", method: http.MethodGet, target: "/oauth-callback?code=1&token=secret", wantStatus: http.StatusSeeOther, wantLocation: "/oauth-callback?code=1", wantCookie: true},
+		{name: "query token over TLS", method: http.MethodGet, target: "/?token=secret", tls: true, wantStatus: http.StatusSeeOther, wantLocation: "/", wantCookie: true},
+		{name: "query token on POST", method: http.MethodPost, target: "/api/sessions?token=secret", wantStatus: http.StatusOK, wantNext: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.target, nil)
+			for k, v := range tt.header {
+				r.Header[k] = v
+			}
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: a.cookie, Value: tt.cookie})
+			}
+			if tt.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+			w := httptest.NewRecorder()
+			called := false
+			a.serveToken(w, r, http.HandlerFunc(func(http.ResponseWriter, *http.Request) { called = true }))
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if called != tt.wantNext {
+				t.Errorf("next called = %v, want %v", called, tt.wantNext)
+			}
+			if got := w.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", got, tt.wantLocation)
+			}
+			cookies := w.Result().Cookies()
+			if !tt.wantCookie {
+				if len(cookies) != 0 {
+					t.Errorf("cookies = %v, want none", cookies)
+				}
+				return
+			}
+			if len(cookies) != 1 {
+				t.Fatalf("cookies = %v, want one", cookies)
+			}
+			c := cookies[0]
+			if c.Name != a.cookie || c.Value != "secret" || c.Path != "/" || !c.HttpOnly || c.Secure != tt.tls || c.SameSite != http.SameSiteLaxMode {
+				t.Errorf("cookie = %+v, want %s=secret, Path=/, HttpOnly, Secure=%v, SameSite=Lax", c, a.cookie, tt.tls)
+			}
+		})
+	}
+}
+
+func TestFromTrustedProxy(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.5", "fd00::/8"})
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies: %v", err)
+	}
+	a := &accessControl{cfg: AccessConfig{TrustedProxies: proxies}}
+	tests := []struct {
+		remoteAddr string
+		want       bool
+	}{
+		{remoteAddr: "10.1.2.3:5000", want: true},
+		{remoteAddr: "192.168.1.5:5000", want: true},
+		{remoteAddr: "192.168.1.6:5000", want: false},
+		{remoteAddr: "[::ffff:10.1.2.3]:5000", want: true},
+		{remoteAddr: "[fd12::1]:5000", want: true},
+		{remoteAddr: "[fe80::1]:5000", want: false},
+		{remoteAddr: "127.0.0.1:5000", want: false},
+		{remoteAddr: "10.1.2.3", want: false},
+		{remoteAddr: "@", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.remoteAddr, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if got := a.fromTrustedProxy(r); got != tt.want {
+				t.Errorf("fromTrustedProxy(%q) = %v, want %v", tt.remoteAddr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	got, err := ParseTrustedProxies([]string{"10.1.2.3/8", " ::ffff:192.168.1.5 "})
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies: %v", err)
+	}
+	want := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.168.1.5/32")}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("ParseTrustedProxies = %v, want %v", got, want)
+	}
+	if _, err := ParseTrustedProxies([]string{"proxy.lan"}); err == nil {
+		t.Error("ParseTrustedProxies(proxy.lan): want an error")
+	}
+}
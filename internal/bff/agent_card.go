@@ -0,0 +1,238 @@
+package bff
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/a2aproject/a2a-go/a2a"
+	"github.com/a2aproject/a2a-go/a2apb"
+	"github.com/a2aproject/a2a-go/a2apb/pbconv"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// publicCardPath is where an agent serves its public card.
+const publicCardPath = "/.well-known/agent-card.json"
+
+// maxPublicCardSize caps the public card the BFF reads.
+const maxPublicCardSize = 4 << 20
+
+// publicCardClient fetches public cards, which need no credentials.
+var publicCardClient = &http.Client{Timeout: 30 * time.Second}
+
+// publicCardURL returns the URL of the public card of the agent at agentURL: the
+// well-known path at its origin. A gRPC host:port has no scheme; like the gRPC
+// connection, localhost is plain http and anything else https.
+func publicCardURL(agentURL string) string {
+	if !strings.Contains(agentURL, "://") {
+		scheme := "https"
+		if strings.Contains(agentURL, "localhost:") {
+			scheme = "http"
+		}
+		agentURL = scheme + "://" + agentURL
+	}
+	u, err := url.Parse(agentURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host + publicCardPath
+}
+
+// agentCards are an agent's public card and its authenticated extended card. Either may
+// be missing, with the error that kept it from being fetched.
+type agentCards struct {
+	public, extended       *a2apb.AgentCard
+	publicErr, extendedErr error
+}
+
+// fetchAgentCards fetches the public card from cardURL and, when it says the agent has
+// one, the extended card with extended, which sends the agent headers. The extended card
+// is also asked for when the public one cannot be fetched, as gRPC agents often serve
+// none. It fails only when neither card could be fetched, with extended's error.
+func fetchAgentCards(ctx context.Context, cardURL string, extended func(context.Context) (*a2apb.AgentCard, error)) (*agentCards, error) {
+	cards := &agentCards{}
+	cards.public, cards.publicErr = fetchPublicCard(ctx, cardURL)
+	if cards.publicErr == nil && !cards.public.GetSupportsAuthenticatedExtendedCard() {
+		return cards, nil
+	}
+	cards.extended, cards.extendedErr = extended(ctx)
+	if cards.publicErr != nil && cards.extendedErr != nil {
+		return nil, cards.extendedErr
+	}
+	return cards, nil
+}
+
+// card returns the card the playground shows: the extended card merged over the public
+// one, or whichever of them was fetched.
+func (c *agentCards) card() *a2apb.AgentCard {
+	switch {
+	case c.extended == nil:
+		return c.public
+	case c.public == nil:
+		return c.extended
+	}
+	return mergeAgentCards(c.public, c.extended)
+}
+
+// fetchPublicCard fetches and converts the card at cardURL. v1 cards are read too, for
+// their interfaces and extended card flag.
+func fetchPublicCard(ctx context.Context, cardURL string) (*a2apb.AgentCard, error) {
+	if cardURL == "" {
+		return nil, errors.New("the agent has no public card URL")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cardURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := publicCardClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", cardURL, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPublicCardSize))
+	if err != nil {
+		return nil, err
+	}
+	var card a2a.AgentCard
+	if err := json.Unmarshal(body, &card); err != nil {
+		return nil, fmt.Errorf("public card: %w", err)
+	}
+	var v1 struct {
+		SupportedInterfaces []struct {
+			URL             string `json:"url"`
+			ProtocolBinding string `json:"protocolBinding"`
+			ProtocolVersion string `json:"protocolVersion"`
+		} `json:"supportedInterfaces"`
+		Capabilities struct {
+			ExtendedAgentCard bool `json:"extendedAgentCard"`
+		} `json:"capabilities"`
+	}
+	if err := json.Unmarshal(body, &v1); err == nil {
+		for _, i := range v1.SupportedInterfaces {
+			if card.URL == "" {
+				card.URL, card.PreferredTransport, card.ProtocolVersion = i.URL, a2a.TransportProtocol(i.ProtocolBinding), i.ProtocolVersion
+			}
+			card.AdditionalInterfaces = append(card.AdditionalInterfaces, a2a.AgentInterface{URL: i.URL, Transport: a2a.TransportProtocol(i.ProtocolBinding)})
+		}
+		card.SupportsAuthenticatedExtendedCard = card.SupportsAuthenticatedExtendedCard || v1.Capabilities.ExtendedAgentCard
+	}
+	return pbconv.ToProtoAgentCard(&card)
+}
+
+// mergeAgentCards returns extended merged over public. Fields extended sets replace
+// public's, except that lists and maps are merged: skills, extensions and interfaces are
+// matched by id, uri and url, other elements by value, and map entries by key.
+func mergeAgentCards(public, extended *a2apb.AgentCard) *a2apb.AgentCard {
+	out := proto.Clone(public).(*a2apb.AgentCard)
+	mergeMessage(out.ProtoReflect(), proto.Clone(extended).ProtoReflect())
+	return out
+}
+
+func mergeMessage(dst, src protoreflect.Message) {
+	src.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsMap():
+			m := dst.Mutable(fd).Map()
+			v.Map().Range(func(k protoreflect.MapKey, mv protoreflect.Value) bool {
+				m.Set(k, mv)
+				return true
+			})
+		case fd.IsList():
+			l := dst.Mutable(fd).List()
+			for i := 0; i < v.List().Len(); i++ {
+				e := v.List().Get(i)
+				if j := indexOfElement(l, e, fd); j >= 0 {
+					l.Set(j, e)
+				} else {
+					l.Append(e)
+				}
+			}
+		case fd.Message() != nil && dst.Has(fd):
+			mergeMessage(dst.Mutable(fd).Message(), v.Message())
+		default:
+			dst.Set(fd, v)
+		}
+		return true
+	})
+}
+
+// indexOfElement returns the index of the element of l that e replaces, or -1.
+func indexOfElement(l protoreflect.List, e protoreflect.Value, fd protoreflect.FieldDescriptor) int {
+	for i := 0; i < l.Len(); i++ {
+		if fd.Message() == nil {
+			if l.Get(i).Interface() == e.Interface() {
+				return i
+			}
+			continue
+		}
+		if key, ok := elementKey(e.Message()); ok {
+			if other, _ := elementKey(l.Get(i).Message()); other == key {
+				return i
+			}
+		} else if proto.Equal(l.Get(i).Message().Interface(), e.Message().Interface()) {
+			return i
+		}
+	}
+	return -1
+}
+
+// elementKey returns the id, uri or url that identifies m in a list.
+func elementKey(m protoreflect.Message) (string, bool) {
+	for _, name := range []protoreflect.Name{"id", "uri", "url"} {
+		if fd := m.Descriptor().Fields().ByName(name); fd != nil && fd.Kind() == protoreflect.StringKind {
+			return m.Get(fd).String(), true
+		}
+	}
+	return "", false
+}
+
+// ExtendedOnly is what only the authenticated extended card shows.
+type ExtendedOnly struct {
+	// Skills are skill IDs.
+	Skills []string `json:"skills,omitempty"`
+	// Capabilities are AgentCapabilities flags, such as "streaming".
+	Capabilities []string `json:"capabilities,omitempty"`
+	// Extensions are extension URIs.
+	Extensions []string `json:"extensions,omitempty"`
+}
+
+// extendedOnly returns what extended has and public lacks.
+func extendedOnly(public, extended *a2apb.AgentCard) ExtendedOnly {
+	var out ExtendedOnly
+	for _, s := range extended.GetSkills() {
+		if !slices.ContainsFunc(public.GetSkills(), func(p *a2apb.AgentSkill) bool { return p.GetId() == s.GetId() }) {
+			out.Skills = append(out.Skills, s.GetId())
+		}
+	}
+	pc, ec := public.GetCapabilities(), extended.GetCapabilities()
+	for _, c := range []struct {
+		name               string
+		inPublic, extended bool
+	}{
+		{"streaming", pc.GetStreaming(), ec.GetStreaming()},
+		{"pushNotifications", pc.GetPushNotifications(), ec.GetPushNotifications()},
+		{"stateTransitionHistory", pc.GetStateTransitionHistory(), ec.GetStateTransitionHistory()},
+	} {
+		if c.extended && !c.inPublic {
+			out.Capabilities = append(out.Capabilities, c.name)
+		}
+	}
+	for _, e := range ec.GetExtensions() {
+		if !slices.ContainsFunc(pc.GetExtensions(), func(p *a2apb.AgentExtension) bool { return p.GetUri() == e.GetUri() }) {
+			out.Extensions = append(out.Extensions, e.GetUri())
+		}
+	}
+	return out
+}
@@ -0,0 +1,81 @@
+package bff
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/a2aproject/a2a-go/a2apb"
+	"github.com/gorilla/mux"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// agentCardAPIPath is the agent card endpoint.
+const agentCardAPIPath = "/api/agent-card"
+
+// agentCardSource is implemented by the proxies, which fetch the public and extended
+// cards separately.
+type agentCardSource interface {
+	agentCards(ctx context.Context) (*agentCards, error)
+}
+
+// AgentCardView is the agent's card as /api/agent-card shows it.
+type AgentCardView struct {
+	// Card is what GetAgentCard returns: Extended merged over Public.
+	Card json.RawMessage `json:"card"`
+	// Public is the card at the agent's well-known URL, and Extended the one fetched
+	// with the agent headers. Either may be missing, with an error saying why.
+	Public        json.RawMessage `json:"public,omitempty"`
+	PublicError   string          `json:"publicError,omitempty"`
+	Extended      json.RawMessage `json:"extended,omitempty"`
+	ExtendedError string          `json:"extendedError,omitempty"`
+	// ExtendedOnly is what only authenticated users see, when both cards were fetched.
+	ExtendedOnly *ExtendedOnly `json:"extendedOnly,omitempty"`
+}
+
+// registerAgentCardRoutes mounts the agent card API on r:
+//
+//	GET /api/agent-card   the public, extended and merged cards, as an AgentCardView
+//
+// The extended card is fetched with the request's X-A2A-Agent-Headers.
+func registerAgentCardRoutes(r *mux.Router, proxy A2AServiceHandler) {
+	source, ok := proxy.(agentCardSource)
+	if !ok {
+		return
+	}
+	r.Handle(agentCardAPIPath, AgentHeadersMiddleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		cards, err := source.agentCards(req.Context())
+		if err != nil {
+			writeJSONError(w, err)
+			return
+		}
+		view := AgentCardView{
+			Card:     marshalCard(cards.card()),
+			Public:   marshalCard(cards.public),
+			Extended: marshalCard(cards.extended),
+		}
+		if cards.publicErr != nil {
+			view.PublicError = cards.publicErr.Error()
+		}
+		if cards.extendedErr != nil {
+			view.ExtendedError = cards.extendedErr.Error()
+		}
+		if cards.public != nil && cards.extended != nil {
+			only := extendedOnly(cards.public, cards.extended)
+			view.ExtendedOnly = &only
+		}
+		writeJSON(w, http.StatusOK, view)
+	}))).Methods(http.MethodGet)
+}
+
+// marshalCard returns card as protojson, or nil.
+func marshalCard(card *a2apb.AgentCard) json.RawMessage {
+	if card == nil {
+		return nil
+	}
+	b, err := protojson.Marshal(card)
+	if err != nil {
+		return nil
+	}
+	return b
+}
@@ -0,0 +1,114 @@
+package bff
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// NormalizeBasePath returns p as the path the playground is served under: "" for the
+// root, or a path such as "/playground" with a leading and no trailing slash.
+func NormalizeBasePath(p string) (string, error) {
+	p = strings.TrimSpace(p)
+	if p == "" || p == "/" {
+		return "", nil
+	}
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	p = strings.TrimSuffix(p, "/")
+	if path.Clean(p) != p || strings.ContainsAny(p, "?#$\"'<>\\") {
+		return "", fmt.Errorf("base path %q is not a clean URL path", p)
+	}
+	return p, nil
+}
+
+// stripBasePath serves next the paths under base with base removed, redirecting base
+// itself to base/. Other paths are not found. A blank base serves every path.
+func stripBasePath(base string, next http.Handler) http.Handler {
+	if base == "" {
+		return next
+	}
+	strip := http.StripPrefix(base, next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == base {
+			u := *r.URL
+			u.Path += "/"
+			http.Redirect(w, r, u.RequestURI(), http.StatusMovedPermanently)
+			return
+		}
+		if !strings.HasPrefix(r.URL.Path, base+"/") {
+			http.NotFound(w, r)
+			return
+		}
+		strip.ServeHTTP(w, r)
+	})
+}
+
+// basePathFS serves the files of a Vite build of the SPA, adding to index.html the
+// <base> element the SPA reads the path it is served under from. The SPA is built with
+// a relative base, so the element also resolves its asset URLs on nested routes.
+type basePathFS struct {
+	fsys fs.FS
+	base string
+}
+
+func newBasePathFS(fsys fs.FS, base string) *basePathFS {
+	return &basePathFS{fsys: fsys, base: base}
+}
+
+// Open opens name, adding the <base> element to index.html.
+func (b *basePathFS) Open(name string) (fs.File, error) {
+	f, err := b.fsys.Open(name)
+	if err != nil || name != "index.html" {
+		return f, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	tag := fmt.Sprintf(`<head>
+    <base href="%s/" />`, html.EscapeString(b.base))
+	data = bytes.Replace(data, []byte("<head>"), []byte(tag), 1)
+	return newMemFile(name, info.ModTime(), data), nil
+}
+
+// memFile is an in-memory fs.File that http.FS can seek in.
+type memFile struct {
+	*bytes.Reader
+	info memFileInfo
+}
+
+func newMemFile(name string, modTime time.Time, data []byte) *memFile {
+	return &memFile{
+		Reader: bytes.NewReader(data),
+		info:   memFileInfo{name: path.Base(name), size: int64(len(data)), modTime: modTime},
+	}
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memFile) Close() error               { return nil }
+
+type memFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (i memFileInfo) Name() string       { return i.name }
+func (i memFileInfo) Size() int64        { return i.size }
+func (i memFileInfo) Mode() fs.FileMode  { return 0o444 }
+func (i memFileInfo) ModTime() time.Time { return i.modTime }
+func (i memFileInfo) IsDir() bool        { return false }
+func (i memFileInfo) Sys() any           { return nil }
@@ -0,0 +1,380 @@
+package bff
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"connectrpc.com/connect"
+	"github.com/a2aproject/a2a-go/a2a"
+	"github.com/a2aproject/a2a-go/a2apb"
+	"github.com/a2aproject/a2a-go/a2apb/pbconv"
+	"github.com/a2aproject/a2a-go/a2asrv"
+	"github.com/alis-exchange/a2a-playground/gen/go/a2apbconnect"
+)
+
+const (
+	// bridgeJSONRPCPath serves the agent over the A2A JSON-RPC binding.
+	bridgeJSONRPCPath = "/a2a/jsonrpc"
+	// bridgeRESTPath is the base of the A2A HTTP+JSON binding, e.g. /a2a/rest/message:send.
+	bridgeRESTPath = "/a2a/rest"
+	// agentCardPath serves the agent card rewritten to point at the BFF.
+	agentCardPath = "/.well-known/agent-card.json"
+)
+
+// bridgedHeaders are the request headers of bridged calls passed on to the Connect service.
+var bridgedHeaders = []string{agentHeadersHeader, extensionsHeader}
+
+// unbridgedResponseHeaders describe the in-process Connect response rather than the
+// call, and are not copied to bridged responses.
+var unbridgedResponseHeaders = []string{"accept-encoding", "connect-*"}
+
+// bridge re-exposes the agent over the A2A JSON-RPC and HTTP+JSON bindings. Calls are
+// made in-process against the BFF's own Connect handler, so they get the same header
+// injection, recording and fault injection as the UI's, whichever proxy reaches the agent.
+type bridge struct {
+	client    a2apbconnect.A2AServiceClient
+	server    *http.Server
+	publicURL string
+	basePath  string
+}
+
+// Ensure bridge implements a2asrv.RequestHandler for the JSON-RPC binding.
+var _ a2asrv.RequestHandler = (*bridge)(nil)
+
+// newBridge serves handler, the Connect service mounted at path, over an in-memory
+// listener. publicURL is the configured PublicURL; without one, the agent card points at
+// the host the card was requested from, under basePath.
+func newBridge(path string, handler http.Handler, publicURL, basePath string) *bridge {
+	mux := http.NewServeMux()
+	mux.Handle(path, handler)
+	ln := newPipeListener()
+	srv := &http.Server{Handler: mux}
+	go func() {
+		_ = srv.Serve(ln)
+	}()
+	httpClient := &http.Client{Transport: bridgeTransport{&http.Transport{DialContext: ln.dial}}}
+	return &bridge{
+		client:    a2apbconnect.NewA2AServiceClient(httpClient, localBaseURL),
+		server:    srv,
+		publicURL: strings.TrimSuffix(publicURL, "/"),
+		basePath:  basePath,
+	}
+}
+
+// Close stops the in-process server.
+func (b *bridge) Close() error {
+	return b.server.Close()
+}
+
+// bridgeCallKey is the context key for the bridgeCall of a request.
+type bridgeCallKey struct{}
+
+// bridgeCall carries what the in-process call needs from the bridged request, and
+// collects the response headers for it.
+type bridgeCall struct {
+	header     http.Header
+	respHeader http.Header
+	// base is the URL the bridged bindings are reached at.
+	base string
+}
+
+// middleware attaches the bridgeCall for the request to its context.
+func (b *bridge) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := &bridgeCall{header: r.Header, respHeader: w.Header(), base: b.baseURL(r)}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), bridgeCallKey{}, call)))
+	})
+}
+
+func bridgeCallFromContext(ctx context.Context) *bridgeCall {
+	call, _ := ctx.Value(bridgeCallKey{}).(*bridgeCall)
+	if call == nil {
+		return &bridgeCall{}
+	}
+	return call
+}
+
+// baseURL is the URL the bridged bindings are reached at, without a trailing slash.
+func (b *bridge) baseURL(r *http.Request) string {
+	if b.publicURL != "" {
+		return b.publicURL
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + b.basePath
+}
+
+// copyResponse adds the in-process response's headers and trailers, such as forwarded
+// agent headers and Server-Timing, to the bridged response.
+func (c *bridgeCall) copyResponse(header, trailer http.Header) {
+	if c.respHeader == nil {
+		return
+	}
+	for _, h := range []http.Header{header, trailer} {
+		for name, values := range h {
+			if matchesAny(framingHeaders, name) || matchesAny(unbridgedResponseHeaders, name) {
+				continue
+			}
+			for _, v := range values {
+				c.respHeader.Add(name, v)
+			}
+		}
+	}
+}
+
+// bridgeTransport passes the bridgedHeaders of the bridged request on to the Connect service.
+type bridgeTransport struct {
+	base http.RoundTripper
+}
+
+func (t bridgeTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	call := bridgeCallFromContext(r.Context())
+	r = r.Clone(r.Context())
+	for _, name := range bridgedHeaders {
+		for _, v := range call.header.Values(name) {
+			r.Header.Add(name, v)
+		}
+	}
+	return t.base.RoundTrip(r)
+}
+
+// fromConnectError maps Connect codes back to the A2A errors the JSON-RPC binding reports,
+// as a2a-go does for gRPC status codes.
+func fromConnectError(err error) error {
+	var cerr *connect.Error
+	if !errors.As(err, &cerr) {
+		return err
+	}
+	base := a2a.ErrInternalError
+	switch cerr.Code() {
+	case connect.CodeNotFound:
+		base = a2a.ErrTaskNotFound
+	case connect.CodeFailedPrecondition:
+		base = a2a.ErrTaskNotCancelable
+	case connect.CodeUnimplemented:
+		base = a2a.ErrUnsupportedOperation
+	case connect.CodeInvalidArgument:
+		base = a2a.ErrInvalidParams
+	case connect.CodeUnauthenticated:
+		base = a2a.ErrUnauthenticated
+	case connect.CodePermissionDenied:
+		base = a2a.ErrUnauthorized
+	case connect.CodeCanceled:
+		base = context.Canceled
+	case connect.CodeDeadlineExceeded:
+		base = context.DeadlineExceeded
+	}
+	return a2a.NewError(base, cerr.Message())
+}
+
+// callUnary makes an in-process unary call and copies its response headers.
+func callUnary[Req, Resp any](ctx context.Context, call func(context.Context, *connect.Request[Req]) (*connect.Response[Resp], error), msg *Req) (*Resp, error) {
+	resp, err := call(ctx, connect.NewRequest(msg))
+	if err != nil {
+		var cerr *connect.Error
+		if errors.As(err, &cerr) {
+			bridgeCallFromContext(ctx).copyResponse(cerr.Meta(), nil)
+		}
+		return nil, err
+	}
+	bridgeCallFromContext(ctx).copyResponse(resp.Header(), resp.Trailer())
+	return resp.Msg, nil
+}
+
+// unary is callUnary for the JSON-RPC binding, which reports A2A errors.
+func unary[Req, Resp any](ctx context.Context, call func(context.Context, *connect.Request[Req]) (*connect.Response[Resp], error), msg *Req) (*Resp, error) {
+	resp, err := callUnary(ctx, call, msg)
+	if err != nil {
+		return nil, fromConnectError(err)
+	}
+	return resp, nil
+}
+
+// events makes an in-process streaming call for the JSON-RPC binding. Its response
+// headers are not copied: the binding has sent its own by the time they arrive.
+func events[Req any](ctx context.Context, call func(context.Context, *connect.Request[Req]) (*connect.ServerStreamForClient[a2apb.StreamResponse], error), msg *Req, convErr error) iter.Seq2[a2a.Event, error] {
+	return func(yield func(a2a.Event, error) bool) {
+		if convErr != nil {
+			yield(nil, invalidParams(convErr))
+			return
+		}
+		stream, err := call(ctx, connect.NewRequest(msg))
+		if err != nil {
+			yield(nil, fromConnectError(err))
+			return
+		}
+		defer stream.Close()
+		for stream.Receive() {
+			event, err := pbconv.FromProtoStreamResponse(stream.Msg())
+			if !yield(event, err) || err != nil {
+				return
+			}
+		}
+		if err := stream.Err(); err != nil {
+			yield(nil, fromConnectError(err))
+		}
+	}
+}
+
+// invalidParams reports a request the binding accepted but pbconv could not convert.
+func invalidParams(err error) error {
+	return a2a.NewError(a2a.ErrInvalidParams, err.Error())
+}
+
+// OnGetTask implements the JSON-RPC tasks/get method.
+func (b *bridge) OnGetTask(ctx context.Context, query *a2a.TaskQueryParams) (*a2a.Task, error) {
+	req, err := pbconv.ToProtoGetTaskRequest(query)
+	if err != nil {
+		return nil, invalidParams(err)
+	}
+	task, err := unary(ctx, b.client.GetTask, req)
+	if err != nil {
+		return nil, err
+	}
+	return pbconv.FromProtoTask(task)
+}
+
+// OnCancelTask implements the JSON-RPC tasks/cancel method.
+func (b *bridge) OnCancelTask(ctx context.Context, params *a2a.TaskIDParams) (*a2a.Task, error) {
+	req, err := pbconv.ToProtoCancelTaskRequest(params)
+	if err != nil {
+		return nil, invalidParams(err)
+	}
+	task, err := unary(ctx, b.client.CancelTask, req)
+	if err != nil {
+		return nil, err
+	}
+	return pbconv.FromProtoTask(task)
+}
+
+// OnSendMessage implements the JSON-RPC message/send method.
+func (b *bridge) OnSendMessage(ctx context.Context, params *a2a.MessageSendParams) (a2a.SendMessageResult, error) {
+	req, err := pbconv.ToProtoSendMessageRequest(params)
+	if err != nil {
+		return nil, invalidParams(err)
+	}
+	resp, err := unary(ctx, b.client.SendMessage, req)
+	if err != nil {
+		return nil, err
+	}
+	return pbconv.FromProtoSendMessageResponse(resp)
+}
+
+// OnResubscribeToTask implements the JSON-RPC tasks/resubscribe method.
+func (b *bridge) OnResubscribeToTask(ctx context.Context, params *a2a.TaskIDParams) iter.Seq2[a2a.Event, error] {
+	req, err := pbconv.ToProtoTaskSubscriptionRequest(params)
+	return events(ctx, b.client.TaskSubscription, req, err)
+}
+
+// OnSendMessageStream implements the JSON-RPC message/stream method.
+func (b *bridge) OnSendMessageStream(ctx context.Context, params *a2a.MessageSendParams) iter.Seq2[a2a.Event, error] {
+	req, err := pbconv.ToProtoSendMessageRequest(params)
+	return events(ctx, b.client.SendStreamingMessage, req, err)
+}
+
+// OnGetTaskPushConfig implements the JSON-RPC tasks/pushNotificationConfig/get method.
+func (b *bridge) OnGetTaskPushConfig(ctx context.Context, params *a2a.GetTaskPushConfigParams) (*a2a.TaskPushConfig, error) {
+	req, err := pbconv.ToProtoGetTaskPushConfigRequest(params)
+	if err != nil {
+		return nil, invalidParams(err)
+	}
+	config, err := unary(ctx, b.client.GetTaskPushNotificationConfig, req)
+	if err != nil {
+		return nil, err
+	}
+	return pbconv.FromProtoTaskPushConfig(config)
+}
+
+// OnListTaskPushConfig implements the JSON-RPC tasks/pushNotificationConfig/list method.
+func (b *bridge) OnListTaskPushConfig(ctx context.Context, params *a2a.ListTaskPushConfigParams) ([]*a2a.TaskPushConfig, error) {
+	req, err := pbconv.ToProtoListTaskPushConfigRequest(params)
+	if err != nil {
+		return nil, invalidParams(err)
+	}
+	resp, err := unary(ctx, b.client.ListTaskPushNotificationConfig, req)
+	if err != nil {
+		return nil, err
+	}
+	return pbconv.FromProtoListTaskPushConfig(resp)
+}
+
+// OnSetTaskPushConfig implements the JSON-RPC tasks/pushNotificationConfig/set method.
+func (b *bridge) OnSetTaskPushConfig(ctx context.Context, params *a2a.TaskPushConfig) (*a2a.TaskPushConfig, error) {
+	req, err := pbconv.ToProtoCreateTaskPushConfigRequest(params)
+	if err != nil {
+		return nil, invalidParams(err)
+	}
+	config, err := unary(ctx, b.client.CreateTaskPushNotificationConfig, req)
+	if err != nil {
+		return nil, err
+	}
+	return pbconv.FromProtoTaskPushConfig(config)
+}
+
+// OnDeleteTaskPushConfig implements the JSON-RPC tasks/pushNotificationConfig/delete method.
+func (b *bridge) OnDeleteTaskPushConfig(ctx context.Context, params *a2a.DeleteTaskPushConfigParams) error {
+	req, err := pbconv.ToProtoDeleteTaskPushConfigRequest(params)
+	if err != nil {
+		return invalidParams(err)
+	}
+	_, err = unary(ctx, b.client.DeleteTaskPushNotificationConfig, req)
+	return err
+}
+
+// OnGetExtendedAgentCard implements the JSON-RPC agent/getAuthenticatedExtendedCard
+// method with the agent's card, rewritten like the well-known one.
+func (b *bridge) OnGetExtendedAgentCard(ctx context.Context) (*a2a.AgentCard, error) {
+	card, err := b.agentCard(ctx)
+	if err != nil {
+		return nil, fromConnectError(err)
+	}
+	return card, nil
+}
+
+// agentCard fetches the agent's card through the Connect service and rewrites it to
+// advertise the BFF's interfaces.
+func (b *bridge) agentCard(ctx context.Context) (*a2a.AgentCard, error) {
+	pc, err := callUnary(ctx, b.client.GetAgentCard, &a2apb.GetAgentCardRequest{})
+	if err != nil {
+		return nil, err
+	}
+	card, err := pbconv.FromProtoAgentCard(pc)
+	if err != nil {
+		return nil, err
+	}
+	rewriteAgentCard(card, bridgeCallFromContext(ctx).base)
+	return card, nil
+}
+
+// rewriteAgentCard points card at the BFF at base: the Connect service as gRPC, and the
+// JSON-RPC and HTTP+JSON bridges. The agent's preferred transport stays preferred when
+// the BFF serves it. Signatures are dropped, as they no longer match.
+func rewriteAgentCard(card *a2a.AgentCard, base string) {
+	interfaces := []a2a.AgentInterface{
+		{Transport: a2a.TransportProtocolJSONRPC, URL: base + bridgeJSONRPCPath},
+		{Transport: a2a.TransportProtocolHTTPJSON, URL: base + bridgeRESTPath},
+	}
+	// gRPC targets are host:port; a BFF behind a path prefix has none.
+	if u, err := url.Parse(base); err == nil && u.Host != "" && strings.Trim(u.Path, "/") == "" {
+		host := u.Host
+		if u.Port() == "" {
+			host += map[string]string{"http": ":80", "https": ":443"}[u.Scheme]
+		}
+		interfaces = append(interfaces, a2a.AgentInterface{Transport: a2a.TransportProtocolGRPC, URL: host})
+	}
+	i := slices.IndexFunc(interfaces, func(i a2a.AgentInterface) bool { return i.Transport == card.PreferredTransport })
+	if i < 0 {
+		i = 0
+	}
+	card.PreferredTransport = interfaces[i].Transport
+	card.URL = interfaces[i].URL
+	card.AdditionalInterfaces = interfaces
+	card.Signatures = nil
+}
@@ -0,0 +1,332 @@
+package bff
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"connectrpc.com/connect"
+	"github.com/a2aproject/a2a-go/a2a"
+	"github.com/a2aproject/a2a-go/a2apb"
+	"github.com/a2aproject/a2a-go/a2apb/pbconv"
+	"github.com/a2aproject/a2a-go/a2asrv"
+	"github.com/gorilla/mux"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// registerBridgeRoutes mounts the agent card and the bridged A2A bindings on r:
+//
+//	GET    /.well-known/agent-card.json     the agent's card, pointing at the BFF
+//	POST   /a2a/jsonrpc                     the JSON-RPC binding
+//	POST   /a2a/rest/message:send           SendMessage
+//	POST   /a2a/rest/message:stream         SendStreamingMessage, as Server-Sent Events
+//	GET    /a2a/rest/tasks                  ListTasks
+//	GET    /a2a/rest/tasks/{id}             GetTask
+//	POST   /a2a/rest/tasks/{id}:cancel      CancelTask
+//	GET    /a2a/rest/tasks/{id}:subscribe   TaskSubscription, as Server-Sent Events
+//	POST   /a2a/rest/tasks/{taskId}/pushNotificationConfigs       create a push config
+//	GET    /a2a/rest/tasks/{taskId}/pushNotificationConfigs       list push configs
+//	GET    /a2a/rest/tasks/{taskId}/pushNotificationConfigs/{id}  get a push config
+//	DELETE /a2a/rest/tasks/{taskId}/pushNotificationConfigs/{id}  delete a push config
+//	GET    /a2a/rest/extendedAgentCard      the agent's card, as from GetAgentCard
+//
+// The HTTP+JSON routes follow the google.api.http annotations of the A2A proto, without
+// the tenant bindings. Bodies and responses are the protobuf JSON of a2apb; query
+// parameters set scalar request fields by JSON or proto name.
+func registerBridgeRoutes(r *mux.Router, b *bridge) {
+	r.Handle(agentCardPath, b.middleware(http.HandlerFunc(b.serveAgentCard))).Methods(http.MethodGet, http.MethodHead)
+	jsonrpc := a2asrv.NewJSONRPCHandler(b)
+	r.Handle(bridgeJSONRPCPath, b.middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// a2asrv sets the type of streams only.
+		w.Header().Set("Content-Type", "application/json")
+		jsonrpc.ServeHTTP(w, req)
+	})))
+
+	rest := r.PathPrefix(bridgeRESTPath).Subrouter()
+	rest.Use(b.middleware)
+	rest.HandleFunc("/message:send", b.sendMessage).Methods(http.MethodPost)
+	rest.HandleFunc("/message:stream", b.sendStreamingMessage).Methods(http.MethodPost)
+	rest.HandleFunc("/tasks", b.listTasks).Methods(http.MethodGet)
+	rest.HandleFunc("/tasks/{id:[^/:]+}", b.getTask).Methods(http.MethodGet)
+	rest.HandleFunc("/tasks/{id:[^/:]+}:cancel", b.cancelTask).Methods(http.MethodPost)
+	rest.HandleFunc("/tasks/{id:[^/:]+}:subscribe", b.subscribeToTask).Methods(http.MethodGet)
+	rest.HandleFunc("/tasks/{taskId}/pushNotificationConfigs", b.createPushConfig).Methods(http.MethodPost)
+	rest.HandleFunc("/tasks/{taskId}/pushNotificationConfigs", b.listPushConfigs).Methods(http.MethodGet)
+	rest.HandleFunc("/tasks/{taskId}/pushNotificationConfigs/{id}", b.getPushConfig).Methods(http.MethodGet)
+	rest.HandleFunc("/tasks/{taskId}/pushNotificationConfigs/{id}", b.deletePushConfig).Methods(http.MethodDelete)
+	rest.HandleFunc("/extendedAgentCard", b.extendedAgentCard).Methods(http.MethodGet)
+}
+
+func (b *bridge) serveAgentCard(w http.ResponseWriter, req *http.Request) {
+	card, err := b.agentCard(req.Context())
+	if err != nil {
+		writeRESTError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, card)
+}
+
+func (b *bridge) sendMessage(w http.ResponseWriter, req *http.Request) {
+	msg := &a2apb.SendMessageRequest{}
+	if decodeBody(w, req, msg) {
+		restUnary(w, req, b.client.SendMessage, msg)
+	}
+}
+
+func (b *bridge) sendStreamingMessage(w http.ResponseWriter, req *http.Request) {
+	msg := &a2apb.SendMessageRequest{}
+	if decodeBody(w, req, msg) {
+		restStream(w, req, b.client.SendStreamingMessage, msg)
+	}
+}
+
+func (b *bridge) listTasks(w http.ResponseWriter, req *http.Request) {
+	msg := &a2apb.ListTasksRequest{}
+	if decodeQuery(w, req, msg) {
+		restUnary(w, req, b.client.ListTasks, msg)
+	}
+}
+
+func (b *bridge) getTask(w http.ResponseWriter, req *http.Request) {
+	msg := &a2apb.GetTaskRequest{}
+	if decodeQuery(w, req, msg) {
+		msg.Name = pbconv.MakeTaskName(a2a.TaskID(mux.Vars(req)["id"]))
+		restUnary(w, req, b.client.GetTask, msg)
+	}
+}
+
+func (b *bridge) cancelTask(w http.ResponseWriter, req *http.Request) {
+	msg := &a2apb.CancelTaskRequest{Name: pbconv.MakeTaskName(a2a.TaskID(mux.Vars(req)["id"]))}
+	restUnary(w, req, b.client.CancelTask, msg)
+}
+
+func (b *bridge) subscribeToTask(w http.ResponseWriter, req *http.Request) {
+	msg := &a2apb.TaskSubscriptionRequest{Name: pbconv.MakeTaskName(a2a.TaskID(mux.Vars(req)["id"]))}
+	restStream(w, req, b.client.TaskSubscription, msg)
+}
+
+// createPushConfig takes the push notification config as the body, as the annotation's
+// body: "config" does, and its ID from the configId parameter or the config.
+func (b *bridge) createPushConfig(w http.ResponseWriter, req *http.Request) {
+	config := &a2apb.PushNotificationConfig{}
+	if !decodeBody(w, req, config) {
+		return
+	}
+	taskID := a2a.TaskID(mux.Vars(req)["taskId"])
+	configID := req.URL.Query().Get("configId")
+	if configID == "" {
+		configID = config.GetId()
+	}
+	msg := &a2apb.CreateTaskPushNotificationConfigRequest{
+		Parent:   pbconv.MakeTaskName(taskID),
+		ConfigId: configID,
+		Config: &a2apb.TaskPushNotificationConfig{
+			Name:                   pbconv.MakeConfigName(taskID, configID),
+			PushNotificationConfig: config,
+		},
+	}
+	restUnary(w, req, b.client.CreateTaskPushNotificationConfig, msg)
+}
+
+func (b *bridge) listPushConfigs(w http.ResponseWriter, req *http.Request) {
+	msg := &a2apb.ListTaskPushNotificationConfigRequest{}
+	if decodeQuery(w, req, msg) {
+		msg.Parent = pbconv.MakeTaskName(a2a.TaskID(mux.Vars(req)["taskId"]))
+		restUnary(w, req, b.client.ListTaskPushNotificationConfig, msg)
+	}
+}
+
+func (b *bridge) getPushConfig(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	msg := &a2apb.GetTaskPushNotificationConfigRequest{Name: pbconv.MakeConfigName(a2a.TaskID(vars["taskId"]), vars["id"])}
+	restUnary(w, req, b.client.GetTaskPushNotificationConfig, msg)
+}
+
+func (b *bridge) deletePushConfig(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	msg := &a2apb.DeleteTaskPushNotificationConfigRequest{Name: pbconv.MakeConfigName(a2a.TaskID(vars["taskId"]), vars["id"])}
+	restUnary(w, req, b.client.DeleteTaskPushNotificationConfig, msg)
+}
+
+func (b *bridge) extendedAgentCard(w http.ResponseWriter, req *http.Request) {
+	card, err := b.agentCard(req.Context())
+	if err != nil {
+		writeRESTError(w, err)
+		return
+	}
+	pc, err := pbconv.ToProtoAgentCard(card)
+	if err != nil {
+		writeRESTError(w, err)
+		return
+	}
+	writeProtoJSON(w, pc)
+}
+
+// restUnary makes an in-process unary call for the HTTP+JSON binding and writes its result.
+func restUnary[Req, Resp any](w http.ResponseWriter, req *http.Request, call func(context.Context, *connect.Request[Req]) (*connect.Response[Resp], error), msg *Req) {
+	resp, err := callUnary(req.Context(), call, msg)
+	if err != nil {
+		writeRESTError(w, err)
+		return
+	}
+	writeProtoJSON(w, any(resp).(proto.Message))
+}
+
+// restStream relays an in-process streaming call as Server-Sent Events, one StreamResponse
+// per data line. Errors before the first event get an error response; later ones end the
+// stream with an "error" event carrying the same body.
+func restStream[Req any](w http.ResponseWriter, req *http.Request, call func(context.Context, *connect.Request[Req]) (*connect.ServerStreamForClient[a2apb.StreamResponse], error), msg *Req) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeRESTError(w, errors.New("streaming not supported"))
+		return
+	}
+	stream, err := call(req.Context(), connect.NewRequest(msg))
+	if err != nil {
+		writeRESTError(w, err)
+		return
+	}
+	defer stream.Close()
+	started := false
+	start := func() {
+		started = true
+		bridgeCallFromContext(req.Context()).copyResponse(stream.ResponseHeader(), nil)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+	}
+	for stream.Receive() {
+		if !started {
+			start()
+		}
+		data, err := protojson.Marshal(stream.Msg())
+		if err != nil {
+			return
+		}
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+	err = stream.Err()
+	if !started {
+		if err != nil {
+			var cerr *connect.Error
+			if errors.As(err, &cerr) {
+				bridgeCallFromContext(req.Context()).copyResponse(cerr.Meta(), nil)
+			}
+			writeRESTError(w, err)
+			return
+		}
+		start()
+		return
+	}
+	if err != nil && req.Context().Err() == nil {
+		_, body := restError(err)
+		data, _ := json.Marshal(body)
+		fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+		flusher.Flush()
+	}
+}
+
+// decodeBody reads the protobuf JSON body into msg, answering malformed ones itself.
+// Unknown fields are ignored, so clients of newer protocol versions are understood.
+func decodeBody(w http.ResponseWriter, req *http.Request, msg proto.Message) bool {
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxUploadSize))
+	if err == nil {
+		err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(body, msg)
+	}
+	if err != nil {
+		writeRESTError(w, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("request body: %w", err)))
+		return false
+	}
+	return true
+}
+
+// decodeQuery sets the scalar fields of msg named by query parameters, answering invalid
+// values itself. Unknown parameters are ignored.
+func decodeQuery(w http.ResponseWriter, req *http.Request, msg proto.Message) bool {
+	if err := unmarshalQuery(req.URL.Query(), msg); err != nil {
+		writeRESTError(w, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("query: %w", err)))
+		return false
+	}
+	return true
+}
+
+func unmarshalQuery(q url.Values, msg proto.Message) error {
+	fields := msg.ProtoReflect().Descriptor().Fields()
+	obj := map[string]any{}
+	for key, values := range q {
+		fd := fields.ByJSONName(key)
+		if fd == nil {
+			fd = fields.ByName(protoreflect.Name(key))
+		}
+		if fd == nil || fd.Cardinality() == protoreflect.Repeated || fd.Message() != nil {
+			continue
+		}
+		v := values[len(values)-1]
+		switch fd.Kind() {
+		case protoreflect.BoolKind:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			obj[fd.JSONName()] = b
+		case protoreflect.EnumKind:
+			if n, err := strconv.Atoi(v); err == nil {
+				obj[fd.JSONName()] = n
+			} else {
+				obj[fd.JSONName()] = v
+			}
+		default:
+			// protojson accepts numbers as strings.
+			obj[fd.JSONName()] = v
+		}
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	return protojson.Unmarshal(data, msg)
+}
+
+func writeProtoJSON(w http.ResponseWriter, msg proto.Message) {
+	data, err := protojson.Marshal(msg)
+	if err != nil {
+		writeRESTError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// writeRESTError answers with err in the form Google-style HTTP APIs use:
+// {"error": {"code": 404, "status": "NOT_FOUND", "message": "..."}}.
+func writeRESTError(w http.ResponseWriter, err error) {
+	status, body := restError(err)
+	writeJSON(w, status, body)
+}
+
+func restError(err error) (int, map[string]any) {
+	code := connect.CodeOf(err)
+	msg := err.Error()
+	var cerr *connect.Error
+	if errors.As(err, &cerr) {
+		msg = cerr.Message()
+	}
+	status := harStatus(code.String(), false)
+	return status, map[string]any{"error": map[string]any{
+		"code":    status,
+		"status":  strings.ToUpper(code.String()),
+		"message": msg,
+	}}
+}
@@ -0,0 +1,215 @@
+package bff
+
+import (
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"path"
+
+	"connectrpc.com/connect"
+	"github.com/a2aproject/a2a-go/a2apb"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// Call describes the A2AService call a CallInterceptor runs around.
+type Call struct {
+	// Procedure is the full name of the method, such as "/a2a.v1.A2AService/SendMessage",
+	// and Method its short name, such as "SendMessage".
+	Procedure string
+	Method    string
+	// Header is the request header from the browser or the gateway client. It does not
+	// reach the agent.
+	Header http.Header
+	// AgentHeaders are the headers sent to the agent. Request interceptors may change
+	// them, for example to add a tenant ID.
+	AgentHeaders map[string]string
+}
+
+// CallInterceptor customizes the traffic between the BFF and the agent without forking
+// it: rewriting message metadata, injecting tenant IDs, masking PII or blocking skills.
+// It sees every A2AService call from the UI, the CLI and the gateway, to a gRPC or a
+// JSON-RPC agent alike. Messages are changed in place. An error fails the call, or ends
+// the stream, with it; return a *connect.Error to pick the code, such as
+// connect.CodePermissionDenied.
+type CallInterceptor interface {
+	// InterceptRequest runs before the request is sent to the agent.
+	InterceptRequest(ctx context.Context, call *Call, req proto.Message) error
+	// InterceptResponse runs on the response of a unary call.
+	InterceptResponse(ctx context.Context, call *Call, resp proto.Message) error
+	// InterceptEvent runs on every event of SendStreamingMessage and TaskSubscription.
+	InterceptEvent(ctx context.Context, call *Call, event *a2apb.StreamResponse) error
+}
+
+// CallInterceptorFuncs is a CallInterceptor made of functions, any of which may be nil.
+type CallInterceptorFuncs struct {
+	Request  func(ctx context.Context, call *Call, req proto.Message) error
+	Response func(ctx context.Context, call *Call, resp proto.Message) error
+	Event    func(ctx context.Context, call *Call, event *a2apb.StreamResponse) error
+}
+
+func (f CallInterceptorFuncs) InterceptRequest(ctx context.Context, call *Call, req proto.Message) error {
+	if f.Request == nil {
+		return nil
+	}
+	return f.Request(ctx, call, req)
+}
+
+func (f CallInterceptorFuncs) InterceptResponse(ctx context.Context, call *Call, resp proto.Message) error {
+	if f.Response == nil {
+		return nil
+	}
+	return f.Response(ctx, call, resp)
+}
+
+func (f CallInterceptorFuncs) InterceptEvent(ctx context.Context, call *Call, event *a2apb.StreamResponse) error {
+	if f.Event == nil {
+		return nil
+	}
+	return f.Event(ctx, call, event)
+}
+
+// callInterceptors runs CallInterceptors around the calls to the agent.
+type callInterceptors struct {
+	list []CallInterceptor
+}
+
+// NewCallInterceptors returns the interceptor that runs list around every call. The
+// first in the list is the outermost: it sees requests first, and responses and events
+// last. The interceptor belongs inside the recorders, so sessions and the task cache
+// keep what the agent was sent and what the browser sees, and outside the deadline, so
+// retries are not intercepted twice.
+func NewCallInterceptors(list ...CallInterceptor) connect.Interceptor {
+	return callInterceptors{list: list}
+}
+
+func (i callInterceptors) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		call := newCall(ctx, req.Spec().Procedure, req.Header())
+		msg, ok := req.Any().(proto.Message)
+		if !ok {
+			return next(ctx, req)
+		}
+		ctx, err := i.request(ctx, call, msg)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := next(ctx, req)
+		if err != nil {
+			return resp, err
+		}
+		if out, ok := resp.Any().(proto.Message); ok {
+			for _, ci := range i.reversed() {
+				if err := ci.InterceptResponse(ctx, call, out); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return resp, nil
+	}
+}
+
+func (i callInterceptors) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler reads the request before the call starts, so request
+// interceptors can change the agent headers it is sent with.
+func (i callInterceptors) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		req, err := newRequestMessage(conn.Spec())
+		if err != nil {
+			return next(ctx, conn)
+		}
+		if err := conn.Receive(req); err != nil {
+			return err
+		}
+		call := newCall(ctx, conn.Spec().Procedure, conn.RequestHeader())
+		if ctx, err = i.request(ctx, call, req); err != nil {
+			return err
+		}
+		return next(ctx, &interceptedConn{StreamingHandlerConn: conn, interceptors: i, ctx: ctx, call: call, req: req})
+	}
+}
+
+// request runs the request interceptors, and returns ctx with the agent headers they
+// left.
+func (i callInterceptors) request(ctx context.Context, call *Call, req proto.Message) (context.Context, error) {
+	for _, ci := range i.list {
+		if err := ci.InterceptRequest(ctx, call, req); err != nil {
+			return ctx, err
+		}
+	}
+	return context.WithValue(ctx, AgentHeadersKey{}, call.AgentHeaders), nil
+}
+
+// reversed returns the interceptors innermost first, the order responses go through.
+func (i callInterceptors) reversed() []CallInterceptor {
+	out := make([]CallInterceptor, len(i.list))
+	for n, ci := range i.list {
+		out[len(i.list)-1-n] = ci
+	}
+	return out
+}
+
+// newCall describes a call, with a copy of its agent headers for interceptors to change.
+func newCall(ctx context.Context, procedure string, header http.Header) *Call {
+	return &Call{
+		Procedure:    procedure,
+		Method:       path.Base(procedure),
+		Header:       header,
+		AgentHeaders: maps.Clone(AgentHeadersFromContext(ctx)),
+	}
+}
+
+// newRequestMessage returns an empty request message of the method in spec.
+func newRequestMessage(spec connect.Spec) (proto.Message, error) {
+	md, ok := spec.Schema.(protoreflect.MethodDescriptor)
+	if !ok {
+		return nil, errors.New("no schema for " + spec.Procedure)
+	}
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(md.Input().FullName())
+	if err != nil {
+		return nil, err
+	}
+	return mt.New().Interface(), nil
+}
+
+// interceptedConn hands the intercepted request to the call and runs the event
+// interceptors on what it sends.
+type interceptedConn struct {
+	connect.StreamingHandlerConn
+	interceptors callInterceptors
+	ctx          context.Context
+	call         *Call
+	req          proto.Message
+	received     bool
+}
+
+func (c *interceptedConn) Receive(msg any) error {
+	if c.received {
+		return io.EOF
+	}
+	out, ok := msg.(proto.Message)
+	if !ok || out.ProtoReflect().Descriptor() != c.req.ProtoReflect().Descriptor() {
+		return connect.NewError(connect.CodeInternal, errors.New("intercepted stream: unexpected request type"))
+	}
+	c.received = true
+	proto.Reset(out)
+	proto.Merge(out, c.req)
+	return nil
+}
+
+func (c *interceptedConn) Send(msg any) error {
+	if ev, ok := msg.(*a2apb.StreamResponse); ok {
+		for _, ci := range c.interceptors.reversed() {
+			if err := ci.InterceptEvent(c.ctx, c.call, ev); err != nil {
+				return err
+			}
+		}
+	}
+	return c.StreamingHandlerConn.Send(msg)
+}
@@ -0,0 +1,268 @@
+package bff
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/a2aproject/a2a-go/a2apb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Tolerance controls how strictly two A2A results are compared.
+type Tolerance string
+
+const (
+	// ToleranceExact requires identical content; only IDs and timestamps are ignored.
+	ToleranceExact Tolerance = "exact"
+	// ToleranceNormalized also ignores case and whitespace differences in strings.
+	ToleranceNormalized Tolerance = "normalized"
+	// ToleranceStructure compares only the shape: fields present, value types and list lengths.
+	ToleranceStructure Tolerance = "structure"
+)
+
+// ParseTolerance validates a tolerance name.
+func ParseTolerance(s string) (Tolerance, error) {
+	switch t := Tolerance(strings.ToLower(s)); t {
+	case ToleranceExact, ToleranceNormalized, ToleranceStructure:
+		return t, nil
+	}
+	return "", fmt.Errorf("unknown tolerance %q (want exact, normalized or structure)", s)
+}
+
+// volatileKeys are fields that legitimately differ between two runs of the same
+// conversation and are dropped before comparing.
+var volatileKeys = map[string]bool{
+	"id":               true,
+	"taskId":           true,
+	"contextId":        true,
+	"messageId":        true,
+	"artifactId":       true,
+	"timestamp":        true,
+	"referenceTaskIds": true,
+}
+
+// Difference is one path at which two results disagree. A nil side means the path is
+// missing from that result.
+type Difference struct {
+	Path  string `json:"path"`
+	Left  any    `json:"left"`
+	Right any    `json:"right"`
+}
+
+// String formats the difference for terminal output.
+func (d Difference) String() string {
+	return fmt.Sprintf("%s: %s != %s", d.Path, diffValue(d.Left), diffValue(d.Right))
+}
+
+func diffValue(v any) string {
+	if v == nil {
+		return "<missing>"
+	}
+	b, _ := json.Marshal(v)
+	if len(b) > 80 {
+		return string(b[:77]) + "..."
+	}
+	return string(b)
+}
+
+// CompareJSON compares two JSON documents under tol and returns their differences,
+// with paths in JSONPath form ($.status.state).
+func CompareJSON(left, right []byte, tol Tolerance) ([]Difference, error) {
+	var l, r any
+	if err := json.Unmarshal(left, &l); err != nil {
+		return nil, fmt.Errorf("decode left: %w", err)
+	}
+	if err := json.Unmarshal(right, &r); err != nil {
+		return nil, fmt.Errorf("decode right: %w", err)
+	}
+	var diffs []Difference
+	diffJSON("$", NormalizeJSON(l, tol), NormalizeJSON(r, tol), &diffs)
+	return diffs, nil
+}
+
+// CompareMessages is CompareJSON for protos, compared in their protojson form.
+func CompareMessages(left, right proto.Message, tol Tolerance) ([]Difference, error) {
+	l, err := protojson.Marshal(left)
+	if err != nil {
+		return nil, err
+	}
+	r, err := protojson.Marshal(right)
+	if err != nil {
+		return nil, err
+	}
+	return CompareJSON(l, r, tol)
+}
+
+// NormalizeJSON drops volatile keys from a decoded JSON value and applies tol to its leaves.
+func NormalizeJSON(v any, tol Tolerance) any {
+	switch t := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, val := range t {
+			if volatileKeys[k] {
+				continue
+			}
+			out[k] = NormalizeJSON(val, tol)
+		}
+		return out
+	case []any:
+		out := make([]any, len(t))
+		for i, val := range t {
+			out[i] = NormalizeJSON(val, tol)
+		}
+		return out
+	case string:
+		switch tol {
+		case ToleranceNormalized:
+			return strings.ToLower(strings.Join(strings.Fields(t), " "))
+		case ToleranceStructure:
+			return "<string>"
+		}
+	case float64:
+		if tol == ToleranceStructure {
+			return "<number>"
+		}
+	case bool:
+		if tol == ToleranceStructure {
+			return "<bool>"
+		}
+	}
+	return v
+}
+
+// diffJSON appends the differences between two normalized values to out.
+func diffJSON(path string, l, r any, out *[]Difference) {
+	switch lt := l.(type) {
+	case map[string]any:
+		rt, ok := r.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(lt)+len(rt))
+		for k := range lt {
+			keys = append(keys, k)
+		}
+		for k := range rt {
+			if _, seen := lt[k]; !seen {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			diffJSON(path+"."+k, lt[k], rt[k], out)
+		}
+		return
+	case []any:
+		rt, ok := r.([]any)
+		if !ok {
+			break
+		}
+		n := max(len(lt), len(rt))
+		for i := range n {
+			var lv, rv any
+			if i < len(lt) {
+				lv = lt[i]
+			}
+			if i < len(rt) {
+				rv = rt[i]
+			}
+			diffJSON(fmt.Sprintf("%s[%d]", path, i), lv, rv, out)
+		}
+		return
+	}
+	if l == nil && r == nil {
+		return
+	}
+	lb, _ := json.Marshal(l)
+	rb, _ := json.Marshal(r)
+	if string(lb) != string(rb) {
+		*out = append(*out, Difference{Path: path, Left: l, Right: r})
+	}
+}
+
+// SendResult returns the Task or Message carried by a unary SendMessage response.
+func SendResult(resp *a2apb.SendMessageResponse) proto.Message {
+	if t := resp.GetTask(); t != nil {
+		return t
+	}
+	if m := resp.GetMsg(); m != nil {
+		return m
+	}
+	return &a2apb.Task{}
+}
+
+// StreamResult folds stream events into the result a client ends up with: the last
+// Message, or the Task with every status and artifact update applied.
+func StreamResult(events []*a2apb.StreamResponse) proto.Message {
+	var task *a2apb.Task
+	for _, ev := range events {
+		if m := ev.GetMsg(); m != nil {
+			return m
+		}
+		task = foldEvent(task, ev)
+	}
+	if task == nil {
+		return &a2apb.Task{}
+	}
+	return task
+}
+
+// foldEvent applies a task, status or artifact event to task and returns the result,
+// starting a new task when task is nil. Messages are ignored. task is updated in place;
+// the event itself is never modified.
+func foldEvent(task *a2apb.Task, ev *a2apb.StreamResponse) *a2apb.Task {
+	ensure := func(taskID, contextID string) {
+		if task == nil {
+			task = &a2apb.Task{Id: taskID, ContextId: contextID}
+		}
+	}
+	switch p := ev.GetPayload().(type) {
+	case *a2apb.StreamResponse_Task:
+		task = proto.Clone(p.Task).(*a2apb.Task)
+	case *a2apb.StreamResponse_StatusUpdate:
+		ensure(p.StatusUpdate.GetTaskId(), p.StatusUpdate.GetContextId())
+		task.Status = p.StatusUpdate.GetStatus()
+	case *a2apb.StreamResponse_ArtifactUpdate:
+		ensure(p.ArtifactUpdate.GetTaskId(), p.ArtifactUpdate.GetContextId())
+		applyArtifactUpdate(task, p.ArtifactUpdate)
+	}
+	return task
+}
+
+// applyArtifactUpdate adds the artifact to task, or appends its parts to the artifact
+// with the same ID when the update is an append.
+func applyArtifactUpdate(task *a2apb.Task, u *a2apb.TaskArtifactUpdateEvent) {
+	a := u.GetArtifact()
+	for i, existing := range task.Artifacts {
+		if existing.GetArtifactId() != a.GetArtifactId() {
+			continue
+		}
+		if u.GetAppend() {
+			merged := proto.Clone(existing).(*a2apb.Artifact)
+			merged.Parts = append(merged.Parts, a.GetParts()...)
+			task.Artifacts[i] = merged
+		} else {
+			task.Artifacts[i] = a
+		}
+		return
+	}
+	task.Artifacts = append(task.Artifacts, a)
+}
+
+// EventKind names the payload of a stream event: task, message, status-update or artifact-update.
+func EventKind(ev *a2apb.StreamResponse) string {
+	switch ev.GetPayload().(type) {
+	case *a2apb.StreamResponse_Task:
+		return "task"
+	case *a2apb.StreamResponse_Msg:
+		return "message"
+	case *a2apb.StreamResponse_StatusUpdate:
+		return "status-update"
+	case *a2apb.StreamResponse_ArtifactUpdate:
+		return "artifact-update"
+	}
+	return "unknown"
+}
@@ -1,5 +1,14 @@
 package bff
 
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/a2aproject/a2a-go/a2apb"
+	"google.golang.org/grpc"
+)
+
 // Protocol identifies the A2A agent transport protocol.
 type Protocol string
 
@@ -12,4 +21,70 @@ const (
 type AgentConfig struct {
 	URL      string
 	Protocol Protocol
+	// Version is the A2A version spoken to a gRPC agent; the zero value is Version03.
+	Version ProtocolVersion
+	// CardURL is the URL of the agent's public card. It defaults to the well-known path
+	// at the agent URL's host.
+	CardURL string
+	// Conn or Client, when set, reaches a gRPC agent, such as one in the same process,
+	// instead of URL, which then only names it. A Client speaks A2A 0.3.
+	Conn   grpc.ClientConnInterface
+	Client a2apb.A2AServiceClient
+}
+
+// OtherTransport returns the configuration of the same agent at url on the other transport
+// protocol, which speaks A2A 0.3: v1 is only supported over gRPC, so an agent configured
+// over JSON-RPC speaks 0.3 on both.
+func (c AgentConfig) OtherTransport(url string) AgentConfig {
+	other := AgentConfig{URL: url, Protocol: ProtocolJSONRPC, CardURL: c.CardURL}
+	if c.Protocol == ProtocolJSONRPC {
+		other.Protocol = ProtocolGRPC
+	}
+	return other
+}
+
+// NewProxy returns the proxy for the agent's transport protocol.
+func NewProxy(cfg AgentConfig) A2AServiceHandler {
+	if cfg.Protocol == ProtocolJSONRPC {
+		p := NewJSONRPCProxy(cfg.URL)
+		if cfg.CardURL != "" {
+			p.cardURL = cfg.CardURL
+		}
+		return p
+	}
+	var p *grpcProxy
+	switch {
+	case cfg.Conn != nil:
+		p = NewGrpcProxyFromConn(cfg.Conn, cfg.Version)
+		p.agentURL = cfg.URL
+	case cfg.Client != nil:
+		p = NewGrpcProxyFromClient(cfg.Client)
+		p.agentURL = cfg.URL
+	default:
+		p = NewGrpcProxy(cfg.URL, cfg.Version)
+	}
+	if cfg.CardURL != "" {
+		p.cardURL = cfg.CardURL
+	}
+	return p
+}
+
+// DefaultDataDir returns the per-user directory for playground data:
+// $XDG_DATA_HOME or ~/.local/share on Linux, the user config dir elsewhere.
+func DefaultDataDir() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "a2a-playground"), nil
+	}
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, "a2a-playground"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "a2a-playground"), nil
 }
@@ -0,0 +1,121 @@
+package bff
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/a2aproject/a2a-go/a2apb"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// AnyRPC is the RPCTimeouts key for methods without a timeout of their own.
+const AnyRPC = "*"
+
+// RPCTimeouts maps A2AService method names, such as SendMessage, to the deadline for
+// calls to the agent. AnyRPC applies to methods not listed.
+type RPCTimeouts map[string]time.Duration
+
+// ParseRPCTimeouts parses method=duration pairs, as given to --rpc-timeout.
+func ParseRPCTimeouts(pairs map[string]string) (RPCTimeouts, error) {
+	methods := a2apb.File_a2a_proto.Services().ByName("A2AService").Methods()
+	out := RPCTimeouts{}
+	for method, v := range pairs {
+		method = strings.TrimSpace(method)
+		if method != AnyRPC && methods.ByName(protoreflect.Name(method)) == nil {
+			return nil, fmt.Errorf("unknown A2A method %q", method)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("timeout for %s: want a positive duration such as 30s, got %q", method, v)
+		}
+		out[method] = d
+	}
+	return out, nil
+}
+
+// forProcedure returns the timeout for a procedure such as "/a2a.v1.A2AService/GetTask".
+func (t RPCTimeouts) forProcedure(procedure string) time.Duration {
+	if d, ok := t[path.Base(procedure)]; ok {
+		return d
+	}
+	return t[AnyRPC]
+}
+
+// deadlineInterceptor bounds calls to the agent by the configured timeout and by the
+// timeout the client sent, whichever is shorter.
+type deadlineInterceptor struct {
+	timeouts RPCTimeouts
+}
+
+// NewDeadlineInterceptor returns the interceptor that sets deadlines on calls to the agent.
+// The client's timeout is read from the Connect-Timeout-Ms or Grpc-Timeout header rather
+// than the context, so it still applies to upstream calls detached from the browser.
+func NewDeadlineInterceptor(timeouts RPCTimeouts) connect.Interceptor {
+	return deadlineInterceptor{timeouts}
+}
+
+// withDeadline returns ctx bounded by the timeout for procedure.
+func (i deadlineInterceptor) withDeadline(ctx context.Context, procedure string, header http.Header) (context.Context, context.CancelFunc) {
+	d := i.timeouts.forProcedure(procedure)
+	if client, ok := clientTimeout(header); ok && (d == 0 || client < d) {
+		d = client
+	}
+	if d == 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+func (i deadlineInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		ctx, cancel := i.withDeadline(ctx, req.Spec().Procedure, req.Header())
+		defer cancel()
+		return next(ctx, req)
+	}
+}
+
+func (i deadlineInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (i deadlineInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		ctx, cancel := i.withDeadline(ctx, conn.Spec().Procedure, conn.RequestHeader())
+		defer cancel()
+		return next(ctx, conn)
+	}
+}
+
+// clientTimeout returns the timeout from a Connect-Timeout-Ms header, or from a
+// Grpc-Timeout header for gRPC and gRPC-Web clients.
+func clientTimeout(h http.Header) (time.Duration, bool) {
+	if v := h.Get("Connect-Timeout-Ms"); v != "" {
+		ms, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || ms <= 0 {
+			return 0, false
+		}
+		return time.Duration(ms) * time.Millisecond, true
+	}
+	v := h.Get("Grpc-Timeout")
+	if len(v) < 2 {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(v[:len(v)-1], 10, 64)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	unit := map[byte]time.Duration{
+		'H': time.Hour, 'M': time.Minute, 'S': time.Second,
+		'm': time.Millisecond, 'u': time.Microsecond, 'n': time.Nanosecond,
+	}[v[len(v)-1]]
+	if unit == 0 {
+		return 0, false
+	}
+	return time.Duration(n) * unit, true
+}
@@ -0,0 +1,61 @@
+package bff
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"connectrpc.com/connect"
+	"github.com/a2aproject/a2a-go/a2apb"
+	"google.golang.org/protobuf/proto"
+)
+
+// detachedConn is the connection a shared or long-lived upstream call writes to. The
+// request has already been read from the browser, events go to send, and nothing touches
+// the browser connection, which may be gone long before the upstream call ends.
+type detachedConn struct {
+	connect.StreamingHandlerConn
+	req             proto.Message
+	received        bool
+	send            func(*a2apb.StreamResponse) error
+	requestHeader   http.Header
+	responseHeader  http.Header
+	responseTrailer http.Header
+}
+
+func newDetachedConn(conn connect.StreamingHandlerConn, req proto.Message, send func(*a2apb.StreamResponse) error) *detachedConn {
+	return &detachedConn{
+		StreamingHandlerConn: conn,
+		req:                  req,
+		send:                 send,
+		requestHeader:        conn.RequestHeader().Clone(),
+		responseHeader:       http.Header{},
+		responseTrailer:      http.Header{},
+	}
+}
+
+func (c *detachedConn) Receive(msg any) error {
+	if c.received {
+		return io.EOF
+	}
+	out, ok := msg.(proto.Message)
+	if !ok || out.ProtoReflect().Descriptor() != c.req.ProtoReflect().Descriptor() {
+		return connect.NewError(connect.CodeInternal, errors.New("detached stream: unexpected request type"))
+	}
+	c.received = true
+	proto.Reset(out)
+	proto.Merge(out, c.req)
+	return nil
+}
+
+func (c *detachedConn) Send(msg any) error {
+	ev, ok := msg.(*a2apb.StreamResponse)
+	if !ok {
+		return connect.NewError(connect.CodeInternal, errors.New("detached stream: unexpected response type"))
+	}
+	return c.send(ev)
+}
+
+func (c *detachedConn) RequestHeader() http.Header   { return c.requestHeader }
+func (c *detachedConn) ResponseHeader() http.Header  { return c.responseHeader }
+func (c *detachedConn) ResponseTrailer() http.Header { return c.responseTrailer }
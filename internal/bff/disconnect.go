@@ -0,0 +1,102 @@
+package bff
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/a2aproject/a2a-go/a2apb"
+	"github.com/alis-exchange/a2a-playground/gen/go/a2apbconnect"
+)
+
+// cancelTimeout bounds the CancelTask call sent after a client disconnects.
+const cancelTimeout = 10 * time.Second
+
+// cancelOnDisconnect sends CancelTask for a streamed task whose client went away while the
+// task was still running. Tasks waiting for input are left alone, since they are not
+// doing any work.
+type cancelOnDisconnect struct {
+	agent a2apbconnect.A2AServiceHandler
+}
+
+// NewCancelOnDisconnectInterceptor returns the interceptor that cancels the task of a
+// SendStreamingMessage call when the client disconnects before the task ends. With
+// durable streams it belongs inside the durable stream interceptor, where the call is
+// only cancelled once the stream is orphaned.
+func NewCancelOnDisconnectInterceptor(agent a2apbconnect.A2AServiceHandler) connect.Interceptor {
+	return cancelOnDisconnect{agent}
+}
+
+func (i cancelOnDisconnect) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return next
+}
+
+func (i cancelOnDisconnect) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (i cancelOnDisconnect) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		if conn.Spec().Procedure != a2apbconnect.A2AServiceSendStreamingMessageProcedure {
+			return next(ctx, conn)
+		}
+		tc := &taskTrackingConn{StreamingHandlerConn: conn}
+		err := next(ctx, tc)
+		if !errors.Is(ctx.Err(), context.Canceled) || tc.taskID == "" || !isActiveState(tc.state) {
+			return err
+		}
+		cctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cancelTimeout)
+		defer cancel()
+		if _, cerr := i.agent.CancelTask(cctx, connect.NewRequest(&a2apb.CancelTaskRequest{Name: "tasks/" + tc.taskID})); cerr != nil {
+			log.Printf("cancel task %s after client disconnect: %v", tc.taskID, cerr)
+		}
+		return err
+	}
+}
+
+// isActiveState reports whether a task in this state may still be working.
+func isActiveState(state a2apb.TaskState) bool {
+	switch state {
+	case a2apb.TaskState_TASK_STATE_UNSPECIFIED,
+		a2apb.TaskState_TASK_STATE_SUBMITTED,
+		a2apb.TaskState_TASK_STATE_WORKING:
+		return true
+	}
+	return false
+}
+
+// taskTrackingConn remembers the task and latest state seen in the request and events.
+type taskTrackingConn struct {
+	connect.StreamingHandlerConn
+	taskID string
+	state  a2apb.TaskState
+}
+
+func (c *taskTrackingConn) Receive(msg any) error {
+	if err := c.StreamingHandlerConn.Receive(msg); err != nil {
+		return err
+	}
+	if req, ok := msg.(*a2apb.SendMessageRequest); ok && c.taskID == "" {
+		c.taskID = req.GetRequest().GetTaskId()
+	}
+	return nil
+}
+
+func (c *taskTrackingConn) Send(msg any) error {
+	if ev, ok := msg.(*a2apb.StreamResponse); ok {
+		switch p := ev.GetPayload().(type) {
+		case *a2apb.StreamResponse_Task:
+			c.taskID, c.state = p.Task.GetId(), p.Task.GetStatus().GetState()
+		case *a2apb.StreamResponse_StatusUpdate:
+			c.taskID, c.state = p.StatusUpdate.GetTaskId(), p.StatusUpdate.GetStatus().GetState()
+		case *a2apb.StreamResponse_ArtifactUpdate:
+			c.taskID = p.ArtifactUpdate.GetTaskId()
+		case *a2apb.StreamResponse_Msg:
+			// A message reply means no task was created.
+			c.taskID = ""
+		}
+	}
+	return c.StreamingHandlerConn.Send(msg)
+}
@@ -0,0 +1,269 @@
+package bff
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/a2aproject/a2a-go/a2apb"
+	"github.com/alis-exchange/a2a-playground/gen/go/a2apbconnect"
+	"github.com/google/uuid"
+)
+
+const (
+	// streamIDHeader is set on durable SendStreamingMessage responses.
+	streamIDHeader = "X-A2A-Stream-Id"
+	// streamResumeHeader on a SendStreamingMessage request reattaches to a buffered stream
+	// instead of sending the request to the agent.
+	streamResumeHeader = "X-A2A-Stream-Resume"
+	// streamCursorHeader is the number of events the client already received when resuming.
+	streamCursorHeader = "X-A2A-Stream-Cursor"
+
+	// maxBufferedEvents bounds the events kept per stream; older events are dropped first.
+	maxBufferedEvents = 10000
+	// streamRetention is how long a finished stream stays available for reattaching.
+	streamRetention = 5 * time.Minute
+	// orphanTimeout cancels an upstream stream nobody has been attached to for this long.
+	orphanTimeout = 10 * time.Minute
+)
+
+// DurableStreams keeps SendStreamingMessage calls running when the browser goes away.
+// Events are buffered in order, so event n of a stream has sequence number n, and a client
+// that has seen n events reattaches with cursor n to receive the rest.
+type DurableStreams struct {
+	mu      sync.Mutex
+	streams map[string]*durableStream
+	stop    chan struct{}
+	// running counts the upstream calls, so Close can wait for them.
+	running sync.WaitGroup
+}
+
+// NewDurableStreams returns an empty stream buffer and starts its cleanup loop.
+func NewDurableStreams() *DurableStreams {
+	d := &DurableStreams{streams: map[string]*durableStream{}, stop: make(chan struct{})}
+	go d.janitor()
+	return d
+}
+
+// Close cancels every running upstream stream, stops the cleanup loop, and waits for the
+// upstream calls to return.
+func (d *DurableStreams) Close() error {
+	close(d.stop)
+	d.mu.Lock()
+	for _, s := range d.streams {
+		s.cancel()
+	}
+	d.mu.Unlock()
+	d.running.Wait()
+	return nil
+}
+
+// Interceptor returns the Connect interceptor that makes SendStreamingMessage durable.
+// What runs inside it runs with the upstream call and keeps running after the browser
+// leaves, so it must wrap the interceptors that record, cancel or time out the call. The
+// response metadata, extensions and subscription broker interceptors may wrap it in turn:
+// the first two set up the request before it is detached, whose context keeps their
+// values, and report to the browser attached at the time; the broker only handles
+// TaskSubscription.
+func (d *DurableStreams) Interceptor() connect.Interceptor {
+	return durableInterceptor{d}
+}
+
+func (d *DurableStreams) get(id string) *durableStream {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.streams[id]
+}
+
+// janitor drops finished streams after streamRetention and cancels orphaned ones.
+func (d *DurableStreams) janitor() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.stop:
+			return
+		case now := <-ticker.C:
+			d.mu.Lock()
+			for id, s := range d.streams {
+				switch done, idleSince := s.idle(); {
+				case idleSince.IsZero():
+				case done && now.Sub(idleSince) > streamRetention:
+					delete(d.streams, id)
+				case !done && now.Sub(idleSince) > orphanTimeout:
+					s.cancel()
+				}
+			}
+			d.mu.Unlock()
+		}
+	}
+}
+
+// start runs the upstream call for req in the background, detached from ctx's cancellation.
+func (d *DurableStreams) start(ctx context.Context, conn connect.StreamingHandlerConn, req *a2apb.SendMessageRequest, next connect.StreamingHandlerFunc) *durableStream {
+	upstreamCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	s := &durableStream{id: uuid.NewString(), cancel: cancel, changed: make(chan struct{})}
+	d.mu.Lock()
+	d.streams[s.id] = s
+	d.running.Add(1)
+	d.mu.Unlock()
+
+	dc := newDetachedConn(conn, req, func(ev *a2apb.StreamResponse) error {
+		s.append(ev)
+		return nil
+	})
+	go func() {
+		defer d.running.Done()
+		defer cancel()
+		s.finish(next(upstreamCtx, dc))
+	}()
+	return s
+}
+
+// durableStream is one buffered upstream stream.
+type durableStream struct {
+	id     string
+	cancel context.CancelFunc
+
+	mu          sync.Mutex
+	events      []*a2apb.StreamResponse
+	base        int // sequence number of events[0] minus one
+	done        bool
+	err         error
+	changed     chan struct{} // closed and replaced whenever events or done change
+	subscribers int
+	idleSince   time.Time // when the last subscriber left, or when the stream finished
+}
+
+func (s *durableStream) append(ev *a2apb.StreamResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, ev)
+	if len(s.events) > maxBufferedEvents {
+		s.events = s.events[1:]
+		s.base++
+	}
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *durableStream) finish(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.done, s.err = true, err
+	if s.subscribers == 0 {
+		s.idleSince = time.Now()
+	}
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// idle reports whether the stream is done and since when it has had no subscribers
+// (zero while someone is attached).
+func (s *durableStream) idle() (bool, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.done, s.idleSince
+}
+
+// follow sends events after cursor, then live events, until the stream ends, send fails
+// or ctx is done. It returns the upstream error once every event has been sent.
+func (s *durableStream) follow(ctx context.Context, cursor int, send func(seq int, ev *a2apb.StreamResponse) error) error {
+	s.mu.Lock()
+	s.subscribers++
+	s.idleSince = time.Time{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.subscribers--
+		if s.subscribers == 0 {
+			s.idleSince = time.Now()
+		}
+		s.mu.Unlock()
+	}()
+
+	for {
+		s.mu.Lock()
+		if cursor < s.base {
+			s.mu.Unlock()
+			return connect.NewError(connect.CodeOutOfRange, errors.New("stream cursor is older than the buffered events"))
+		}
+		var pending []*a2apb.StreamResponse
+		if cursor-s.base < len(s.events) {
+			pending = s.events[cursor-s.base:]
+		}
+		done, err, changed := s.done, s.err, s.changed
+		s.mu.Unlock()
+
+		for _, ev := range pending {
+			cursor++
+			if err := send(cursor, ev); err != nil {
+				return err
+			}
+		}
+		if len(pending) > 0 {
+			continue
+		}
+		if done {
+			return err
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// durableInterceptor detaches SendStreamingMessage from the browser connection.
+type durableInterceptor struct {
+	streams *DurableStreams
+}
+
+func (i durableInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return next
+}
+
+func (i durableInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler starts a buffered upstream call, or reattaches to one when the
+// request carries X-A2A-Stream-Resume. The stream ID is returned in X-A2A-Stream-Id.
+func (i durableInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		if conn.Spec().Procedure != a2apbconnect.A2AServiceSendStreamingMessageProcedure {
+			return next(ctx, conn)
+		}
+		// Read the request now: the upstream call may outlive this connection's body.
+		req := &a2apb.SendMessageRequest{}
+		if err := conn.Receive(req); err != nil {
+			return err
+		}
+		send := func(_ int, ev *a2apb.StreamResponse) error { return conn.Send(ev) }
+
+		if id := conn.RequestHeader().Get(streamResumeHeader); id != "" {
+			s := i.streams.get(id)
+			if s == nil {
+				return connect.NewError(connect.CodeNotFound, errors.New("stream "+id+" not found or expired"))
+			}
+			cursor := 0
+			if v := conn.RequestHeader().Get(streamCursorHeader); v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil || n < 0 {
+					return connect.NewError(connect.CodeInvalidArgument, errors.New("invalid "+streamCursorHeader+": "+v))
+				}
+				cursor = n
+			}
+			conn.ResponseHeader().Set(streamIDHeader, id)
+			return s.follow(ctx, cursor, send)
+		}
+
+		s := i.streams.start(ctx, conn, req, next)
+		conn.ResponseHeader().Set(streamIDHeader, s.id)
+		return s.follow(ctx, 0, send)
+	}
+}
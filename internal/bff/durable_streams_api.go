@@ -0,0 +1,82 @@
+package bff
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"connectrpc.com/connect"
+	"github.com/a2aproject/a2a-go/a2apb"
+	"github.com/gorilla/mux"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// streamsAPIPath is the prefix of the durable stream API.
+const streamsAPIPath = "/api/streams"
+
+// registerStreamRoutes mounts the durable stream API on r:
+//
+//	GET /api/streams/{id}/events?cursor=n   Server-Sent Events from event n+1 on
+//
+// Each event's SSE id is its sequence number, so an EventSource resumes where it left off
+// through Last-Event-ID. The stream ends with an "end" event carrying the Connect code.
+func registerStreamRoutes(r *mux.Router, streams *DurableStreams) {
+	r.HandleFunc(streamsAPIPath+"/{id}/events", func(w http.ResponseWriter, req *http.Request) {
+		s := streams.get(mux.Vars(req)["id"])
+		if s == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "stream not found or expired"})
+			return
+		}
+		cursor := 0
+		for _, v := range []string{req.Header.Get("Last-Event-ID"), req.URL.Query().Get("cursor")} {
+			if v == "" {
+				continue
+			}
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid cursor " + strconv.Quote(v)})
+				return
+			}
+			cursor = n
+			break
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "streaming not supported"})
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+		err := s.follow(req.Context(), cursor, func(seq int, ev *a2apb.StreamResponse) error {
+			data, err := protojson.Marshal(ev)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\ndata: %s\n\n", seq, data); err != nil {
+				return err
+			}
+			flusher.Flush()
+			return nil
+		})
+		if req.Context().Err() != nil {
+			return
+		}
+		end := map[string]string{"code": "ok"}
+		if err != nil {
+			var cerr *connect.Error
+			if errors.As(err, &cerr) {
+				end = map[string]string{"code": cerr.Code().String(), "message": cerr.Message()}
+			} else {
+				end = map[string]string{"code": connect.CodeUnknown.String(), "message": err.Error()}
+			}
+		}
+		data, _ := json.Marshal(end)
+		fmt.Fprintf(w, "event: end\ndata: %s\n\n", data)
+		flusher.Flush()
+	}).Methods(http.MethodGet)
+}
@@ -0,0 +1,193 @@
+package bff
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/a2aproject/a2a-go/a2a"
+	"github.com/a2aproject/a2a-go/a2apb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// TransportComparer sends the same SendMessageRequest to an agent's gRPC and JSON-RPC
+// bindings through the regular proxies and compares what comes back.
+type TransportComparer struct {
+	GRPCURL    string
+	JSONRPCURL string
+	grpc       *LocalClient
+	jsonrpc    *LocalClient
+}
+
+// ComparedBinding is one side of a TransportComparer.
+type ComparedBinding struct {
+	// URL names the agent's endpoint on the binding in comparisons.
+	URL string
+	// Proxy reaches the agent over the binding.
+	Proxy A2AServiceHandler
+	// Interceptors wrap the calls to Proxy, as the BFF's own interceptors wrap its calls,
+	// so a comparison sees the deadlines, retries and faults the UI does.
+	Interceptors []connect.Interceptor
+}
+
+// NewTransportComparer returns a comparer for an agent's gRPC and JSON-RPC bindings.
+func NewTransportComparer(grpc, jsonrpc ComparedBinding) (*TransportComparer, error) {
+	grpcClient, err := NewLocalClient(grpc.Proxy, nil, connect.WithInterceptors(grpc.Interceptors...))
+	if err != nil {
+		return nil, err
+	}
+	jsonrpcClient, err := NewLocalClient(jsonrpc.Proxy, nil, connect.WithInterceptors(jsonrpc.Interceptors...))
+	if err != nil {
+		_ = grpcClient.Close()
+		return nil, err
+	}
+	return &TransportComparer{GRPCURL: grpc.URL, JSONRPCURL: jsonrpc.URL, grpc: grpcClient, jsonrpc: jsonrpcClient}, nil
+}
+
+// Close stops both in-process clients.
+func (c *TransportComparer) Close() error {
+	return errors.Join(c.grpc.Close(), c.jsonrpc.Close())
+}
+
+// TransportRun is what one binding returned for a compared request.
+type TransportRun struct {
+	URL        string          `json:"url"`
+	DurationMs float64         `json:"durationMs"`
+	Code       string          `json:"code"`
+	Error      string          `json:"error,omitempty"`
+	Events     []string        `json:"events,omitempty"`
+	Result     json.RawMessage `json:"result,omitempty"`
+
+	events []*a2apb.StreamResponse
+	result proto.Message
+}
+
+// TransportComparison is the outcome of sending one request over both bindings. Left
+// in each Difference is gRPC, Right is JSON-RPC.
+type TransportComparison struct {
+	Streaming         bool         `json:"streaming"`
+	Tolerance         Tolerance    `json:"tolerance"`
+	Equivalent        bool         `json:"equivalent"`
+	GRPC              TransportRun `json:"grpc"`
+	JSONRPC           TransportRun `json:"jsonrpc"`
+	ResultDifferences []Difference `json:"resultDifferences"`
+	EventDifferences  []Difference `json:"eventDifferences"`
+}
+
+// Compare sends req over both bindings at the same time. Each binding gets its own
+// messageId so one agent serving both does not reject the second as a duplicate; IDs and
+// timestamps are then ignored by the comparison. Agent headers in ctx are forwarded to both.
+func (c *TransportComparer) Compare(ctx context.Context, req *a2apb.SendMessageRequest, streaming bool, tol Tolerance) (*TransportComparison, error) {
+	var header string
+	if h := AgentHeadersFromContext(ctx); len(h) > 0 {
+		b, err := json.Marshal(h)
+		if err != nil {
+			return nil, err
+		}
+		header = string(b)
+	}
+
+	out := &TransportComparison{
+		Streaming: streaming,
+		Tolerance: tol,
+		GRPC:      TransportRun{URL: c.GRPCURL},
+		JSONRPC:   TransportRun{URL: c.JSONRPCURL},
+	}
+	var wg sync.WaitGroup
+	for _, side := range []struct {
+		client *LocalClient
+		run    *TransportRun
+	}{{c.grpc, &out.GRPC}, {c.jsonrpc, &out.JSONRPC}} {
+		r := proto.Clone(req).(*a2apb.SendMessageRequest)
+		if r.GetRequest() != nil {
+			r.Request.MessageId = a2a.NewMessageID()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			side.run.send(ctx, side.client, r, header, streaming)
+		}()
+	}
+	wg.Wait()
+
+	if out.GRPC.Code != out.JSONRPC.Code {
+		out.ResultDifferences = append(out.ResultDifferences, Difference{Path: "$.code", Left: out.GRPC.Code, Right: out.JSONRPC.Code})
+	}
+	if out.GRPC.result != nil && out.JSONRPC.result != nil {
+		diffs, err := CompareJSON(out.GRPC.Result, out.JSONRPC.Result, tol)
+		if err != nil {
+			return nil, err
+		}
+		out.ResultDifferences = append(out.ResultDifferences, diffs...)
+	}
+	if streaming {
+		left, err := eventsJSON(out.GRPC.events)
+		if err != nil {
+			return nil, err
+		}
+		right, err := eventsJSON(out.JSONRPC.events)
+		if err != nil {
+			return nil, err
+		}
+		if out.EventDifferences, err = CompareJSON(left, right, tol); err != nil {
+			return nil, err
+		}
+	}
+	out.Equivalent = len(out.ResultDifferences) == 0 && len(out.EventDifferences) == 0
+	return out, nil
+}
+
+// send runs the request on one binding and records the events and result.
+func (r *TransportRun) send(ctx context.Context, client *LocalClient, req *a2apb.SendMessageRequest, header string, streaming bool) {
+	start := time.Now()
+	creq := connect.NewRequest(req)
+	if header != "" {
+		creq.Header().Set(agentHeadersHeader, header)
+	}
+	var err error
+	if streaming {
+		var stream *connect.ServerStreamForClient[a2apb.StreamResponse]
+		if stream, err = client.SendStreamingMessage(ctx, creq); err == nil {
+			for stream.Receive() {
+				r.events = append(r.events, stream.Msg())
+				r.Events = append(r.Events, EventKind(stream.Msg()))
+			}
+			err = errors.Join(stream.Err(), stream.Close())
+			r.result = StreamResult(r.events)
+		}
+	} else {
+		var resp *connect.Response[a2apb.SendMessageResponse]
+		if resp, err = client.SendMessage(ctx, creq); err == nil {
+			r.result = SendResult(resp.Msg)
+		}
+	}
+	r.DurationMs = millis(time.Since(start))
+	r.Code = "ok"
+	if err != nil {
+		r.Code = connect.CodeOf(err).String()
+		r.Error = err.Error()
+		r.result = nil
+		return
+	}
+	r.Result, err = protojson.Marshal(r.result)
+	if err != nil {
+		r.Code, r.Error, r.result = connect.CodeInternal.String(), err.Error(), nil
+	}
+}
+
+// eventsJSON encodes events as a JSON array so sequences can be diffed element by element.
+func eventsJSON(events []*a2apb.StreamResponse) ([]byte, error) {
+	raw := make([]json.RawMessage, 0, len(events))
+	for _, ev := range events {
+		b, err := protojson.Marshal(ev)
+		if err != nil {
+			return nil, err
+		}
+		raw = append(raw, b)
+	}
+	return json.Marshal(raw)
+}
@@ -0,0 +1,52 @@
+package bff
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/a2aproject/a2a-go/a2apb"
+	"github.com/gorilla/mux"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// compareAPIPath is the cross-transport comparison endpoint.
+const compareAPIPath = "/api/compare"
+
+// registerCompareRoutes mounts the comparison API on r:
+//
+//	POST /api/compare   {"request": SendMessageRequest, "streaming": true, "tolerance": "normalized"}
+//
+// The request is sent over gRPC and JSON-RPC at once and a TransportComparison is returned.
+func registerCompareRoutes(r *mux.Router, comparer *TransportComparer) {
+	r.Handle(compareAPIPath, AgentHeadersMiddleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var body struct {
+			Request   json.RawMessage `json:"request"`
+			Streaming *bool           `json:"streaming"`
+			Tolerance string          `json:"tolerance"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil || len(body.Request) == 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "body must be {\"request\": SendMessageRequest, \"streaming\": bool, \"tolerance\": \"...\"}"})
+			return
+		}
+		sendReq := &a2apb.SendMessageRequest{}
+		if err := protojson.Unmarshal(body.Request, sendReq); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "request: " + err.Error()})
+			return
+		}
+		tol := ToleranceNormalized
+		if body.Tolerance != "" {
+			var err error
+			if tol, err = ParseTolerance(body.Tolerance); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+		}
+		streaming := body.Streaming == nil || *body.Streaming
+		result, err := comparer.Compare(req.Context(), sendReq, streaming, tol)
+		if err != nil {
+			writeJSONError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+	}))).Methods(http.MethodPost)
+}
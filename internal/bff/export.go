@@ -0,0 +1,441 @@
+package bff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/a2aproject/a2a-go/a2apb"
+	"github.com/alis-exchange/a2a-playground/gen/go/a2apbconnect"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// ExportFormat selects the encoding produced by ExportSession.
+type ExportFormat string
+
+const (
+	// ExportJSON is the canonical session document: the session and every record.
+	ExportJSON ExportFormat = "json"
+	// ExportMarkdown is a readable transcript with a timing table.
+	ExportMarkdown ExportFormat = "markdown"
+	// ExportHAR is an HTTP Archive (HAR 1.2) of the recorded Connect calls.
+	ExportHAR ExportFormat = "har"
+)
+
+// exportDocFormat identifies the canonical JSON export so later versions can migrate it.
+const exportDocFormat = "a2a-playground.session/v1"
+
+// exportDoc is the canonical JSON export.
+type exportDoc struct {
+	Format     string          `json:"format"`
+	ExportedAt time.Time       `json:"exportedAt"`
+	Session    *Session        `json:"session"`
+	Records    []SessionRecord `json:"records"`
+}
+
+// ParseExportFormat validates a format name; "md" is accepted for markdown.
+func ParseExportFormat(s string) (ExportFormat, error) {
+	switch strings.ToLower(s) {
+	case "", "json":
+		return ExportJSON, nil
+	case "markdown", "md":
+		return ExportMarkdown, nil
+	case "har":
+		return ExportHAR, nil
+	}
+	return "", fmt.Errorf("unknown export format %q (want json, markdown or har)", s)
+}
+
+// ContentType returns the MIME type of the export.
+func (f ExportFormat) ContentType() string {
+	switch f {
+	case ExportMarkdown:
+		return "text/markdown; charset=utf-8"
+	}
+	return "application/json"
+}
+
+// Filename returns the default file name for exporting contextID.
+func (f ExportFormat) Filename(contextID string) string {
+	ext := "json"
+	switch f {
+	case ExportMarkdown:
+		ext = "md"
+	case ExportHAR:
+		ext = "har"
+	}
+	return fmt.Sprintf("session-%s.%s", contextID, ext)
+}
+
+// ExportSession encodes a recorded session. Secret header values and sensitive JSON
+// fields are redacted in every format.
+func ExportSession(sess *Session, recs []SessionRecord, format ExportFormat) ([]byte, error) {
+	clean := make([]SessionRecord, len(recs))
+	for i, rec := range recs {
+		rec.Data = redactJSON(rec.Data)
+		clean[i] = rec
+	}
+	switch format {
+	case ExportJSON:
+		return json.MarshalIndent(exportDoc{
+			Format:     exportDocFormat,
+			ExportedAt: time.Now().UTC(),
+			Session:    sess,
+			Records:    clean,
+		}, "", "  ")
+	case ExportMarkdown:
+		return exportMarkdown(sess, clean), nil
+	case ExportHAR:
+		return exportHAR(clean)
+	}
+	return nil, fmt.Errorf("unknown export format %q", format)
+}
+
+// ReadSessionExport decodes a canonical JSON export written by ExportSession.
+func ReadSessionExport(data []byte) (*Session, []SessionRecord, error) {
+	var doc exportDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("decode session export: %w", err)
+	}
+	if doc.Format != exportDocFormat || doc.Session == nil {
+		return nil, nil, fmt.Errorf("not a session export (format %q, want %q)", doc.Format, exportDocFormat)
+	}
+	return doc.Session, doc.Records, nil
+}
+
+// exportMarkdown renders the conversation as a transcript followed by per-call timings.
+func exportMarkdown(sess *Session, recs []SessionRecord) []byte {
+	var b bytes.Buffer
+	title := sess.Title
+	if title == "" {
+		title = sess.ContextID
+	}
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	fmt.Fprintf(&b, "- Context ID: `%s`\n", sess.ContextID)
+	if sess.AgentURL != "" {
+		fmt.Fprintf(&b, "- Agent: `%s`\n", sess.AgentURL)
+	}
+	fmt.Fprintf(&b, "- Recorded: %s – %s\n\n", sess.CreatedAt.Format(time.RFC3339), sess.UpdatedAt.Format(time.RFC3339))
+	b.WriteString("## Transcript\n\n")
+
+	var calls []CallInfo
+	for _, rec := range recs {
+		ts := rec.Time.Format("15:04:05.000")
+		switch rec.Kind {
+		case RecordKindMessage:
+			var msg a2apb.Message
+			if protojson.Unmarshal(rec.Data, &msg) == nil {
+				writeMarkdownMessage(&b, ts, &msg)
+			}
+		case RecordKindResponse:
+			var resp a2apb.SendMessageResponse
+			if protojson.Unmarshal(rec.Data, &resp) != nil {
+				continue
+			}
+			if m := resp.GetMsg(); m != nil {
+				writeMarkdownMessage(&b, ts, m)
+			}
+			if t := resp.GetTask(); t != nil {
+				writeMarkdownTask(&b, ts, t)
+			}
+		case RecordKindEvent:
+			var ev a2apb.StreamResponse
+			if protojson.Unmarshal(rec.Data, &ev) == nil {
+				writeMarkdownEvent(&b, ts, &ev)
+			}
+		case RecordKindTask:
+			var task a2apb.Task
+			if protojson.Unmarshal(rec.Data, &task) == nil {
+				writeMarkdownTask(&b, ts, &task)
+			}
+		case RecordKindCall:
+			var info CallInfo
+			if json.Unmarshal(rec.Data, &info) == nil {
+				calls = append(calls, info)
+			}
+		}
+	}
+
+	if len(calls) > 0 {
+		b.WriteString("## Calls\n\n")
+		b.WriteString("| Started | Procedure | Protocol | Code | Duration (ms) | First event (ms) | Events |\n")
+		b.WriteString("| --- | --- | --- | --- | ---: | ---: | ---: |\n")
+		for _, c := range calls {
+			fmt.Fprintf(&b, "| %s | `%s` | %s | %s | %.1f | %.1f | %d |\n",
+				c.StartedAt.Format("15:04:05.000"), c.Procedure, c.Protocol, c.Code, c.DurationMs, c.FirstEventMs, c.Events)
+		}
+		b.WriteString("\n")
+	}
+	return b.Bytes()
+}
+
+func writeMarkdownMessage(b *bytes.Buffer, ts string, msg *a2apb.Message) {
+	who := "Agent"
+	if msg.GetRole() == a2apb.Role_ROLE_USER {
+		who = "User"
+	}
+	fmt.Fprintf(b, "**%s** · %s\n\n%s", who, ts, markdownParts(msg.GetParts()))
+}
+
+func writeMarkdownTask(b *bytes.Buffer, ts string, task *a2apb.Task) {
+	fmt.Fprintf(b, "_%s · task `%s` %s_\n\n", ts, task.GetId(), task.GetStatus().GetState())
+	if m := task.GetStatus().GetUpdate(); m != nil {
+		writeMarkdownMessage(b, ts, m)
+	}
+	for _, a := range task.GetArtifacts() {
+		writeMarkdownArtifact(b, ts, a)
+	}
+}
+
+func writeMarkdownEvent(b *bytes.Buffer, ts string, ev *a2apb.StreamResponse) {
+	switch p := ev.GetPayload().(type) {
+	case *a2apb.StreamResponse_Msg:
+		writeMarkdownMessage(b, ts, p.Msg)
+	case *a2apb.StreamResponse_Task:
+		writeMarkdownTask(b, ts, p.Task)
+	case *a2apb.StreamResponse_StatusUpdate:
+		u := p.StatusUpdate
+		fmt.Fprintf(b, "_%s · task `%s` %s_\n\n", ts, u.GetTaskId(), u.GetStatus().GetState())
+		if m := u.GetStatus().GetUpdate(); m != nil {
+			writeMarkdownMessage(b, ts, m)
+		}
+	case *a2apb.StreamResponse_ArtifactUpdate:
+		writeMarkdownArtifact(b, ts, p.ArtifactUpdate.GetArtifact())
+	}
+}
+
+func writeMarkdownArtifact(b *bytes.Buffer, ts string, a *a2apb.Artifact) {
+	name := a.GetName()
+	if name == "" {
+		name = a.GetArtifactId()
+	}
+	fmt.Fprintf(b, "**Artifact `%s`** · %s\n\n%s", name, ts, markdownParts(a.GetParts()))
+}
+
+// markdownParts renders text parts as paragraphs, files as links and data as JSON blocks.
+func markdownParts(parts []*a2apb.Part) string {
+	var b strings.Builder
+	for _, p := range parts {
+		switch {
+		case p.GetText() != "":
+			b.WriteString(p.GetText() + "\n\n")
+		case p.GetFile() != nil:
+			f := p.GetFile()
+			if uri := f.GetFileWithUri(); uri != "" {
+				fmt.Fprintf(&b, "[%s](%s) (%s)\n\n", f.GetName(), uri, f.GetMimeType())
+			} else {
+				fmt.Fprintf(&b, "File `%s` (%s, %d bytes)\n\n", f.GetName(), f.GetMimeType(), len(f.GetFileWithBytes()))
+			}
+		case p.GetData() != nil:
+			data, _ := protojson.MarshalOptions{Multiline: true}.Marshal(p.GetData().GetData())
+			fmt.Fprintf(&b, "```json\n%s\n```\n\n", redactJSON(data))
+		}
+	}
+	return b.String()
+}
+
+// HAR 1.2 structures; see http://www.softwareishard.com/blog/har-12-spec/.
+type (
+	harLog struct {
+		Log harContent `json:"log"`
+	}
+	harContent struct {
+		Version string     `json:"version"`
+		Creator harCreator `json:"creator"`
+		Entries []harEntry `json:"entries"`
+	}
+	harCreator struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	harEntry struct {
+		StartedDateTime string      `json:"startedDateTime"`
+		Time            float64     `json:"time"`
+		Request         harRequest  `json:"request"`
+		Response        harResponse `json:"response"`
+		Cache           struct{}    `json:"cache"`
+		Timings         harTimings  `json:"timings"`
+		Comment         string      `json:"comment,omitempty"`
+	}
+	harRequest struct {
+		Method      string      `json:"method"`
+		URL         string      `json:"url"`
+		HTTPVersion string      `json:"httpVersion"`
+		Cookies     []struct{}  `json:"cookies"`
+		Headers     []harHeader `json:"headers"`
+		QueryString []struct{}  `json:"queryString"`
+		PostData    harPostData `json:"postData"`
+		HeadersSize int         `json:"headersSize"`
+		BodySize    int         `json:"bodySize"`
+	}
+	harResponse struct {
+		Status      int         `json:"status"`
+		StatusText  string      `json:"statusText"`
+		HTTPVersion string      `json:"httpVersion"`
+		Cookies     []struct{}  `json:"cookies"`
+		Headers     []harHeader `json:"headers"`
+		Content     harBody     `json:"content"`
+		RedirectURL string      `json:"redirectURL"`
+		HeadersSize int         `json:"headersSize"`
+		BodySize    int         `json:"bodySize"`
+	}
+	harHeader struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	harPostData struct {
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+	}
+	harBody struct {
+		Size     int    `json:"size"`
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+	}
+	harTimings struct {
+		Send    float64 `json:"send"`
+		Wait    float64 `json:"wait"`
+		Receive float64 `json:"receive"`
+	}
+)
+
+// exportHAR builds one HAR entry per recorded call. Request bodies and headers come from
+// the call's CallInfo; the response body is the unary response or the call's stream events
+// as newline-delimited JSON.
+func exportHAR(recs []SessionRecord) ([]byte, error) {
+	bodies := map[string][]json.RawMessage{}
+	for _, rec := range recs {
+		if rec.CallID != "" && (rec.Kind == RecordKindResponse || rec.Kind == RecordKindEvent || rec.Kind == RecordKindTask) {
+			bodies[rec.CallID] = append(bodies[rec.CallID], rec.Data)
+		}
+	}
+
+	entries := []harEntry{}
+	for _, rec := range recs {
+		if rec.Kind != RecordKindCall {
+			continue
+		}
+		var info CallInfo
+		if err := json.Unmarshal(rec.Data, &info); err != nil {
+			return nil, fmt.Errorf("decode call record %d: %w", rec.Seq, err)
+		}
+		// A stream that failed before its first event is still a stream.
+		streaming := info.Procedure == a2apbconnect.A2AServiceSendStreamingMessageProcedure ||
+			info.Procedure == a2apbconnect.A2AServiceTaskSubscriptionProcedure
+		var body []byte
+		for i, data := range bodies[rec.CallID] {
+			if i > 0 {
+				body = append(body, '\n')
+			}
+			body = append(body, data...)
+		}
+		mime := "application/json"
+		if streaming {
+			mime = "application/x-ndjson"
+		}
+		if info.Error != "" && len(body) == 0 {
+			body, _ = json.Marshal(map[string]string{"code": info.Code, "message": info.Error})
+		}
+
+		status := harStatus(info.Code, streaming)
+		wait := info.DurationMs
+		if info.FirstEventMs > 0 {
+			wait = info.FirstEventMs
+		}
+		entries = append(entries, harEntry{
+			StartedDateTime: info.StartedAt.Format(time.RFC3339Nano),
+			Time:            info.DurationMs,
+			Request: harRequest{
+				Method:      http.MethodPost,
+				URL:         harURL(info),
+				HTTPVersion: "HTTP/1.1",
+				Cookies:     []struct{}{},
+				Headers:     harHeaders(info.RequestHeaders),
+				QueryString: []struct{}{},
+				PostData:    harPostData{MimeType: "application/json", Text: string(info.Request)},
+				HeadersSize: -1,
+				BodySize:    len(info.Request),
+			},
+			Response: harResponse{
+				Status:      status,
+				StatusText:  http.StatusText(status),
+				HTTPVersion: "HTTP/1.1",
+				Cookies:     []struct{}{},
+				Headers:     harHeaders(info.ResponseHeaders),
+				Content:     harBody{Size: len(body), MimeType: mime, Text: string(body)},
+				HeadersSize: -1,
+				BodySize:    len(body),
+			},
+			Timings: harTimings{Wait: wait, Receive: info.DurationMs - wait},
+			Comment: fmt.Sprintf("%s via %s: %s", info.Procedure, info.Protocol, info.Code),
+		})
+	}
+	return json.MarshalIndent(harLog{Log: harContent{
+		Version: "1.2",
+		Creator: harCreator{Name: "a2a-playground", Version: "1"},
+		Entries: entries,
+	}}, "", "  ")
+}
+
+// harURL rebuilds the call URL from the browser's Origin, falling back to localhost.
+func harURL(info CallInfo) string {
+	origin := "http://localhost"
+	for k, vs := range info.RequestHeaders {
+		if strings.EqualFold(k, "Origin") && len(vs) > 0 && vs[0] != "" {
+			origin = vs[0]
+		}
+	}
+	return origin + info.Procedure
+}
+
+// harStatus maps a Connect code to the HTTP status a unary Connect call would return.
+// Streams always answer 200 and report errors in the end-of-stream message.
+func harStatus(code string, streaming bool) int {
+	if streaming || code == "ok" {
+		return http.StatusOK
+	}
+	var c connect.Code
+	if err := c.UnmarshalText([]byte(code)); err != nil {
+		return http.StatusInternalServerError
+	}
+	switch c {
+	case connect.CodeCanceled:
+		return 499
+	case connect.CodeInvalidArgument, connect.CodeFailedPrecondition, connect.CodeOutOfRange:
+		return http.StatusBadRequest
+	case connect.CodeDeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case connect.CodeNotFound:
+		return http.StatusNotFound
+	case connect.CodeAlreadyExists, connect.CodeAborted:
+		return http.StatusConflict
+	case connect.CodePermissionDenied:
+		return http.StatusForbidden
+	case connect.CodeResourceExhausted:
+		return http.StatusTooManyRequests
+	case connect.CodeUnimplemented:
+		return http.StatusNotImplemented
+	case connect.CodeUnavailable:
+		return http.StatusServiceUnavailable
+	case connect.CodeUnauthenticated:
+		return http.StatusUnauthorized
+	}
+	return http.StatusInternalServerError
+}
+
+// harHeaders flattens h into sorted HAR name/value pairs.
+func harHeaders(h map[string][]string) []harHeader {
+	out := []harHeader{}
+	for k, vs := range h {
+		for _, v := range vs {
+			out = append(out, harHeader{Name: k, Value: v})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
@@ -0,0 +1,286 @@
+package bff
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/a2aproject/a2a-go/a2apb"
+	"github.com/alis-exchange/a2a-playground/gen/go/a2apbconnect"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// extensionsHeader activates A2A extensions for a call, as a comma-separated list of
+// extension URIs. The agent answers with the ones it activated under the same name.
+const extensionsHeader = "X-A2A-Extensions"
+
+// extensionMetadataHeader lists the metadata keys of the response that belong to an
+// extension, as Type=key, e.g. "Message=https://example.com/ext/citations".
+const extensionMetadataHeader = "X-A2A-Extension-Metadata"
+
+// cardExtensionsTTL is how long the agent card's extensions are cached for.
+const cardExtensionsTTL = time.Minute
+
+// extensionsKey is the context key for the extensions a call activates.
+type extensionsKey struct{}
+
+// withExtensions returns ctx with the extensions the proxies ask the agent to activate.
+func withExtensions(ctx context.Context, uris []string) context.Context {
+	return context.WithValue(ctx, extensionsKey{}, uris)
+}
+
+// extensionsFromContext returns the extensions to activate, or nil.
+func extensionsFromContext(ctx context.Context) []string {
+	uris, _ := ctx.Value(extensionsKey{}).([]string)
+	return uris
+}
+
+// parseExtensions splits header values into extension URIs, without duplicates.
+func parseExtensions(values ...string) []string {
+	var uris []string
+	for _, v := range values {
+		for _, uri := range strings.Split(v, ",") {
+			if uri = strings.TrimSpace(uri); uri != "" && !slices.Contains(uris, uri) {
+				uris = append(uris, uri)
+			}
+		}
+	}
+	return uris
+}
+
+// messageProcedures are the calls that fail when a required extension is not activated.
+var messageProcedures = []string{
+	a2apbconnect.A2AServiceSendMessageProcedure,
+	a2apbconnect.A2AServiceSendStreamingMessageProcedure,
+}
+
+// extensions activates the A2A extensions a call asks for, checks them against the
+// agent card, and reports which ones the agent applied.
+type extensions struct {
+	cards    agentCardSource
+	defaults []string
+
+	mu    sync.Mutex
+	cache map[string]cardExtensions
+}
+
+// cardExtensions are the extensions on the agent card fetched with some agent headers.
+type cardExtensions struct {
+	fetched time.Time
+	list    []*a2apb.AgentExtension
+	err     error
+}
+
+// NewExtensionsInterceptor returns the interceptor that activates A2A extensions. A call
+// activates the extensions in its X-A2A-Extensions header, in X-A2A-Extensions among its
+// agent headers, and in defaults. They must be on the card of the agent behind proxy, and
+// messages must activate those the card marks as required. The extensions the agent
+// activated come back in X-A2A-Extensions, and metadata keys named after extensions in
+// X-A2A-Extension-Metadata; on streams, both are trailers. It belongs inside the response
+// metadata interceptor, whose record of the agent's response it reads.
+func NewExtensionsInterceptor(proxy A2AServiceHandler, defaults []string) connect.Interceptor {
+	cards, _ := proxy.(agentCardSource)
+	return &extensions{cards: cards, defaults: parseExtensions(defaults...), cache: map[string]cardExtensions{}}
+}
+
+func (e *extensions) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		ctx, known, err := e.activate(ctx, req.Spec().Procedure, req.Header())
+		if err != nil {
+			return nil, err
+		}
+		resp, err := next(ctx, req)
+		var cerr *connect.Error
+		switch {
+		case err == nil:
+			e.report(ctx, resp.Header(), metadataKeys(resp.Any(), known))
+		case errors.As(err, &cerr):
+			e.report(ctx, cerr.Meta(), nil)
+		}
+		return resp, err
+	}
+}
+
+func (e *extensions) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (e *extensions) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		ctx, known, err := e.activate(ctx, conn.Spec().Procedure, conn.RequestHeader())
+		if err != nil {
+			return err
+		}
+		mc := &extensionMetadataConn{StreamingHandlerConn: conn, known: known}
+		err = next(ctx, mc)
+		e.report(ctx, conn.ResponseTrailer(), mc.keys)
+		return err
+	}
+}
+
+// activate returns ctx with the extensions the call activates, and the URIs of the known
+// extensions: those on the card and those activated. The agent headers lose their
+// X-A2A-Extensions, which the proxies send themselves.
+func (e *extensions) activate(ctx context.Context, procedure string, header http.Header) (context.Context, []string, error) {
+	values := header.Values(extensionsHeader)
+	headers := AgentHeadersFromContext(ctx)
+	var rest map[string]string
+	for k, v := range headers {
+		if strings.EqualFold(k, extensionsHeader) {
+			values = append(values, v)
+			continue
+		}
+		if rest == nil {
+			rest = make(map[string]string, len(headers))
+		}
+		rest[k] = v
+	}
+	if len(rest) < len(headers) {
+		ctx = context.WithValue(ctx, AgentHeadersKey{}, rest)
+	}
+	uris := parseExtensions(append(values, e.defaults...)...)
+	message := slices.Contains(messageProcedures, procedure)
+	if e.cards == nil || (len(uris) == 0 && !message) {
+		return withExtensions(ctx, uris), uris, nil
+	}
+	list, err := e.cardExtensions(ctx)
+	if err != nil {
+		// Without a card there is nothing to check against; the agent decides.
+		return withExtensions(ctx, uris), uris, nil
+	}
+	known := slices.Clone(uris)
+	for _, ext := range list {
+		if !slices.Contains(known, ext.GetUri()) {
+			known = append(known, ext.GetUri())
+		}
+	}
+	for _, uri := range uris {
+		if !slices.ContainsFunc(list, func(ext *a2apb.AgentExtension) bool { return ext.GetUri() == uri }) {
+			return ctx, nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("extension %s is not on the agent card", uri))
+		}
+	}
+	if message {
+		for _, ext := range list {
+			if ext.GetRequired() && !slices.Contains(uris, ext.GetUri()) {
+				return ctx, nil, connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("the agent requires extension %s: activate it with %s", ext.GetUri(), extensionsHeader))
+			}
+		}
+	}
+	return withExtensions(ctx, uris), known, nil
+}
+
+// cardExtensions returns the extensions on the agent's card, as fetched with the agent
+// headers. Cards are cached for a while, per set of agent headers, as credentials may
+// show an extended card with more extensions.
+func (e *extensions) cardExtensions(ctx context.Context) ([]*a2apb.AgentExtension, error) {
+	b, _ := json.Marshal(AgentHeadersFromContext(ctx))
+	key := string(b)
+	e.mu.Lock()
+	c, ok := e.cache[key]
+	e.mu.Unlock()
+	if ok && time.Since(c.fetched) < cardExtensionsTTL {
+		return c.list, c.err
+	}
+	c = cardExtensions{fetched: time.Now()}
+	// The card is not the call's response, whose metadata the recorder is for.
+	cards, err := e.cards.agentCards(context.WithValue(ctx, agentResponseKey{}, (*agentResponse)(nil)))
+	if err != nil {
+		c.err = err
+	} else {
+		c.list = cards.card().GetCapabilities().GetExtensions()
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for k, old := range e.cache {
+		if time.Since(old.fetched) >= cardExtensionsTTL {
+			delete(e.cache, k)
+		}
+	}
+	e.cache[key] = c
+	return c.list, c.err
+}
+
+// report adds the extensions the agent activated, and the extension metadata keys, to dst.
+func (e *extensions) report(ctx context.Context, dst http.Header, keys []string) {
+	if rec := agentResponseFromContext(ctx); rec != nil {
+		header, trailer, _ := rec.snapshot()
+		for _, uri := range parseExtensions(append(header.Values(extensionsHeader), trailer.Values(extensionsHeader)...)...) {
+			dst.Add(extensionsHeader, uri)
+		}
+	}
+	for _, k := range keys {
+		dst.Add(extensionMetadataHeader, k)
+	}
+}
+
+// extensionMetadataConn collects the extension metadata keys of the events it sends.
+type extensionMetadataConn struct {
+	connect.StreamingHandlerConn
+	known []string
+	keys  []string
+}
+
+func (c *extensionMetadataConn) Send(msg any) error {
+	for _, k := range metadataKeys(msg, c.known) {
+		if !slices.Contains(c.keys, k) {
+			c.keys = append(c.keys, k)
+		}
+	}
+	return c.StreamingHandlerConn.Send(msg)
+}
+
+// metadataKeys returns the metadata keys in msg named after one of the extension URIs in
+// known, or under one, as Type=key with the type of message holding the metadata.
+func metadataKeys(msg any, known []string) []string {
+	m, ok := msg.(proto.Message)
+	if !ok || len(known) == 0 {
+		return nil
+	}
+	var keys []string
+	var walk func(m protoreflect.Message)
+	walk = func(m protoreflect.Message) {
+		m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+			switch {
+			case fd.Name() == "metadata" && fd.Message() != nil && fd.Message().FullName() == "google.protobuf.Struct":
+				s, _ := v.Message().Interface().(*structpb.Struct)
+				for name := range s.GetFields() {
+					if !isExtensionKey(name, known) {
+						continue
+					}
+					if k := string(m.Descriptor().Name()) + "=" + name; !slices.Contains(keys, k) {
+						keys = append(keys, k)
+					}
+				}
+			case fd.IsList() && fd.Message() != nil:
+				for i := 0; i < v.List().Len(); i++ {
+					walk(v.List().Get(i).Message())
+				}
+			case fd.Message() != nil && !fd.IsMap():
+				walk(v.Message())
+			}
+			return true
+		})
+	}
+	walk(m.ProtoReflect())
+	slices.Sort(keys)
+	return keys
+}
+
+// isExtensionKey reports whether the metadata key name is one of the URIs or under one.
+func isExtensionKey(name string, uris []string) bool {
+	for _, uri := range uris {
+		if name == uri || strings.HasPrefix(name, strings.TrimSuffix(uri, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
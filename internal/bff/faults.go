@@ -0,0 +1,340 @@
+package bff
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"path"
+	"slices"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+)
+
+// faultHeader is set on injected errors so they can be told apart from real ones.
+const faultHeader = "X-A2A-Fault"
+
+// FaultConfig describes the faults injected into A2A calls. The zero value injects none.
+// Rates are percentages of calls (ErrorRate) or of stream events (DuplicateRate,
+// ReorderRate).
+type FaultConfig struct {
+	// Latency is added before each call reaches the agent, plus up to Jitter at random.
+	Latency time.Duration `json:"-"`
+	Jitter  time.Duration `json:"-"`
+	// ErrorRate fails calls with one of ErrorCodes (unavailable when empty).
+	ErrorRate  float64        `json:"errorRate,omitempty"`
+	ErrorCodes []connect.Code `json:"errorCodes,omitempty"`
+	// TruncateAfter ends streams after this many events, without their final event.
+	TruncateAfter int `json:"truncateAfter,omitempty"`
+	// DuplicateRate sends events twice; ReorderRate swaps events with the next one.
+	DuplicateRate float64 `json:"duplicateRate,omitempty"`
+	ReorderRate   float64 `json:"reorderRate,omitempty"`
+	// BytesPerSecond limits how fast responses are written to the browser.
+	BytesPerSecond int `json:"bytesPerSecond,omitempty"`
+	// Procedures limits faults to these RPCs, e.g. "SendStreamingMessage". Empty means all.
+	Procedures []string `json:"procedures,omitempty"`
+}
+
+type faultConfigAlias FaultConfig
+
+// faultConfigJSON is FaultConfig with durations written as strings such as "250ms".
+type faultConfigJSON struct {
+	Latency string `json:"latency,omitempty"`
+	Jitter  string `json:"jitter,omitempty"`
+	*faultConfigAlias
+}
+
+func (c FaultConfig) MarshalJSON() ([]byte, error) {
+	out := faultConfigJSON{faultConfigAlias: (*faultConfigAlias)(&c)}
+	if c.Latency != 0 {
+		out.Latency = c.Latency.String()
+	}
+	if c.Jitter != 0 {
+		out.Jitter = c.Jitter.String()
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON rejects unknown fields, so a misspelt fault is an error rather than
+// silently not injected.
+func (c *FaultConfig) UnmarshalJSON(data []byte) error {
+	in := faultConfigJSON{faultConfigAlias: (*faultConfigAlias)(c)}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&in); err != nil {
+		return err
+	}
+	for _, d := range []struct {
+		s   string
+		out *time.Duration
+	}{{in.Latency, &c.Latency}, {in.Jitter, &c.Jitter}} {
+		if d.s == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.s)
+		if err != nil {
+			return err
+		}
+		*d.out = v
+	}
+	return nil
+}
+
+// Validate reports out-of-range settings.
+func (c FaultConfig) Validate() error {
+	if c.Latency < 0 || c.Jitter < 0 {
+		return errors.New("latency and jitter must not be negative")
+	}
+	for _, r := range []struct {
+		name string
+		v    float64
+	}{{"errorRate", c.ErrorRate}, {"duplicateRate", c.DuplicateRate}, {"reorderRate", c.ReorderRate}} {
+		if r.v < 0 || r.v > 100 {
+			return fmt.Errorf("%s must be a percentage between 0 and 100, got %v", r.name, r.v)
+		}
+	}
+	if c.TruncateAfter < 0 || c.BytesPerSecond < 0 {
+		return errors.New("truncateAfter and bytesPerSecond must not be negative")
+	}
+	for _, code := range c.ErrorCodes {
+		if code == 0 {
+			return errors.New("errorCodes: ok is not an error")
+		}
+	}
+	return nil
+}
+
+// applies reports whether faults apply to the procedure, e.g. "/a2a.v1.A2AService/GetTask".
+func (c FaultConfig) applies(procedure string) bool {
+	return len(c.Procedures) == 0 || slices.Contains(c.Procedures, path.Base(procedure))
+}
+
+// FaultInjector injects faults into A2A calls. Its configuration can be changed while the
+// server runs.
+type FaultInjector struct {
+	mu  sync.RWMutex
+	cfg FaultConfig
+}
+
+// NewFaultInjector returns an injector with the given initial configuration.
+func NewFaultInjector(cfg FaultConfig) (*FaultInjector, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &FaultInjector{cfg: cfg}, nil
+}
+
+// Config returns the current configuration.
+func (f *FaultInjector) Config() FaultConfig {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.cfg
+}
+
+// SetConfig replaces the configuration; calls already in progress keep the old one.
+func (f *FaultInjector) SetConfig(cfg FaultConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cfg = cfg
+	return nil
+}
+
+// Interceptor returns the Connect interceptor that injects latency, errors and stream
+// faults. It should be the innermost interceptor, so everything else in the BFF sees the
+// faults as if the agent produced them.
+func (f *FaultInjector) Interceptor() connect.Interceptor {
+	return faultInterceptor{f}
+}
+
+// Middleware limits response bandwidth for A2A calls when BytesPerSecond is set.
+func (f *FaultInjector) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := f.Config()
+		if cfg.BytesPerSecond <= 0 || !cfg.applies(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(&throttledWriter{ResponseWriter: w, ctx: r.Context(), rate: cfg.BytesPerSecond}, r)
+	})
+}
+
+// delay waits for the configured latency and jitter.
+func (c FaultConfig) delay(ctx context.Context) error {
+	d := c.Latency
+	if c.Jitter > 0 {
+		d += rand.N(c.Jitter)
+	}
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// injectedError returns an error for ErrorRate percent of calls, or nil.
+func (c FaultConfig) injectedError() error {
+	if !chance(c.ErrorRate) {
+		return nil
+	}
+	code := connect.CodeUnavailable
+	if len(c.ErrorCodes) > 0 {
+		code = c.ErrorCodes[rand.IntN(len(c.ErrorCodes))]
+	}
+	err := connect.NewError(code, errors.New("injected fault"))
+	err.Meta().Set(faultHeader, "error")
+	return err
+}
+
+// chance returns true with the given percentage probability.
+func chance(percent float64) bool {
+	return percent > 0 && rand.Float64()*100 < percent
+}
+
+type faultInterceptor struct {
+	faults *FaultInjector
+}
+
+func (i faultInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		cfg := i.faults.Config()
+		if !cfg.applies(req.Spec().Procedure) {
+			return next(ctx, req)
+		}
+		if err := cfg.delay(ctx); err != nil {
+			return nil, err
+		}
+		if err := cfg.injectedError(); err != nil {
+			return nil, err
+		}
+		return next(ctx, req)
+	}
+}
+
+func (i faultInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (i faultInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		cfg := i.faults.Config()
+		if !cfg.applies(conn.Spec().Procedure) {
+			return next(ctx, conn)
+		}
+		if err := cfg.delay(ctx); err != nil {
+			return err
+		}
+		if err := cfg.injectedError(); err != nil {
+			return err
+		}
+		fc := &faultConn{StreamingHandlerConn: conn, cfg: cfg}
+		err := next(ctx, fc)
+		if errors.Is(err, errTruncated) {
+			return nil
+		}
+		if fc.held != nil {
+			if sendErr := fc.StreamingHandlerConn.Send(fc.held); sendErr != nil && err == nil {
+				err = sendErr
+			}
+		}
+		return err
+	}
+}
+
+// errTruncated stops the upstream stream once TruncateAfter events were sent.
+var errTruncated = errors.New("stream truncated by fault injection")
+
+// faultConn duplicates, reorders and truncates the events sent on a stream.
+type faultConn struct {
+	connect.StreamingHandlerConn
+	cfg  FaultConfig
+	sent int
+	held any // an event held back to be sent after the next one
+}
+
+func (c *faultConn) Send(msg any) error {
+	if c.held == nil && chance(c.cfg.ReorderRate) {
+		c.held = msg
+		return nil
+	}
+	if err := c.send(msg); err != nil {
+		return err
+	}
+	if held := c.held; held != nil {
+		c.held = nil
+		return c.send(held)
+	}
+	return nil
+}
+
+// send writes msg, possibly twice, counting it towards TruncateAfter.
+func (c *faultConn) send(msg any) error {
+	n := 1
+	if chance(c.cfg.DuplicateRate) {
+		n = 2
+	}
+	for range n {
+		if c.cfg.TruncateAfter > 0 && c.sent >= c.cfg.TruncateAfter {
+			c.held = nil
+			return errTruncated
+		}
+		if err := c.StreamingHandlerConn.Send(msg); err != nil {
+			return err
+		}
+		c.sent++
+	}
+	return nil
+}
+
+// throttledWriter writes at most rate bytes per second, flushing as it goes so the
+// browser sees a slow drip rather than a delayed burst.
+type throttledWriter struct {
+	http.ResponseWriter
+	ctx  context.Context
+	rate int
+}
+
+func (w *throttledWriter) Write(p []byte) (int, error) {
+	chunk := max(w.rate/10, 1)
+	written := 0
+	for written < len(p) {
+		n := min(chunk, len(p)-written)
+		m, err := w.ResponseWriter.Write(p[written : written+n])
+		written += m
+		if err != nil {
+			return written, err
+		}
+		w.Flush()
+		t := time.NewTimer(time.Duration(n) * time.Second / time.Duration(w.rate))
+		select {
+		case <-t.C:
+		case <-w.ctx.Done():
+			t.Stop()
+			return written, w.ctx.Err()
+		}
+	}
+	return written, nil
+}
+
+func (w *throttledWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *throttledWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
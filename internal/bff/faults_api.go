@@ -0,0 +1,40 @@
+package bff
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// faultsAPIPath is the admin endpoint for fault injection.
+const faultsAPIPath = "/api/admin/faults"
+
+// registerFaultRoutes mounts the fault injection API on r:
+//
+//	GET    /api/admin/faults        the current FaultConfig
+//	PUT    /api/admin/faults        replace it, e.g. {"latency": "500ms", "errorRate": 10}
+//	DELETE /api/admin/faults        stop injecting faults
+func registerFaultRoutes(r *mux.Router, faults *FaultInjector) {
+	r.HandleFunc(faultsAPIPath, func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, faults.Config())
+	}).Methods(http.MethodGet)
+
+	r.HandleFunc(faultsAPIPath, func(w http.ResponseWriter, req *http.Request) {
+		var cfg FaultConfig
+		if err := json.NewDecoder(req.Body).Decode(&cfg); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid fault config: " + err.Error()})
+			return
+		}
+		if err := faults.SetConfig(cfg); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, faults.Config())
+	}).Methods(http.MethodPut)
+
+	r.HandleFunc(faultsAPIPath, func(w http.ResponseWriter, req *http.Request) {
+		_ = faults.SetConfig(FaultConfig{})
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods(http.MethodDelete)
+}
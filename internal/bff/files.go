@@ -0,0 +1,317 @@
+package bff
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/a2aproject/a2a-go/a2apb"
+	"github.com/alis-exchange/a2a-playground/gen/go/a2apbconnect"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+)
+
+// FileRefScheme prefixes the URI of an uploaded file in a FilePart, as in
+// "playground-file:<id>". The BFF replaces such parts before they reach the agent.
+const FileRefScheme = "playground-file"
+
+// maxUploadSize bounds a single upload request.
+const maxUploadSize = 1 << 30
+
+// maxInlineSize bounds the uploaded files inlined into one message in FilePartsBytes mode.
+// Base64 grows them by a third, and the message must stay under the 4 MB gRPC agents accept
+// by default; files beyond the bound are sent by URI instead.
+const maxInlineSize = 2 << 20
+
+// FilePartMode is how uploaded files referenced by a message are sent to the agent.
+type FilePartMode string
+
+const (
+	// FilePartsBytes inlines the file into file_with_bytes, up to maxInlineSize per
+	// message, and sends larger files as FilePartsURI does.
+	FilePartsBytes FilePartMode = "bytes"
+	// FilePartsURI points file_with_uri at the file served by the BFF.
+	FilePartsURI FilePartMode = "uri"
+)
+
+// ParseFilePartMode parses a --file-parts value.
+func ParseFilePartMode(s string) (FilePartMode, error) {
+	switch m := FilePartMode(strings.ToLower(s)); m {
+	case FilePartsBytes, FilePartsURI:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown file part mode %q (want bytes or uri)", s)
+}
+
+// StoredFile describes an uploaded file.
+type StoredFile struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	MimeType  string    `json:"mimeType"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"createdAt"`
+	// URI is the reference to put in file_with_uri.
+	URI string `json:"uri"`
+}
+
+// FileStore keeps uploaded files in a directory, each as <id> with metadata in <id>.json.
+type FileStore struct {
+	dir string
+}
+
+// OpenFileStore returns the store in dir, creating the directory if needed.
+func OpenFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Save stores the contents of r. An empty or generic mimeType is replaced by one guessed
+// from the name or the content.
+func (s *FileStore) Save(name, mimeType string, r io.Reader) (*StoredFile, error) {
+	tmp, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	br := bufio.NewReader(r)
+	if mimeType == "" || mimeType == "application/octet-stream" {
+		mimeType = detectMimeType(name, br)
+	}
+	size, err := io.Copy(tmp, br)
+	if err != nil {
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+
+	f := &StoredFile{
+		ID:        uuid.NewString(),
+		Name:      filepath.Base(name),
+		MimeType:  mimeType,
+		Size:      size,
+		CreatedAt: time.Now().UTC(),
+	}
+	f.URI = FileRefScheme + ":" + f.ID
+	meta, err := json.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(s.path(f.ID)+".json", meta, 0o600); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), s.path(f.ID)); err != nil {
+		_ = os.Remove(s.path(f.ID) + ".json")
+		return nil, err
+	}
+	return f, nil
+}
+
+// Stat returns the metadata of the file with the given ID.
+func (s *FileStore) Stat(id string) (*StoredFile, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrFileNotFound
+	}
+	data, err := os.ReadFile(s.path(id) + ".json")
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrFileNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	f := &StoredFile{}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Open returns the metadata and contents of a file. The caller closes the file.
+func (s *FileStore) Open(id string) (*StoredFile, *os.File, error) {
+	f, err := s.Stat(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := os.Open(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, ErrFileNotFound
+	}
+	return f, data, err
+}
+
+// Delete removes a file.
+func (s *FileStore) Delete(id string) error {
+	if _, err := s.Stat(id); err != nil {
+		return err
+	}
+	return errors.Join(os.Remove(s.path(id)), os.Remove(s.path(id)+".json"))
+}
+
+func (s *FileStore) path(id string) string {
+	return filepath.Join(s.dir, id)
+}
+
+// ErrFileNotFound is returned for unknown file IDs.
+var ErrFileNotFound = errors.New("file not found")
+
+// fileRefID returns the ID in a playground-file URI, or "" if uri is not one.
+func fileRefID(uri string) string {
+	id, ok := strings.CutPrefix(uri, FileRefScheme+":")
+	if !ok {
+		return ""
+	}
+	return id
+}
+
+// detectMimeType guesses a MIME type from the file extension, then from the content.
+func detectMimeType(name string, r *bufio.Reader) string {
+	if t := mime.TypeByExtension(filepath.Ext(name)); t != "" {
+		return t
+	}
+	head, _ := r.Peek(512)
+	return http.DetectContentType(head)
+}
+
+// fileRefInterceptor replaces playground-file parts in SendMessage and
+// SendStreamingMessage requests with the stored file.
+type fileRefInterceptor struct {
+	files     *FileStore
+	mode      FilePartMode
+	publicURL func() string
+}
+
+// NewFileRefInterceptor returns the interceptor that resolves uploaded-file references.
+// In FilePartsURI mode parts point at publicURL/api/files/<id>, so the agent must be able
+// to reach the BFF at publicURL. publicURL is called for each message, as the BFF's
+// address may only be known once it listens. The interceptor belongs inside the session
+// recorder, which then records the short reference instead of the file.
+func NewFileRefInterceptor(files *FileStore, mode FilePartMode, publicURL func() string) connect.Interceptor {
+	return fileRefInterceptor{files: files, mode: mode, publicURL: publicURL}
+}
+
+func (i fileRefInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		in, ok := req.Any().(*a2apb.SendMessageRequest)
+		if !ok || req.Spec().Procedure != a2apbconnect.A2AServiceSendMessageProcedure {
+			return next(ctx, req)
+		}
+		out, err := i.resolve(in)
+		if err != nil {
+			return nil, err
+		}
+		if out == in {
+			return next(ctx, req)
+		}
+		// A new request, so the caller's message (and what the recorder holds) is unchanged.
+		resolved := connect.NewRequest(out)
+		for k, v := range req.Header() {
+			resolved.Header()[k] = v
+		}
+		return next(ctx, resolved)
+	}
+}
+
+func (i fileRefInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (i fileRefInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		if conn.Spec().Procedure != a2apbconnect.A2AServiceSendStreamingMessageProcedure {
+			return next(ctx, conn)
+		}
+		return next(ctx, &fileRefConn{StreamingHandlerConn: conn, interceptor: i})
+	}
+}
+
+// fileRefConn resolves file references in the request it receives.
+type fileRefConn struct {
+	connect.StreamingHandlerConn
+	interceptor fileRefInterceptor
+}
+
+func (c *fileRefConn) Receive(msg any) error {
+	out, ok := msg.(*a2apb.SendMessageRequest)
+	if !ok {
+		return c.StreamingHandlerConn.Receive(msg)
+	}
+	in := &a2apb.SendMessageRequest{}
+	if err := c.StreamingHandlerConn.Receive(in); err != nil {
+		return err
+	}
+	resolved, err := c.interceptor.resolve(in)
+	if err != nil {
+		return err
+	}
+	proto.Reset(out)
+	proto.Merge(out, resolved)
+	return nil
+}
+
+// resolve returns req with every playground-file part replaced, or req itself when it
+// references no uploaded files.
+func (i fileRefInterceptor) resolve(req *a2apb.SendMessageRequest) (*a2apb.SendMessageRequest, error) {
+	var out *a2apb.SendMessageRequest
+	var inlined int64 // bytes inlined so far
+	for n, part := range req.GetRequest().GetParts() {
+		id := fileRefID(part.GetFile().GetFileWithUri())
+		if id == "" {
+			continue
+		}
+		f, err := i.files.Stat(id)
+		if errors.Is(err, ErrFileNotFound) {
+			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("part %d: uploaded file %s not found", n, id))
+		}
+		if err != nil {
+			return nil, connect.NewError(connect.CodeInternal, err)
+		}
+		fp := &a2apb.FilePart{Name: part.GetFile().GetName(), MimeType: part.GetFile().GetMimeType()}
+		if fp.Name == "" {
+			fp.Name = f.Name
+		}
+		if fp.MimeType == "" {
+			fp.MimeType = f.MimeType
+		}
+		if i.mode == FilePartsBytes && inlined+f.Size <= maxInlineSize {
+			data, err := os.ReadFile(i.files.path(f.ID))
+			if err != nil {
+				return nil, connect.NewError(connect.CodeInternal, err)
+			}
+			inlined += f.Size
+			// file_with_bytes carries base64 text, as the JSON binding does.
+			fp.File = &a2apb.FilePart_FileWithBytes{FileWithBytes: []byte(base64.StdEncoding.EncodeToString(data))}
+		} else {
+			base := strings.TrimSuffix(i.publicURL(), "/")
+			if base == "" {
+				if i.mode == FilePartsBytes {
+					return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("part %d: uploaded file %s is too large to inline (over %d MiB per message), and sending it by URI needs --public-url when the BFF listens on a unix socket", n, id, maxInlineSize>>20))
+				}
+				return nil, connect.NewError(connect.CodeFailedPrecondition, errors.New("sending files by URI needs --public-url when the BFF listens on a unix socket"))
+			}
+			fp.File = &a2apb.FilePart_FileWithUri{FileWithUri: base + filesAPIPath + "/" + f.ID}
+		}
+		if out == nil {
+			out = proto.Clone(req).(*a2apb.SendMessageRequest)
+		}
+		out.Request.Parts[n] = &a2apb.Part{Part: &a2apb.Part_File{File: fp}, Metadata: part.GetMetadata()}
+	}
+	if out == nil {
+		return req, nil
+	}
+	return out, nil
+}
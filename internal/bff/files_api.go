@@ -0,0 +1,303 @@
+package bff
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/a2aproject/a2a-go/a2apb"
+	"github.com/alis-exchange/a2a-playground/gen/go/a2apbconnect"
+	"github.com/gorilla/mux"
+)
+
+// filesAPIPath is the prefix of the file API.
+const filesAPIPath = "/api/files"
+
+// fileRoutes serves uploaded files and files in task artifacts.
+type fileRoutes struct {
+	files     *FileStore
+	agent     a2apbconnect.A2AServiceHandler
+	client    *http.Client
+	publicURL func() string
+	// base64Bytes is set when file_with_bytes from agent holds base64 text.
+	base64Bytes bool
+}
+
+// registerFileRoutes mounts the file API on r:
+//
+//	POST   /api/files               multipart upload; every file field is stored
+//	GET    /api/files/{id}          an uploaded file (?download=1 for an attachment)
+//	DELETE /api/files/{id}          delete an uploaded file
+//	GET    /api/tasks/{taskId}/artifacts/{artifactId}/parts/{index}
+//	                                the file in an artifact part, from its bytes or its URI
+//
+// Upload routes are only mounted when files is non-nil. Artifact parts are read with
+// GetTask through agent, using the caller's X-A2A-Agent-Headers; the type of agent decides
+// whether file bytes are base64 text. URIs are fetched with client, which should be a safe
+// client from newSafeHTTPClient. publicURL returns the BFF's public URL, under which file
+// URIs are served locally.
+func registerFileRoutes(r *mux.Router, files *FileStore, agent a2apbconnect.A2AServiceHandler, client *http.Client, publicURL func() string) {
+	fr := &fileRoutes{files: files, agent: agent, client: client, publicURL: publicURL}
+	// The JSON-RPC proxy converts with pbconv, which leaves the JSON binding's base64 text
+	// in file_with_bytes; gRPC agents send the bytes themselves.
+	_, fr.base64Bytes = agent.(*jsonrpcProxy)
+	if files != nil {
+		r.HandleFunc(filesAPIPath, fr.upload).Methods(http.MethodPost)
+		r.HandleFunc(filesAPIPath+"/{id}", fr.get).Methods(http.MethodGet, http.MethodHead)
+		r.HandleFunc(filesAPIPath+"/{id}", fr.delete).Methods(http.MethodDelete)
+	}
+	r.Handle("/api/tasks/{taskId}/artifacts/{artifactId}/parts/{index}", AgentHeadersMiddleware(http.HandlerFunc(fr.artifactPart))).Methods(http.MethodGet)
+}
+
+// upload stores every file in a multipart/form-data body without buffering it in memory.
+func (fr *fileRoutes) upload(w http.ResponseWriter, req *http.Request) {
+	req.Body = http.MaxBytesReader(w, req.Body, maxUploadSize)
+	mr, err := req.MultipartReader()
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "body must be multipart/form-data: " + err.Error()})
+		return
+	}
+	stored := []*StoredFile{}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			writeUploadError(w, err)
+			return
+		}
+		if part.FileName() == "" {
+			continue
+		}
+		f, err := fr.files.Save(part.FileName(), part.Header.Get("Content-Type"), part)
+		if err != nil {
+			writeUploadError(w, err)
+			return
+		}
+		stored = append(stored, f)
+	}
+	if len(stored) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "no files in upload"})
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{"files": stored})
+}
+
+// writeUploadError reports an oversized body as 413 and anything else as a bad upload.
+func writeUploadError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": fmt.Sprintf("upload exceeds %d bytes", tooLarge.Limit)})
+		return
+	}
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+}
+
+func (fr *fileRoutes) get(w http.ResponseWriter, req *http.Request) {
+	fr.serveStored(w, req, mux.Vars(req)["id"], "", "")
+}
+
+func (fr *fileRoutes) delete(w http.ResponseWriter, req *http.Request) {
+	if err := fr.files.Delete(mux.Vars(req)["id"]); err != nil {
+		writeJSONError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveStored serves an uploaded file, with name and mimeType overriding the stored ones
+// when set. Range requests are supported.
+func (fr *fileRoutes) serveStored(w http.ResponseWriter, req *http.Request, id, name, mimeType string) {
+	if fr.files == nil {
+		writeJSONError(w, ErrFileNotFound)
+		return
+	}
+	f, data, err := fr.files.Open(id)
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+	defer data.Close()
+	if name == "" {
+		name = f.Name
+	}
+	if mimeType == "" {
+		mimeType = f.MimeType
+	}
+	setFileHeaders(w, req, name, mimeType)
+	http.ServeContent(w, req, name, f.CreatedAt, data)
+}
+
+// artifactPart serves the file in one part of a task artifact.
+func (fr *fileRoutes) artifactPart(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	index, err := strconv.Atoi(vars["index"])
+	if err != nil || index < 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid part index " + strconv.Quote(vars["index"])})
+		return
+	}
+	name := vars["taskId"]
+	if !strings.HasPrefix(name, "tasks/") {
+		name = "tasks/" + name
+	}
+	task, err := fr.agent.GetTask(req.Context(), connect.NewRequest(&a2apb.GetTaskRequest{Name: name}))
+	if err != nil {
+		writeJSONError(w, err)
+		return
+	}
+	var artifact *a2apb.Artifact
+	for _, a := range task.Msg.GetArtifacts() {
+		if a.GetArtifactId() == vars["artifactId"] {
+			artifact = a
+			break
+		}
+	}
+	if artifact == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "artifact " + vars["artifactId"] + " not found"})
+		return
+	}
+	if index >= len(artifact.GetParts()) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("artifact has %d parts", len(artifact.GetParts()))})
+		return
+	}
+	fp := artifact.GetParts()[index].GetFile()
+	if fp == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("part %d is not a file", index)})
+		return
+	}
+
+	if fp.GetFile() == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("part %d has neither bytes nor a URI", index)})
+		return
+	}
+	if _, ok := fp.GetFile().(*a2apb.FilePart_FileWithBytes); ok {
+		data := fp.GetFileWithBytes()
+		if fr.base64Bytes {
+			if data, err = base64.StdEncoding.DecodeString(string(data)); err != nil {
+				writeJSON(w, http.StatusBadGateway, map[string]string{"error": fmt.Sprintf("part %d: file bytes are not base64: %v", index, err)})
+				return
+			}
+		}
+		mimeType := fp.GetMimeType()
+		if mimeType == "" {
+			mimeType = http.DetectContentType(data)
+		}
+		setFileHeaders(w, req, fp.GetName(), mimeType)
+		http.ServeContent(w, req, fp.GetName(), time.Time{}, bytes.NewReader(data))
+		return
+	}
+
+	uri := fp.GetFileWithUri()
+	if id := fr.localFileID(uri); id != "" {
+		fr.serveStored(w, req, id, fp.GetName(), fp.GetMimeType())
+		return
+	}
+	fr.fetch(w, req, uri, fp.GetName(), fp.GetMimeType())
+}
+
+// localFileID returns the upload ID when uri refers to a file stored by this BFF.
+func (fr *fileRoutes) localFileID(uri string) string {
+	if id := fileRefID(uri); id != "" {
+		return id
+	}
+	if base := strings.TrimSuffix(fr.publicURL(), "/"); base != "" {
+		if id, ok := strings.CutPrefix(uri, base+filesAPIPath+"/"); ok {
+			return id
+		}
+	}
+	return ""
+}
+
+// fetch streams the file at uri to w through the safe client.
+func (fr *fileRoutes) fetch(w http.ResponseWriter, req *http.Request, uri, name, mimeType string) {
+	u, err := url.Parse(uri)
+	if err == nil {
+		err = checkFetchURL(u)
+	}
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "cannot fetch file URI: " + err.Error()})
+		return
+	}
+	upstream, err := http.NewRequestWithContext(req.Context(), http.MethodGet, u.String(), nil)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	resp, err := fr.client.Do(upstream)
+	if err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, errBlockedAddress) {
+			status = http.StatusForbidden
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": "file URI returned " + resp.Status})
+		return
+	}
+	if resp.ContentLength > maxFetchSize {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": fmt.Sprintf("file exceeds %d bytes", maxFetchSize)})
+		return
+	}
+
+	body := bufio.NewReader(resp.Body)
+	if mimeType == "" {
+		mimeType, _, _ = mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	}
+	if mimeType == "" || mimeType == "application/octet-stream" {
+		head, _ := body.Peek(512)
+		mimeType = http.DetectContentType(head)
+	}
+	if name == "" {
+		name = pathBase(u.Path)
+	}
+	setFileHeaders(w, req, name, mimeType)
+	if resp.ContentLength >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(resp.ContentLength, 10))
+	}
+	if n, err := io.Copy(w, io.LimitReader(body, maxFetchSize+1)); err != nil || n > maxFetchSize {
+		// Headers are gone; abort so the browser sees a failed download, not a short file.
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// setFileHeaders sets the type and disposition of a served file. Files come from agents
+// and users, so they are sandboxed and never sniffed into something executable.
+func setFileHeaders(w http.ResponseWriter, req *http.Request, name, mimeType string) {
+	h := w.Header()
+	h.Set("Content-Type", mimeType)
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Content-Security-Policy", "sandbox")
+	disposition := "inline"
+	if req.URL.Query().Get("download") == "1" {
+		disposition = "attachment"
+	}
+	if v := mime.FormatMediaType(disposition, map[string]string{"filename": name}); name != "" && v != "" {
+		disposition = v
+	}
+	h.Set("Content-Disposition", disposition)
+}
+
+// pathBase returns the last element of a URL path, or "" for a directory.
+func pathBase(p string) string {
+	if i := strings.LastIndex(p, "/"); i >= 0 {
+		p = p[i+1:]
+	}
+	if s, err := url.PathUnescape(p); err == nil {
+		return s
+	}
+	return p
+}
@@ -42,3 +42,12 @@ func WithAgentHeaders(ctx context.Context, headers map[string]string) context.Co
 	}
 	return context.WithValue(ctx, AgentHeadersKey{}, headers)
 }
+
+// AgentHeadersMiddleware injects X-A2A-Agent-Headers into the request context so the
+// proxies can forward them to the agent.
+func AgentHeadersMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := WithAgentHeaders(r.Context(), ExtractAgentHeaders(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
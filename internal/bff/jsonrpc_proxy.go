@@ -4,8 +4,11 @@ import (
 	"context"
 	"errors"
 	"io"
+	"net"
 	"net/http"
+	"strings"
 	"sync"
+	"time"
 
 	"connectrpc.com/connect"
 	"github.com/a2aproject/a2a-go/a2a"
@@ -21,12 +24,14 @@ type jsonrpcProxy struct {
 	agentURL string
 	mu       sync.Mutex
 	client   *a2aclient.Client
+	// cardURL is where the agent's public card is fetched from.
+	cardURL string
 }
 
 // NewJSONRPCProxy creates a proxy that forwards to the JSON-RPC agent at agentURL.
 // agentURL must be a full URL e.g. http://localhost:8080/jsonrpc.
 func NewJSONRPCProxy(agentURL string) *jsonrpcProxy {
-	return &jsonrpcProxy{agentURL: agentURL}
+	return &jsonrpcProxy{agentURL: agentURL, cardURL: publicCardURL(agentURL)}
 }
 
 // getClient returns or creates the a2aclient, connecting lazily on first use.
@@ -36,19 +41,28 @@ func (p *jsonrpcProxy) getClient(ctx context.Context) (*a2aclient.Client, error)
 	if p.client != nil {
 		return p.client, nil
 	}
+	client, err := p.newClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.client = client
+	return p.client, nil
+}
+
+// newClient creates an a2aclient for the agent, without a card.
+func (p *jsonrpcProxy) newClient(ctx context.Context) (*a2aclient.Client, error) {
 	endpoints := []a2a.AgentInterface{
 		{URL: p.agentURL, Transport: a2a.TransportProtocolJSONRPC},
 	}
-	client, err := a2aclient.NewFromEndpoints(ctx, endpoints,
+	return a2aclient.NewFromEndpoints(ctx, endpoints,
 		a2aclient.WithDefaultsDisabled(),
-		a2aclient.WithJSONRPCTransport(nil),
+		// The a2aclient default timeout, with a transport that records response metadata.
+		a2aclient.WithJSONRPCTransport(&http.Client{
+			Timeout:   3 * time.Minute,
+			Transport: recordingTransport{http.DefaultTransport},
+		}),
 		a2aclient.WithInterceptors(&agentHeadersInterceptor{}),
 	)
-	if err != nil {
-		return nil, err
-	}
-	p.client = client
-	return p.client, nil
 }
 
 // ConnectOptions returns Connect-RPC handler options for the proxy.
@@ -57,22 +71,32 @@ func (p *jsonrpcProxy) ConnectOptions() []connect.HandlerOption {
 }
 
 // Handler returns the path and HTTP handler for mounting the A2A Connect service.
-func (p *jsonrpcProxy) Handler() (string, http.Handler) {
-	return a2apbconnect.NewA2AServiceHandler(p, p.ConnectOptions()...)
+// opts are applied after the proxy's own ConnectOptions.
+func (p *jsonrpcProxy) Handler(opts ...connect.HandlerOption) (string, http.Handler) {
+	return a2apbconnect.NewA2AServiceHandler(p, append(p.ConnectOptions(), opts...)...)
 }
 
 // Ensure jsonrpcProxy implements a2apbconnect.A2AServiceHandler.
 var _ a2apbconnect.A2AServiceHandler = (*jsonrpcProxy)(nil)
 
-// agentHeadersInterceptor reads agent headers from context and injects them into req.Meta.
+// agentHeadersInterceptor reads agent headers from context and injects them into req.Meta,
+// with the A2A-Version a2aclient speaks unless they set one, and the extensions to activate.
 type agentHeadersInterceptor struct {
 	a2aclient.PassthroughInterceptor
 }
 
 func (agentHeadersInterceptor) Before(ctx context.Context, req *a2aclient.Request) (context.Context, error) {
 	headers := AgentHeadersFromContext(ctx)
+	version := true
 	for k, v := range headers {
 		req.Meta.Append(k, v)
+		version = version && !strings.EqualFold(k, versionHeader)
+	}
+	if version {
+		req.Meta.Append(versionHeader, string(Version03))
+	}
+	if uris := extensionsFromContext(ctx); len(uris) > 0 {
+		req.Meta.Append(extensionsHeader, uris...)
 	}
 	return ctx, nil
 }
@@ -303,23 +327,51 @@ func toConnectError(err error) error {
 			return connect.NewError(connect.CodePermissionDenied, err)
 		}
 	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return connect.NewError(connect.CodeDeadlineExceeded, err)
+	case errors.Is(err, context.Canceled):
+		return connect.NewError(connect.CodeCanceled, err)
+	}
+	// Connection refused, DNS failures and the like: the agent could not be reached.
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return connect.NewError(connect.CodeUnavailable, err)
+	}
 	return connect.NewError(connect.CodeUnknown, err)
 }
 
-// GetAgentCard forwards the request to the JSON-RPC agent.
+// GetAgentCard returns the agent's public card, with its extended card merged over it
+// when the agent has one.
 func (p *jsonrpcProxy) GetAgentCard(ctx context.Context, req *connect.Request[a2apb.GetAgentCardRequest]) (*connect.Response[a2apb.AgentCard], error) {
-	client, err := p.getClient(ctx)
+	cards, err := p.agentCards(ctx)
 	if err != nil {
-		return nil, connect.NewError(connect.CodeUnavailable, err)
-	}
-	card, err := client.GetAgentCard(ctx)
-	if err != nil {
-		return nil, toConnectError(err)
-	}
-	protoCard, err := pbconv.ToProtoAgentCard(card)
-	if err != nil {
-		return nil, connect.NewError(connect.CodeInternal, err)
+		return nil, err
 	}
-	return connect.NewResponse(protoCard), nil
+	return connect.NewResponse(cards.card()), nil
+}
+
+// agentCards fetches the agent's public card and extended card. The extended card is
+// fetched with a client of its own, made without a card, so its GetAgentCard calls
+// agent/getAuthenticatedExtendedCard. The shared client would keep the card, and serve
+// one caller's extended card to the next, and a card without streaming would turn its
+// SendStreamingMessage calls into unary ones.
+func (p *jsonrpcProxy) agentCards(ctx context.Context) (*agentCards, error) {
+	return fetchAgentCards(ctx, p.cardURL, func(ctx context.Context) (*a2apb.AgentCard, error) {
+		client, err := p.newClient(ctx)
+		if err != nil {
+			return nil, connect.NewError(connect.CodeUnavailable, err)
+		}
+		defer func() { _ = client.Destroy() }()
+		card, err := client.GetAgentCard(ctx)
+		if err != nil {
+			return nil, toConnectError(err)
+		}
+		protoCard, err := pbconv.ToProtoAgentCard(card)
+		if err != nil {
+			return nil, connect.NewError(connect.CodeInternal, err)
+		}
+		return protoCard, nil
+	})
 }
 
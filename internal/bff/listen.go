@@ -0,0 +1,73 @@
+package bff
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// DefaultListenAddr is where the BFF listens unless told otherwise: loopback only, as it
+// forwards the caller's credentials to the agent.
+const DefaultListenAddr = "127.0.0.1:3000"
+
+// unixSocketPrefix marks a listen address as a unix socket path, e.g. "unix:pg.sock".
+// Addresses containing a slash are socket paths without it.
+const unixSocketPrefix = "unix:"
+
+// splitListenAddr returns the network and address for net.Listen. addr is host:port,
+// :port (every interface), a port of 0 to pick a free one, or a unix socket path.
+func splitListenAddr(addr string) (network, address string) {
+	if path, ok := strings.CutPrefix(addr, unixSocketPrefix); ok {
+		return "unix", path
+	}
+	if strings.Contains(addr, "/") {
+		return "unix", addr
+	}
+	return "tcp", addr
+}
+
+// listen binds addr. A socket file left behind by a playground that did not shut down
+// cleanly is replaced; one that is still being served is an error.
+func listen(addr string) (net.Listener, error) {
+	network, address := splitListenAddr(addr)
+	if network == "tcp" {
+		return net.Listen(network, address)
+	}
+	if fi, err := os.Stat(address); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("listen unix %s: file exists and is not a socket", address)
+		}
+		if conn, err := net.Dial(network, address); err == nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("listen unix %s: socket is in use by another server", address)
+		}
+		if err := os.Remove(address); err != nil {
+			return nil, err
+		}
+	}
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+	// Only this user may connect, which is what loopback-only means for a socket.
+	if err := os.Chmod(address, 0o600); err != nil {
+		_ = ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// httpURL returns the URL with scheme for a TCP listener address, or "" for a unix
+// socket. Loopback and unspecified hosts are shown as localhost.
+func httpURL(scheme string, addr net.Addr) string {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return ""
+	}
+	host := tcp.IP.String()
+	if tcp.IP.IsLoopback() || tcp.IP.IsUnspecified() {
+		host = "localhost"
+	}
+	return scheme + "://" + net.JoinHostPort(host, fmt.Sprint(tcp.Port))
+}
@@ -0,0 +1,132 @@
+package bff
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"sync"
+
+	"connectrpc.com/connect"
+	"github.com/alis-exchange/a2a-playground/gen/go/a2apbconnect"
+)
+
+// localBaseURL is the base URL used by in-process clients; requests never leave the process.
+const localBaseURL = "http://a2a-playground.local"
+
+// LocalClient is a Connect client wired to a proxy in-process, so CLI commands go through
+// the same handler stack as the browser without opening a port.
+type LocalClient struct {
+	a2apbconnect.A2AServiceClient
+	server   *http.Server
+	listener *pipeListener
+}
+
+// NewLocalClient serves the proxy over an in-memory listener and returns a client for it.
+// headers are sent as X-A2A-Agent-Headers on every request and forwarded to the agent.
+// opts configure the proxy's handler, such as the interceptors calls go through.
+func NewLocalClient(proxy A2AServiceHandler, headers map[string]string, opts ...connect.HandlerOption) (*LocalClient, error) {
+	path, handler := proxy.Handler(opts...)
+	mux := http.NewServeMux()
+	mux.Handle(path, AgentHeadersMiddleware(handler))
+
+	ln := newPipeListener()
+	srv := &http.Server{Handler: mux}
+	go func() {
+		_ = srv.Serve(ln)
+	}()
+
+	var encoded string
+	if len(headers) > 0 {
+		b, err := json.Marshal(headers)
+		if err != nil {
+			_ = srv.Close()
+			return nil, err
+		}
+		encoded = string(b)
+	}
+
+	httpClient := &http.Client{
+		Transport: &headerTransport{
+			header: encoded,
+			base:   &http.Transport{DialContext: ln.dial},
+		},
+	}
+	return &LocalClient{
+		A2AServiceClient: a2apbconnect.NewA2AServiceClient(httpClient, localBaseURL),
+		server:           srv,
+		listener:         ln,
+	}, nil
+}
+
+// Close stops the in-process server.
+func (c *LocalClient) Close() error {
+	return c.server.Close()
+}
+
+// headerTransport sets X-A2A-Agent-Headers on outgoing requests.
+type headerTransport struct {
+	header string
+	base   http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if t.header != "" {
+		r = r.Clone(r.Context())
+		r.Header.Set(agentHeadersHeader, t.header)
+	}
+	return t.base.RoundTrip(r)
+}
+
+// pipeListener is a net.Listener whose connections are created in-memory with net.Pipe.
+type pipeListener struct {
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+func newPipeListener() *pipeListener {
+	return &pipeListener{
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+}
+
+// Accept waits for the next in-memory connection.
+func (l *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close stops accepting connections.
+func (l *pipeListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return nil
+}
+
+// Addr returns a placeholder address.
+func (l *pipeListener) Addr() net.Addr {
+	return pipeAddr{}
+}
+
+// dial creates a connection pair and hands the server end to Accept.
+func (l *pipeListener) dial(ctx context.Context, _, _ string) (net.Conn, error) {
+	server, client := net.Pipe()
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return "pipe" }
+func (pipeAddr) String() string  { return "pipe" }
@@ -2,37 +2,60 @@ package bff
 
 import (
 	"context"
+	"errors"
 	"io"
+	"log"
 	"net/http"
 	"strings"
 	"sync"
+	"time"
 
 	"connectrpc.com/connect"
 	"github.com/a2aproject/a2a-go/a2apb"
 	"github.com/alis-exchange/a2a-playground/gen/go/a2apbconnect"
 	"go.alis.build/client/v2"
 	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 // A2AServiceHandler is the interface implemented by both grpcProxy and jsonrpcProxy.
 type A2AServiceHandler interface {
 	a2apbconnect.A2AServiceHandler
-	Handler() (string, http.Handler)
+	Handler(opts ...connect.HandlerOption) (string, http.Handler)
 }
 
 // grpcProxy implements A2AServiceHandler by forwarding requests to a gRPC agent.
 type grpcProxy struct {
 	agentURL string
 	mu       sync.Mutex
+	conn     grpc.ClientConnInterface
 	client   a2apb.A2AServiceClient
+	// version is the A2A version spoken to the agent, "" until VersionAuto has found out.
+	version ProtocolVersion
+	// cardURL is where the agent's public card is fetched from.
+	cardURL string
 }
 
 // NewGrpcProxy creates a proxy that forwards to the gRPC agent at agentURL.
 // agentURL may be "localhost:8080" or "http://localhost:8080"; the scheme is stripped for gRPC.
 // Connection is established lazily on first request so startup does not fail if the agent is unreachable.
-func NewGrpcProxy(agentURL string) *grpcProxy {
-	return &grpcProxy{agentURL: agentURL}
+// version is the A2A version the agent speaks; VersionAuto negotiates it, and "" is 0.3.
+func NewGrpcProxy(agentURL string, version ProtocolVersion) *grpcProxy {
+	return &grpcProxy{agentURL: agentURL, version: knownVersion(version), cardURL: publicCardURL(agentURL)}
+}
+
+// knownVersion returns v, Version03 for "", or "" when it is to be negotiated.
+func knownVersion(v ProtocolVersion) ProtocolVersion {
+	switch v {
+	case VersionAuto:
+		return ""
+	case "":
+		return Version03
+	}
+	return v
 }
 
 // getClient returns or creates the gRPC client, connecting lazily on first use.
@@ -49,13 +72,95 @@ func (p *grpcProxy) getClient(ctx context.Context) (a2apb.A2AServiceClient, erro
 	if err != nil {
 		return nil, err
 	}
+	p.conn = conn
 	p.client = a2apb.NewA2AServiceClient(conn)
 	return p.client, nil
 }
 
 // NewGrpcProxyFromConn creates a proxy from an existing gRPC connection.
-func NewGrpcProxyFromConn(client a2apb.A2AServiceClient) *grpcProxy {
-	return &grpcProxy{client: client}
+func NewGrpcProxyFromConn(conn grpc.ClientConnInterface, version ProtocolVersion) *grpcProxy {
+	return &grpcProxy{conn: conn, client: a2apb.NewA2AServiceClient(conn), version: knownVersion(version)}
+}
+
+// NewGrpcProxyFromClient creates a proxy from an existing A2A 0.3 client. Without the
+// connection beneath it, v1 cannot be spoken.
+func NewGrpcProxyFromClient(client a2apb.A2AServiceClient) *grpcProxy {
+	return &grpcProxy{client: client, version: Version03}
+}
+
+// prepare returns the client for a call to the agent, the version to speak, and ctx with
+// the agent headers, the extensions to activate and A2A-Version added.
+func (p *grpcProxy) prepare(ctx context.Context) (context.Context, a2apb.A2AServiceClient, ProtocolVersion, error) {
+	ctx = withAgentHeaders(ctx)
+	client, err := p.getClient(ctx)
+	if err != nil {
+		return ctx, nil, "", err
+	}
+	for _, uri := range extensionsFromContext(ctx) {
+		ctx = metadata.AppendToOutgoingContext(ctx, strings.ToLower(extensionsHeader), uri)
+	}
+	v := p.agentVersion(ctx, client)
+	if v == "" {
+		return ctx, client, Version03, nil
+	}
+	for k := range AgentHeadersFromContext(ctx) {
+		if strings.EqualFold(k, versionHeader) {
+			return ctx, client, v, nil
+		}
+	}
+	return metadata.AppendToOutgoingContext(ctx, strings.ToLower(versionHeader), string(v)), client, v, nil
+}
+
+// agentVersion returns the A2A version to speak to the agent, or "" if it is unknown. In
+// VersionAuto mode, the first call asks for the agent card the v0.3 way. Any answer but
+// Unimplemented, such as NotFound from an agent without an extended card, means v0.3.
+// Unimplemented is only taken for v1 once the agent knows GetExtendedAgentCard, as a
+// v0.3 agent may simply not implement GetAgentCard. An unreachable agent leaves the
+// question to the next call.
+func (p *grpcProxy) agentVersion(ctx context.Context, client a2apb.A2AServiceClient) ProtocolVersion {
+	p.mu.Lock()
+	v := p.version
+	p.mu.Unlock()
+	if v != "" {
+		return v
+	}
+	_, err := client.GetAgentCard(ctx, &a2apb.GetAgentCardRequest{})
+	switch code := status.Code(err); {
+	case unreachableCode(code):
+		return ""
+	case code != codes.Unimplemented || p.conn == nil:
+		v = Version03
+	default:
+		_, err = p.getExtendedAgentCard(ctx, &a2apb.GetAgentCardRequest{}, Version1.callOptions()...)
+		switch code := status.Code(err); {
+		case unreachableCode(code):
+			return ""
+		case code == codes.Unimplemented:
+			v = Version03
+		default:
+			v = Version1
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.version == "" {
+		p.version = v
+		log.Printf("A2A agent at %s speaks version %s", p.agentURL, v)
+	}
+	return p.version
+}
+
+// unreachableCode reports whether a call failed before the agent could answer it.
+func unreachableCode(code codes.Code) bool {
+	return code == codes.Unavailable || code == codes.DeadlineExceeded || code == codes.Canceled
+}
+
+// callOptions are the options for calls in version v.
+func (v ProtocolVersion) callOptions() []grpc.CallOption {
+	if v == Version1 {
+		return []grpc.CallOption{grpc.ForceCodec(v1Codec{})}
+	}
+	return nil
 }
 
 // ConnectOptions returns Connect-RPC handler options for the proxy.
@@ -64,8 +169,9 @@ func (p *grpcProxy) ConnectOptions() []connect.HandlerOption {
 }
 
 // Handler returns the path and HTTP handler for mounting the A2A Connect service.
-func (p *grpcProxy) Handler() (string, http.Handler) {
-	return a2apbconnect.NewA2AServiceHandler(p, p.ConnectOptions()...)
+// opts are applied after the proxy's own ConnectOptions.
+func (p *grpcProxy) Handler(opts ...connect.HandlerOption) (string, http.Handler) {
+	return a2apbconnect.NewA2AServiceHandler(p, append(p.ConnectOptions(), opts...)...)
 }
 
 // Ensure grpcProxy implements a2apbconnect.A2AServiceHandler.
@@ -84,173 +190,238 @@ func withAgentHeaders(ctx context.Context) context.Context {
 	return metadata.NewOutgoingContext(ctx, md)
 }
 
+// fromGRPCError converts a gRPC status error into a Connect error with the same code,
+// so the browser sees e.g. NotFound instead of Unknown.
+func fromGRPCError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	return connect.NewError(connect.Code(st.Code()), errors.New(st.Message()))
+}
+
+// callAgent makes a unary call to the gRPC agent, recording its response metadata for
+// the response metadata interceptor.
+func callAgent[Req, Resp any](ctx context.Context, call func(context.Context, Req, ...grpc.CallOption) (Resp, error), req Req, opts ...grpc.CallOption) (Resp, error) {
+	var header, trailer metadata.MD
+	start := time.Now()
+	resp, err := call(ctx, req, append(opts, grpc.Header(&header), grpc.Trailer(&trailer))...)
+	rec := agentResponseFromContext(ctx)
+	rec.setHeader(headerFromMD(header))
+	rec.finish(headerFromMD(trailer), time.Since(start))
+	return resp, err
+}
+
+// relayStream sends the agent's events to the client, recording the agent's response
+// metadata. start is when the stream was opened.
+func relayStream(ctx context.Context, start time.Time, grpcStream grpc.ServerStreamingClient[a2apb.StreamResponse], stream *connect.ServerStream[a2apb.StreamResponse]) error {
+	rec := agentResponseFromContext(ctx)
+	if md, err := grpcStream.Header(); err == nil {
+		rec.setHeader(headerFromMD(md))
+	}
+	for {
+		msg, err := grpcStream.Recv()
+		if err != nil {
+			// Trailers may only be read once Recv fails.
+			rec.finish(headerFromMD(grpcStream.Trailer()), time.Since(start))
+			if err == io.EOF {
+				return nil
+			}
+			return fromGRPCError(err)
+		}
+		if err := stream.Send(msg); err != nil {
+			rec.finish(nil, time.Since(start))
+			return err
+		}
+	}
+}
+
+// subscribeToTask is TaskSubscription under its v1 name.
+func (p *grpcProxy) subscribeToTask(ctx context.Context, req *a2apb.TaskSubscriptionRequest) (grpc.ServerStreamingClient[a2apb.StreamResponse], error) {
+	stream, err := p.conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, v1SubscribeToTaskProcedure, Version1.callOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[a2apb.TaskSubscriptionRequest, a2apb.StreamResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(req); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// getExtendedAgentCard is GetAgentCard under its v1 name.
+func (p *grpcProxy) getExtendedAgentCard(ctx context.Context, req *a2apb.GetAgentCardRequest, opts ...grpc.CallOption) (*a2apb.AgentCard, error) {
+	out := new(a2apb.AgentCard)
+	if err := p.conn.Invoke(ctx, v1GetExtendedAgentCardProcedure, req, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // SendMessage forwards the request to the gRPC agent.
 func (p *grpcProxy) SendMessage(ctx context.Context, req *connect.Request[a2apb.SendMessageRequest]) (*connect.Response[a2apb.SendMessageResponse], error) {
-	ctx = withAgentHeaders(ctx)
-	client, err := p.getClient(ctx)
+	ctx, client, v, err := p.prepare(ctx)
 	if err != nil {
 		return nil, connect.NewError(connect.CodeUnavailable, err)
 	}
-	resp, err := client.SendMessage(ctx, req.Msg)
+	resp, err := callAgent(ctx, client.SendMessage, req.Msg, v.callOptions()...)
 	if err != nil {
-		return nil, err
+		return nil, fromGRPCError(err)
 	}
 	return connect.NewResponse(resp), nil
 }
 
 // SendStreamingMessage forwards the streaming request to the gRPC agent.
 func (p *grpcProxy) SendStreamingMessage(ctx context.Context, req *connect.Request[a2apb.SendMessageRequest], stream *connect.ServerStream[a2apb.StreamResponse]) error {
-	ctx = withAgentHeaders(ctx)
-	client, err := p.getClient(ctx)
+	ctx, client, v, err := p.prepare(ctx)
 	if err != nil {
 		return connect.NewError(connect.CodeUnavailable, err)
 	}
-	grpcStream, err := client.SendStreamingMessage(ctx, req.Msg)
+	start := time.Now()
+	grpcStream, err := client.SendStreamingMessage(ctx, req.Msg, v.callOptions()...)
 	if err != nil {
-		return err
-	}
-	for {
-		msg, err := grpcStream.Recv()
-		if err == io.EOF {
-			return nil
-		}
-		if err != nil {
-			return err
-		}
-		if err := stream.Send(msg); err != nil {
-			return err
-		}
+		agentResponseFromContext(ctx).finish(nil, time.Since(start))
+		return fromGRPCError(err)
 	}
+	return relayStream(ctx, start, grpcStream, stream)
 }
 
 // GetTask forwards the request to the gRPC agent.
 func (p *grpcProxy) GetTask(ctx context.Context, req *connect.Request[a2apb.GetTaskRequest]) (*connect.Response[a2apb.Task], error) {
-	ctx = withAgentHeaders(ctx)
-	client, err := p.getClient(ctx)
+	ctx, client, v, err := p.prepare(ctx)
 	if err != nil {
 		return nil, connect.NewError(connect.CodeUnavailable, err)
 	}
-	resp, err := client.GetTask(ctx, req.Msg)
+	resp, err := callAgent(ctx, client.GetTask, req.Msg, v.callOptions()...)
 	if err != nil {
-		return nil, err
+		return nil, fromGRPCError(err)
 	}
 	return connect.NewResponse(resp), nil
 }
 
 // ListTasks forwards the request to the gRPC agent.
 func (p *grpcProxy) ListTasks(ctx context.Context, req *connect.Request[a2apb.ListTasksRequest]) (*connect.Response[a2apb.ListTasksResponse], error) {
-	ctx = withAgentHeaders(ctx)
-	client, err := p.getClient(ctx)
+	ctx, client, v, err := p.prepare(ctx)
 	if err != nil {
 		return nil, connect.NewError(connect.CodeUnavailable, err)
 	}
-	resp, err := client.ListTasks(ctx, req.Msg)
+	resp, err := callAgent(ctx, client.ListTasks, req.Msg, v.callOptions()...)
 	if err != nil {
-		return nil, err
+		return nil, fromGRPCError(err)
 	}
 	return connect.NewResponse(resp), nil
 }
 
 // CancelTask forwards the request to the gRPC agent.
 func (p *grpcProxy) CancelTask(ctx context.Context, req *connect.Request[a2apb.CancelTaskRequest]) (*connect.Response[a2apb.Task], error) {
-	ctx = withAgentHeaders(ctx)
-	client, err := p.getClient(ctx)
+	ctx, client, v, err := p.prepare(ctx)
 	if err != nil {
 		return nil, connect.NewError(connect.CodeUnavailable, err)
 	}
-	resp, err := client.CancelTask(ctx, req.Msg)
+	resp, err := callAgent(ctx, client.CancelTask, req.Msg, v.callOptions()...)
 	if err != nil {
-		return nil, err
+		return nil, fromGRPCError(err)
 	}
 	return connect.NewResponse(resp), nil
 }
 
 // TaskSubscription forwards the streaming request to the gRPC agent.
 func (p *grpcProxy) TaskSubscription(ctx context.Context, req *connect.Request[a2apb.TaskSubscriptionRequest], stream *connect.ServerStream[a2apb.StreamResponse]) error {
-	ctx = withAgentHeaders(ctx)
-	client, err := p.getClient(ctx)
+	ctx, client, v, err := p.prepare(ctx)
 	if err != nil {
 		return connect.NewError(connect.CodeUnavailable, err)
 	}
-	grpcStream, err := client.TaskSubscription(ctx, req.Msg)
-	if err != nil {
-		return err
+	start := time.Now()
+	var grpcStream grpc.ServerStreamingClient[a2apb.StreamResponse]
+	if v == Version1 {
+		grpcStream, err = p.subscribeToTask(ctx, req.Msg)
+	} else {
+		grpcStream, err = client.TaskSubscription(ctx, req.Msg)
 	}
-	for {
-		msg, err := grpcStream.Recv()
-		if err == io.EOF {
-			return nil
-		}
-		if err != nil {
-			return err
-		}
-		if err := stream.Send(msg); err != nil {
-			return err
-		}
+	if err != nil {
+		agentResponseFromContext(ctx).finish(nil, time.Since(start))
+		return fromGRPCError(err)
 	}
+	return relayStream(ctx, start, grpcStream, stream)
 }
 
 func (p *grpcProxy) CreateTaskPushNotificationConfig(ctx context.Context, req *connect.Request[a2apb.CreateTaskPushNotificationConfigRequest]) (*connect.Response[a2apb.TaskPushNotificationConfig], error) {
-	ctx = withAgentHeaders(ctx)
-	client, err := p.getClient(ctx)
+	ctx, client, v, err := p.prepare(ctx)
 	if err != nil {
 		return nil, connect.NewError(connect.CodeUnavailable, err)
 	}
-	resp, err := client.CreateTaskPushNotificationConfig(ctx, req.Msg)
+	resp, err := callAgent(ctx, client.CreateTaskPushNotificationConfig, req.Msg, v.callOptions()...)
 	if err != nil {
-		return nil, err
+		return nil, fromGRPCError(err)
 	}
 	return connect.NewResponse(resp), nil
 }
 
 func (p *grpcProxy) GetTaskPushNotificationConfig(ctx context.Context, req *connect.Request[a2apb.GetTaskPushNotificationConfigRequest]) (*connect.Response[a2apb.TaskPushNotificationConfig], error) {
-	ctx = withAgentHeaders(ctx)
-	client, err := p.getClient(ctx)
+	ctx, client, v, err := p.prepare(ctx)
 	if err != nil {
 		return nil, connect.NewError(connect.CodeUnavailable, err)
 	}
-	resp, err := client.GetTaskPushNotificationConfig(ctx, req.Msg)
+	resp, err := callAgent(ctx, client.GetTaskPushNotificationConfig, req.Msg, v.callOptions()...)
 	if err != nil {
-		return nil, err
+		return nil, fromGRPCError(err)
 	}
 	return connect.NewResponse(resp), nil
 }
 
 func (p *grpcProxy) ListTaskPushNotificationConfig(ctx context.Context, req *connect.Request[a2apb.ListTaskPushNotificationConfigRequest]) (*connect.Response[a2apb.ListTaskPushNotificationConfigResponse], error) {
-	ctx = withAgentHeaders(ctx)
-	client, err := p.getClient(ctx)
+	ctx, client, v, err := p.prepare(ctx)
 	if err != nil {
 		return nil, connect.NewError(connect.CodeUnavailable, err)
 	}
-	resp, err := client.ListTaskPushNotificationConfig(ctx, req.Msg)
+	resp, err := callAgent(ctx, client.ListTaskPushNotificationConfig, req.Msg, v.callOptions()...)
 	if err != nil {
-		return nil, err
+		return nil, fromGRPCError(err)
 	}
 	return connect.NewResponse(resp), nil
 }
 
+// GetAgentCard returns the agent's public card, with its extended card merged over it
+// when the agent has one.
 func (p *grpcProxy) GetAgentCard(ctx context.Context, req *connect.Request[a2apb.GetAgentCardRequest]) (*connect.Response[a2apb.AgentCard], error) {
-	ctx = withAgentHeaders(ctx)
-	client, err := p.getClient(ctx)
-	if err != nil {
-		return nil, connect.NewError(connect.CodeUnavailable, err)
-	}
-	resp, err := client.GetAgentCard(ctx, req.Msg)
+	cards, err := p.agentCards(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return connect.NewResponse(resp), nil
+	return connect.NewResponse(cards.card()), nil
+}
+
+// agentCards fetches the agent's public card and extended card. Over gRPC, GetAgentCard
+// returns the extended card, as GetExtendedAgentCard does in v1.
+func (p *grpcProxy) agentCards(ctx context.Context) (*agentCards, error) {
+	return fetchAgentCards(ctx, p.cardURL, func(ctx context.Context) (*a2apb.AgentCard, error) {
+		ctx, client, v, err := p.prepare(ctx)
+		if err != nil {
+			return nil, connect.NewError(connect.CodeUnavailable, err)
+		}
+		call := client.GetAgentCard
+		if v == Version1 {
+			call = p.getExtendedAgentCard
+		}
+		resp, err := callAgent(ctx, call, &a2apb.GetAgentCardRequest{}, v.callOptions()...)
+		if err != nil {
+			return nil, fromGRPCError(err)
+		}
+		return resp, nil
+	})
 }
 
 func (p *grpcProxy) DeleteTaskPushNotificationConfig(ctx context.Context, req *connect.Request[a2apb.DeleteTaskPushNotificationConfigRequest]) (*connect.Response[emptypb.Empty], error) {
-	ctx = withAgentHeaders(ctx)
-	client, err := p.getClient(ctx)
+	ctx, client, v, err := p.prepare(ctx)
 	if err != nil {
 		return nil, connect.NewError(connect.CodeUnavailable, err)
 	}
-	resp, err := client.DeleteTaskPushNotificationConfig(ctx, req.Msg)
+	resp, err := callAgent(ctx, client.DeleteTaskPushNotificationConfig, req.Msg, v.callOptions()...)
 	if err != nil {
-		return nil, err
+		return nil, fromGRPCError(err)
 	}
 	return connect.NewResponse(resp), nil
 }
@@ -0,0 +1,96 @@
+package bff
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// redacted replaces secret values in recorded headers and exported payloads.
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are header names and JSON field names whose values are never stored or exported.
+var sensitiveKeys = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+	"set-cookie":          true,
+	"x-api-key":           true,
+	"api-key":             true,
+	"apikey":              true,
+	"token":               true,
+	"credentials":         true,
+	"password":            true,
+	"secret":              true,
+	"client_secret":       true,
+	"clientsecret":        true,
+	"access_token":        true,
+	"refresh_token":       true,
+	"id_token":            true,
+}
+
+// isSensitiveKey reports whether the value for key should be redacted.
+func isSensitiveKey(key string) bool {
+	k := strings.ToLower(key)
+	if sensitiveKeys[k] {
+		return true
+	}
+	return strings.HasSuffix(k, "-token") || strings.HasSuffix(k, "_token") || strings.HasSuffix(k, "-secret")
+}
+
+// redactHeaders copies h with secret values replaced. X-A2A-Agent-Headers is decoded and
+// redacted per header so non-secret forwarded headers stay readable.
+func redactHeaders(h http.Header) map[string][]string {
+	out := make(map[string][]string, len(h))
+	for k, vs := range h {
+		switch {
+		case isSensitiveKey(k):
+			out[k] = []string{redacted}
+		case strings.EqualFold(k, agentHeadersHeader):
+			redactedValues := make([]string, 0, len(vs))
+			for _, v := range vs {
+				redactedValues = append(redactedValues, string(redactJSON([]byte(v))))
+			}
+			out[k] = redactedValues
+		default:
+			out[k] = append([]string(nil), vs...)
+		}
+	}
+	return out
+}
+
+// redactJSON replaces the values of sensitive fields anywhere in a JSON document.
+// Input that is not valid JSON is returned unchanged.
+func redactJSON(data []byte) []byte {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return data
+	}
+	out, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return data
+	}
+	return out
+}
+
+func redactValue(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			if isSensitiveKey(k) {
+				if _, isObj := val.(map[string]any); !isObj {
+					t[k] = redacted
+					continue
+				}
+			}
+			t[k] = redactValue(val)
+		}
+		return t
+	case []any:
+		for i, val := range t {
+			t[i] = redactValue(val)
+		}
+		return t
+	}
+	return v
+}
@@ -0,0 +1,310 @@
+package bff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/alis-exchange/a2a-playground/gen/go/a2apbconnect"
+)
+
+// attemptsHeader reports how many attempts a retried call took.
+const attemptsHeader = "X-A2A-Attempts"
+
+// idempotentProcedures may be retried: repeating them has no effect on the agent. Deletes
+// are left out, as a retry after a lost response would fail with NotFound.
+var idempotentProcedures = []string{
+	a2apbconnect.A2AServiceGetTaskProcedure,
+	a2apbconnect.A2AServiceListTasksProcedure,
+	a2apbconnect.A2AServiceGetAgentCardProcedure,
+	a2apbconnect.A2AServiceGetTaskPushNotificationConfigProcedure,
+	a2apbconnect.A2AServiceListTaskPushNotificationConfigProcedure,
+}
+
+// RetryPolicy retries idempotent unary calls that fail with one of Codes, waiting
+// InitialBackoff, then twice as long each time up to MaxBackoff, with some jitter.
+// MaxAttempts counts the first call; 1 or less disables retries.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Codes          []connect.Code
+}
+
+// backoff returns the wait before retry n (1 for the first retry).
+func (p RetryPolicy) backoff(n int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < n && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	// ±20% so clients retrying together spread out.
+	return time.Duration(float64(d) * (0.8 + 0.4*rand.Float64()))
+}
+
+// BreakerPolicy opens the circuit after Threshold consecutive calls fail because the agent
+// is unreachable; calls then fail fast for Cooldown, after which one call probes the
+// agent. A Threshold of 0 disables the breaker.
+type BreakerPolicy struct {
+	Threshold int
+	Cooldown  time.Duration
+}
+
+// Resilience applies the retry policy and circuit breaker to calls to one agent.
+type Resilience struct {
+	agentURL string
+	retry    RetryPolicy
+	breaker  BreakerPolicy
+
+	mu        sync.Mutex
+	state     breakerState
+	failures  int
+	openedAt  time.Time
+	lastError string
+	probing   bool
+
+	calls, retries, exhausted, opened, rejected atomic.Int64
+}
+
+type breakerState string
+
+const (
+	breakerClosed   breakerState = "closed"
+	breakerOpen     breakerState = "open"
+	breakerHalfOpen breakerState = "half-open"
+)
+
+// NewResilience returns the retry and circuit breaker state for the agent at agentURL.
+func NewResilience(agentURL string, retry RetryPolicy, breaker BreakerPolicy) *Resilience {
+	return &Resilience{agentURL: agentURL, retry: retry, breaker: breaker, state: breakerClosed}
+}
+
+// ResilienceStats is a snapshot of the policies, breaker state and counters.
+type ResilienceStats struct {
+	MaxAttempts      int            `json:"maxAttempts"`
+	InitialBackoff   string         `json:"initialBackoff"`
+	MaxBackoff       string         `json:"maxBackoff"`
+	RetryCodes       []connect.Code `json:"retryCodes"`
+	BreakerThreshold int            `json:"breakerThreshold"`
+	BreakerCooldown  string         `json:"breakerCooldown"`
+	State            string         `json:"state"`
+	// ConsecutiveFailures is the number of calls in a row that found the agent unreachable.
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	OpenedAt            *time.Time `json:"openedAt,omitempty"`
+	LastError           string     `json:"lastError,omitempty"`
+	Calls               int64      `json:"calls"`
+	Retries             int64      `json:"retries"`
+	RetriesExhausted    int64      `json:"retriesExhausted"`
+	BreakerOpened       int64      `json:"breakerOpened"`
+	Rejected            int64      `json:"rejected"`
+}
+
+// Stats returns the current state and counters.
+func (r *Resilience) Stats() ResilienceStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := ResilienceStats{
+		MaxAttempts:         r.retry.MaxAttempts,
+		InitialBackoff:      r.retry.InitialBackoff.String(),
+		MaxBackoff:          r.retry.MaxBackoff.String(),
+		RetryCodes:          r.retry.Codes,
+		BreakerThreshold:    r.breaker.Threshold,
+		BreakerCooldown:     r.breaker.Cooldown.String(),
+		State:               string(r.state),
+		ConsecutiveFailures: r.failures,
+		LastError:           r.lastError,
+		Calls:               r.calls.Load(),
+		Retries:             r.retries.Load(),
+		RetriesExhausted:    r.exhausted.Load(),
+		BreakerOpened:       r.opened.Load(),
+		Rejected:            r.rejected.Load(),
+	}
+	if r.state != breakerClosed {
+		t := r.openedAt
+		s.OpenedAt = &t
+	}
+	return s
+}
+
+// Interceptor returns the Connect interceptor that retries and circuit-breaks calls.
+// It belongs inside the deadline interceptor, so retries share the call's deadline.
+func (r *Resilience) Interceptor() connect.Interceptor {
+	return resilienceInterceptor{r}
+}
+
+// allow reports whether a call may go to the agent, or returns the fail-fast error.
+func (r *Resilience) allow() error {
+	if r.breaker.Threshold <= 0 {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch r.state {
+	case breakerOpen:
+		retryIn := time.Until(r.openedAt.Add(r.breaker.Cooldown))
+		if retryIn > 0 {
+			r.rejected.Add(1)
+			return connect.NewError(connect.CodeUnavailable, fmt.Errorf(
+				"agent %s looks down: %d calls in a row failed (last: %s); not calling it for another %s",
+				r.agentURL, r.failures, r.lastError, retryIn.Round(time.Second)))
+		}
+		r.state = breakerHalfOpen
+		log.Printf("circuit breaker for %s: half-open, probing the agent", r.agentURL)
+		fallthrough
+	case breakerHalfOpen:
+		if r.probing {
+			r.rejected.Add(1)
+			return connect.NewError(connect.CodeUnavailable, fmt.Errorf(
+				"agent %s looks down (last: %s); waiting for a probe call to finish", r.agentURL, r.lastError))
+		}
+		r.probing = true
+	}
+	return nil
+}
+
+// record updates the breaker with the outcome of a call that was allowed through.
+func (r *Resilience) record(err error) {
+	if r.breaker.Threshold <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	wasProbe := r.state == breakerHalfOpen
+	if wasProbe {
+		r.probing = false
+	}
+	if errors.Is(err, context.Canceled) || connect.CodeOf(err) == connect.CodeCanceled {
+		// The client gave up; that says nothing about the agent.
+		return
+	}
+	if !agentUnreachable(err) {
+		if r.state != breakerClosed {
+			log.Printf("circuit breaker for %s: closed, the agent is back", r.agentURL)
+		}
+		r.state, r.failures, r.lastError = breakerClosed, 0, ""
+		return
+	}
+	r.failures++
+	r.lastError = err.Error()
+	if wasProbe || (r.state == breakerClosed && r.failures >= r.breaker.Threshold) {
+		r.state, r.openedAt = breakerOpen, time.Now()
+		r.opened.Add(1)
+		log.Printf("circuit breaker for %s: open for %s after %d failed calls: %v", r.agentURL, r.breaker.Cooldown, r.failures, err)
+	}
+}
+
+// agentUnreachable reports whether err means the agent could not be reached, as opposed
+// to the agent answering with an error.
+func agentUnreachable(err error) bool {
+	return err != nil && connect.CodeOf(err) == connect.CodeUnavailable
+}
+
+// retryable reports whether a failed attempt of procedure should be repeated.
+func (r *Resilience) retryable(procedure string, err error) bool {
+	return slices.Contains(idempotentProcedures, procedure) && slices.Contains(r.retry.Codes, connect.CodeOf(err))
+}
+
+// wait sleeps before retry n, returning early if ctx ends.
+func (r *Resilience) wait(ctx context.Context, n int) error {
+	t := time.NewTimer(r.retry.backoff(n))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type resilienceInterceptor struct {
+	r *Resilience
+}
+
+func (i resilienceInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		r := i.r
+		r.calls.Add(1)
+		if err := r.allow(); err != nil {
+			return nil, err
+		}
+		procedure := req.Spec().Procedure
+		var (
+			resp connect.AnyResponse
+			err  error
+		)
+		attempt := 1
+		for ; ; attempt++ {
+			resp, err = next(ctx, req)
+			if err == nil || !r.retryable(procedure, err) {
+				break
+			}
+			if attempt >= r.retry.MaxAttempts {
+				r.exhausted.Add(1)
+				log.Printf("%s: giving up after %d attempts: %v", procedure, attempt, err)
+				break
+			}
+			log.Printf("%s: attempt %d failed, retrying: %v", procedure, attempt, err)
+			r.retries.Add(1)
+			if werr := r.wait(ctx, attempt); werr != nil {
+				break
+			}
+		}
+		r.record(err)
+		if attempt > 1 {
+			if err != nil {
+				var cerr *connect.Error
+				if errors.As(err, &cerr) {
+					cerr.Meta().Set(attemptsHeader, strconv.Itoa(attempt))
+				}
+			} else {
+				resp.Header().Set(attemptsHeader, strconv.Itoa(attempt))
+			}
+		}
+		return resp, err
+	}
+}
+
+func (i resilienceInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler applies the circuit breaker to streams. Streams are never retried,
+// and only count as failed when the agent was unreachable before sending any event.
+func (i resilienceInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		r := i.r
+		r.calls.Add(1)
+		if err := r.allow(); err != nil {
+			return err
+		}
+		sc := &sentCountingConn{StreamingHandlerConn: conn}
+		err := next(ctx, sc)
+		if sc.sent > 0 {
+			r.record(nil)
+		} else {
+			r.record(err)
+		}
+		return err
+	}
+}
+
+// sentCountingConn counts the messages sent on a stream.
+type sentCountingConn struct {
+	connect.StreamingHandlerConn
+	sent int
+}
+
+func (c *sentCountingConn) Send(msg any) error {
+	c.sent++
+	return c.StreamingHandlerConn.Send(msg)
+}
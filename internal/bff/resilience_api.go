@@ -0,0 +1,19 @@
+package bff
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// resilienceAPIPath reports retry and circuit breaker activity.
+const resilienceAPIPath = "/api/admin/resilience"
+
+// registerResilienceRoutes mounts the resilience report on r:
+//
+//	GET /api/admin/resilience       policies, breaker state and counters
+func registerResilienceRoutes(r *mux.Router, res *Resilience) {
+	r.HandleFunc(resilienceAPIPath, func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, res.Stats())
+	}).Methods(http.MethodGet)
+}
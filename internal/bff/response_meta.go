@@ -0,0 +1,267 @@
+package bff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+	"google.golang.org/grpc/metadata"
+)
+
+// DefaultForwardHeaders are the agent response headers forwarded to the browser unless
+// configured otherwise: request IDs, rate limits and debug information.
+var DefaultForwardHeaders = []string{
+	"x-request-id", "x-correlation-id", "x-trace-id", "traceparent",
+	"retry-after", "ratelimit-*", "x-ratelimit-*", "x-debug-*", "server-timing",
+}
+
+// DefaultForwardHeaderPrefix is prepended to forwarded agent headers, so they cannot be
+// mistaken for, or overwrite, the BFF's own.
+const DefaultForwardHeaderPrefix = "X-Agent-"
+
+// framingHeaders describe the agent's HTTP or gRPC response rather than its content, and
+// are never forwarded.
+var framingHeaders = []string{
+	"connection", "content-*", "date", "grpc-*", "keep-alive", "te", "trailer",
+	"transfer-encoding", "vary", ":*",
+}
+
+// ResponseHeaderPolicy selects the agent response headers and trailers that reach the
+// browser. Allow holds case-insensitive names, which may use path.Match wildcards such
+// as "x-ratelimit-*". Forwarded names get Prefix.
+type ResponseHeaderPolicy struct {
+	Prefix string
+	Allow  []string
+}
+
+// allowed reports whether the agent header name may be forwarded.
+func (p ResponseHeaderPolicy) allowed(name string) bool {
+	return matchesAny(p.Allow, name) && !matchesAny(framingHeaders, name)
+}
+
+func matchesAny(patterns []string, name string) bool {
+	name = strings.ToLower(name)
+	for _, p := range patterns {
+		if ok, _ := path.Match(strings.ToLower(p), name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// copyAllowed adds the allowed headers in src to dst under the prefix.
+func (p ResponseHeaderPolicy) copyAllowed(dst, src http.Header) {
+	for name, values := range src {
+		if !p.allowed(name) {
+			continue
+		}
+		for _, v := range values {
+			dst.Add(p.Prefix+name, v)
+		}
+	}
+}
+
+// agentResponseKey is the context key for the agentResponse of a call.
+type agentResponseKey struct{}
+
+// agentResponse collects the metadata the agent sent back and the time spent waiting for
+// it. The proxies record into it; the response metadata interceptor reads it. A call may
+// reach the agent several times, e.g. when retried: the latest headers win and the times
+// add up.
+type agentResponse struct {
+	mu      sync.Mutex
+	header  http.Header
+	trailer http.Header
+	elapsed time.Duration
+}
+
+// agentResponseFromContext returns the collector for the call, or nil when nobody asked
+// for the agent's response metadata.
+func agentResponseFromContext(ctx context.Context) *agentResponse {
+	r, _ := ctx.Value(agentResponseKey{}).(*agentResponse)
+	return r
+}
+
+func (r *agentResponse) setHeader(h http.Header) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.header = h
+}
+
+// finish records the trailers and duration of a call to the agent.
+func (r *agentResponse) finish(trailer http.Header, elapsed time.Duration) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.trailer = trailer
+	r.elapsed += elapsed
+}
+
+func (r *agentResponse) snapshot() (header, trailer http.Header, elapsed time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.header, r.trailer, r.elapsed
+}
+
+// headerFromMD converts gRPC metadata to HTTP headers. Binary values are base64-encoded,
+// as Connect does for "-bin" headers.
+func headerFromMD(md metadata.MD) http.Header {
+	h := make(http.Header, len(md))
+	for k, values := range md {
+		for _, v := range values {
+			if strings.HasSuffix(k, "-bin") {
+				v = connect.EncodeBinaryHeader([]byte(v))
+			}
+			h.Add(k, v)
+		}
+	}
+	return h
+}
+
+// recordingTransport records the response headers, trailers and duration of JSON-RPC
+// calls into the call's agentResponse.
+type recordingTransport struct {
+	next http.RoundTripper
+}
+
+func (t recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := agentResponseFromContext(req.Context())
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || rec == nil {
+		rec.finish(nil, time.Since(start))
+		return resp, err
+	}
+	rec.setHeader(resp.Header.Clone())
+	resp.Body = &recordingBody{ReadCloser: resp.Body, resp: resp, rec: rec, start: start}
+	return resp, nil
+}
+
+// recordingBody finishes the agentResponse once the body is read or closed, when the
+// trailers are known.
+type recordingBody struct {
+	io.ReadCloser
+	resp  *http.Response
+	rec   *agentResponse
+	start time.Time
+	once  sync.Once
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil {
+		b.done()
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	b.done()
+	return b.ReadCloser.Close()
+}
+
+func (b *recordingBody) done() {
+	b.once.Do(func() { b.rec.finish(b.resp.Trailer.Clone(), time.Since(b.start)) })
+}
+
+// responseMetadataInterceptor forwards agent response metadata to the browser and adds
+// Server-Timing entries that split the time spent in the BFF from the time spent waiting
+// for the agent.
+type responseMetadataInterceptor struct {
+	policy ResponseHeaderPolicy
+}
+
+// NewResponseMetadataInterceptor returns the interceptor that forwards the agent's
+// response headers and trailers allowed by policy, and reports timings. It should be the
+// outermost interceptor, so the BFF time covers the rest of the chain.
+func NewResponseMetadataInterceptor(policy ResponseHeaderPolicy) connect.Interceptor {
+	return responseMetadataInterceptor{policy}
+}
+
+func (i responseMetadataInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		start := time.Now()
+		rec := &agentResponse{}
+		resp, err := next(context.WithValue(ctx, agentResponseKey{}, rec), req)
+		header, trailer, agent := rec.snapshot()
+		var meta http.Header
+		var cerr *connect.Error
+		switch {
+		case err == nil:
+			meta = resp.Header()
+			i.policy.copyAllowed(resp.Trailer(), trailer)
+		case errors.As(err, &cerr):
+			// Unary errors carry their metadata as headers.
+			meta = cerr.Meta()
+			i.policy.copyAllowed(meta, trailer)
+		default:
+			return resp, err
+		}
+		i.policy.copyAllowed(meta, header)
+		meta.Add("Server-Timing", serverTiming(time.Since(start), agent))
+		return resp, err
+	}
+}
+
+func (i responseMetadataInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (i responseMetadataInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		rc := &responseMetadataConn{StreamingHandlerConn: conn, policy: i.policy, rec: &agentResponse{}, start: time.Now()}
+		err := next(context.WithValue(ctx, agentResponseKey{}, rc.rec), rc)
+		_, trailer, agent := rc.rec.snapshot()
+		if !rc.sent {
+			// Nothing was sent, so the headers have not been written yet either.
+			rc.copyHeader(conn.ResponseHeader())
+		}
+		// Connect sends a stream's trailers along with any error.
+		meta := conn.ResponseTrailer()
+		i.policy.copyAllowed(meta, trailer)
+		meta.Add("Server-Timing", serverTiming(time.Since(rc.start), agent))
+		return err
+	}
+}
+
+// responseMetadataConn writes the agent's response headers with the first event, and the
+// time it took to arrive.
+type responseMetadataConn struct {
+	connect.StreamingHandlerConn
+	policy ResponseHeaderPolicy
+	rec    *agentResponse
+	start  time.Time
+	sent   bool
+}
+
+func (c *responseMetadataConn) Send(msg any) error {
+	if !c.sent {
+		c.sent = true
+		h := c.ResponseHeader()
+		c.copyHeader(h)
+		h.Add("Server-Timing", fmt.Sprintf(`first-event;desc="first event";dur=%.1f`, millis(time.Since(c.start))))
+	}
+	return c.StreamingHandlerConn.Send(msg)
+}
+
+func (c *responseMetadataConn) copyHeader(dst http.Header) {
+	header, _, _ := c.rec.snapshot()
+	c.policy.copyAllowed(dst, header)
+}
+
+// serverTiming formats the BFF and agent share of a call's duration.
+func serverTiming(total, agent time.Duration) string {
+	return fmt.Sprintf(`bff;desc="BFF";dur=%.1f, agent;desc="agent";dur=%.1f`, millis(max(total-agent, 0)), millis(agent))
+}
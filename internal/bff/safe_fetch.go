@@ -0,0 +1,125 @@
+package bff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// maxFetchSize bounds a file fetched on behalf of the browser.
+	maxFetchSize = 1 << 30
+	// fetchTimeout bounds a whole fetch, including reading the body.
+	fetchTimeout = 5 * time.Minute
+	// maxFetchRedirects bounds the redirects followed by a fetch.
+	maxFetchRedirects = 5
+)
+
+// errBlockedAddress is returned when a fetch would connect to a non-public address.
+var errBlockedAddress = errors.New("address is not publicly routable")
+
+// newSafeHTTPClient returns a client for fetching URLs taken from agent responses. It only
+// speaks http and https, ignores proxy settings, and refuses to connect to loopback,
+// private, link-local and other non-public addresses, checking the resolved IP it
+// actually dials so DNS cannot point it elsewhere. Hosts in allowHosts (host:port, as
+// dialed) are exempt, so a local agent can still serve its own files.
+func newSafeHTTPClient(allowHosts ...string) *http.Client {
+	allowed := map[string]bool{}
+	for _, h := range allowHosts {
+		if h != "" {
+			allowed[strings.ToLower(h)] = true
+		}
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	transport := &http.Transport{
+		Proxy:               nil,
+		TLSHandshakeTimeout: 10 * time.Second,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if allowed[strings.ToLower(addr)] {
+				return dialer.DialContext(ctx, network, addr)
+			}
+			host, port, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+			if err != nil {
+				return nil, err
+			}
+			for _, ip := range ips {
+				if isPublicAddr(ip) {
+					return dialer.DialContext(ctx, network, net.JoinHostPort(ip.Unmap().String(), port))
+				}
+			}
+			return nil, fmt.Errorf("fetch %s: %w", host, errBlockedAddress)
+		},
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   fetchTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxFetchRedirects {
+				return errors.New("too many redirects")
+			}
+			return checkFetchURL(req.URL)
+		},
+	}
+}
+
+// checkFetchURL rejects URLs the safe client must not follow.
+func checkFetchURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	if u.User != nil {
+		return errors.New("URLs with credentials are not fetched")
+	}
+	return nil
+}
+
+// isPublicAddr reports whether ip is a globally routable unicast address.
+func isPublicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, p := range nonPublicPrefixes {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// nonPublicPrefixes are ranges IsGlobalUnicast and IsPrivate let through.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64, which can reach IPv4 private ranges
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+// dialAddr returns the host:port a client dials for an agent URL, which is either
+// host:port already (gRPC) or an http(s) URL (JSON-RPC).
+func dialAddr(agentURL string) string {
+	u, err := url.Parse(agentURL)
+	if err != nil || u.Host == "" {
+		return agentURL
+	}
+	if u.Port() != "" {
+		return u.Host
+	}
+	if u.Scheme == "https" {
+		return net.JoinHostPort(u.Hostname(), "443")
+	}
+	return net.JoinHostPort(u.Hostname(), "80")
+}
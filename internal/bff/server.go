@@ -2,87 +2,437 @@ package bff
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"log"
+	"net"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 
+	"connectrpc.com/connect"
+	"github.com/alis-exchange/a2a-playground/gen/go/a2apbconnect"
 	"github.com/gorilla/mux"
 )
 
 // ServerConfig holds configuration for the BFF server.
 type ServerConfig struct {
-	Port        int
-	AgentURL    string
-	Protocol    Protocol
-	Dev         bool
-	NoOpen      bool
-	AppDir      string
-	OpenBrowser bool
+	// Listen is host:port, :port for every interface, port 0 for a free port, or a unix
+	// socket path. It defaults to DefaultListenAddr.
+	Listen string
+	// BasePath serves the playground under a path such as "/playground" rather than at
+	// the root.
+	BasePath string
+	AgentURL string
+	// Proxy is the agent to talk to, when it is not one at AgentURL, such as an agent in
+	// the same process. AgentURL then only names it in sessions and the task cache.
+	Proxy    A2AServiceHandler
+	Protocol Protocol
+	// AgentVersion is the A2A version spoken to a gRPC agent. JSON-RPC agents speak 0.3.
+	AgentVersion ProtocolVersion
+	// AgentCardURL is the URL of the agent's public card; see AgentConfig.CardURL.
+	AgentCardURL string
+	Dev          bool
+	NoOpen       bool
+	AppDir       string
+	OpenBrowser  bool
+	// DataDir holds the session database. Sessions are not recorded when empty.
+	DataDir string
+	// TasksDir holds the task cache, searchable at /api/tasks. Tasks are not cached when
+	// empty. TaskRetention bounds it.
+	TasksDir      string
+	TaskRetention TaskRetention
+	// DurableStreams keeps SendStreamingMessage running when the browser disconnects and
+	// lets it reattach with X-A2A-Stream-Resume or /api/streams.
+	DurableStreams bool
+	// ShareSubscriptions lets TaskSubscription calls for the same task share one upstream
+	// subscription, each subscriber starting from a snapshot of the task.
+	ShareSubscriptions bool
+	// Extensions are A2A extension URIs activated on every call, besides those a call
+	// asks for with X-A2A-Extensions.
+	Extensions []string
+	// CompareURL is the agent's endpoint on the other transport (a JSON-RPC URL when
+	// Protocol is gRPC, and host:port otherwise). It enables /api/compare.
+	CompareURL string
+	// FilesDir stores uploads for /api/files. Uploads are disabled when empty.
+	FilesDir string
+	// FileParts is how uploaded files referenced in a message reach the agent.
+	FileParts FilePartMode
+	// PublicURL is the address the agent can reach the BFF at, used for FilePartsURI. It
+	// defaults to the URL of the address the BFF listens on.
+	PublicURL string
+	// RPCTimeouts are deadlines for calls to the agent. A shorter client timeout wins.
+	RPCTimeouts RPCTimeouts
+	// CancelOnDisconnect sends CancelTask when a SendStreamingMessage client disconnects
+	// before its task finishes.
+	CancelOnDisconnect bool
+	// Retry applies to idempotent unary calls; Breaker fails calls fast while the agent is
+	// unreachable. Both are visible at /api/admin/resilience.
+	Retry   RetryPolicy
+	Breaker BreakerPolicy
+	// ForwardHeaders selects the agent response headers and trailers passed on to the
+	// browser. Responses also get Server-Timing entries for the BFF and the agent.
+	ForwardHeaders ResponseHeaderPolicy
+	// TLS serves HTTPS and HTTP/2 when enabled.
+	TLS TLSConfig
+	// Access authenticates users and guards against DNS rebinding and cross-site requests.
+	Access AccessConfig
+	// Faults are injected into A2A calls from the start; /api/admin/faults changes them.
+	Faults FaultConfig
+	// Interceptors run around every A2A call to the agent and every event it streams,
+	// first to last on requests and last to first on responses and events.
+	Interceptors []CallInterceptor
 }
 
 // Server represents the BFF HTTP server.
 type Server struct {
-	cfg    ServerConfig
-	server *http.Server
+	cfg      ServerConfig
+	server   *http.Server
+	listener net.Listener
+	access   *accessControl
+	bridge   *bridge
+	sessions *SessionStore
+	tasks    *TaskCache
+	comparer *TransportComparer
+	streams  *DurableStreams
+	broker   *SubscriptionBroker
 }
 
 // NewServer creates and configures the BFF server.
 func NewServer(ctx context.Context, cfg ServerConfig) (*Server, error) {
+	if cfg.Listen == "" {
+		cfg.Listen = DefaultListenAddr
+	}
+	basePath, err := NormalizeBasePath(cfg.BasePath)
+	if err != nil {
+		return nil, err
+	}
+	cfg.BasePath = basePath
+	s := &Server{cfg: cfg}
 	fsys, err := distFS(cfg.Dev, cfg.AppDir)
 	if err != nil {
 		return nil, fmt.Errorf("dist fs: %w", err)
 	}
+	fsys = newBasePathFS(fsys, cfg.BasePath)
+	access := cfg.Access
+	// Names the certificate is issued for are names the BFF answers to.
+	access.AllowedHosts = append(slices.Clone(access.AllowedHosts), cfg.TLS.Hosts...)
+	if s.access, err = newAccessControl(access, cfg.Listen, cfg.PublicURL); err != nil {
+		return nil, fmt.Errorf("access: %w", err)
+	}
+	s.access.basePath = cfg.BasePath
+	if err := cfg.TLS.validate(); err != nil {
+		return nil, fmt.Errorf("tls: %w", err)
+	}
+
+	if cfg.Protocol == ProtocolJSONRPC && cfg.AgentVersion == Version1 {
+		return nil, errors.New("A2A v1 is only supported for gRPC agents")
+	}
+	proxy := cfg.Proxy
+	if proxy == nil {
+		proxy = NewProxy(AgentConfig{URL: cfg.AgentURL, Protocol: cfg.Protocol, Version: cfg.AgentVersion, CardURL: cfg.AgentCardURL})
+	}
+	faults, err := NewFaultInjector(cfg.Faults)
+	if err != nil {
+		return nil, fmt.Errorf("faults: %w", err)
+	}
+
+	var sessions *SessionStore
+	if cfg.DataDir != "" {
+		// Another playground using the same data dir keeps its sessions; this one runs
+		// without, rather than not at all.
+		sessions, err = OpenSessionStore(cfg.DataDir)
+		if errors.Is(err, ErrStoreInUse) {
+			log.Printf("sessions are not recorded: %v (use another --data-dir to record them)", err)
+		} else if err != nil {
+			return nil, fmt.Errorf("session store: %w", err)
+		}
+	}
+	var tasks *TaskCache
+	if cfg.TasksDir != "" {
+		tasks, err = OpenTaskCache(cfg.TasksDir, cfg.TaskRetention)
+		if errors.Is(err, ErrStoreInUse) {
+			log.Printf("tasks are not cached: %v (use another --data-dir to cache them)", err)
+		} else if err != nil {
+			if sessions != nil {
+				_ = sessions.Close()
+			}
+			return nil, fmt.Errorf("task cache: %w", err)
+		}
+	}
 
-	var proxy A2AServiceHandler
-	if cfg.Protocol == ProtocolJSONRPC {
-		proxy = NewJSONRPCProxy(cfg.AgentURL)
-	} else {
-		proxy = NewGrpcProxy(cfg.AgentURL)
+	// Outermost, so Server-Timing counts the whole chain as BFF time.
+	interceptors := []connect.Interceptor{NewResponseMetadataInterceptor(cfg.ForwardHeaders)}
+	// Inside the response metadata, whose record of the agent's response it reads, and
+	// outside the rest, so every call to the agent activates the extensions.
+	interceptors = append(interceptors, NewExtensionsInterceptor(proxy, cfg.Extensions))
+	var broker *SubscriptionBroker
+	if cfg.ShareSubscriptions {
+		// Subscriptions to one task share a single upstream call. Snapshots are fetched
+		// through the bridge's in-process client, and so through this chain.
+		broker = NewSubscriptionBroker(func() a2apbconnect.A2AServiceClient { return s.bridge.client })
+		interceptors = append(interceptors, broker.Interceptor())
+	}
+	var streams *DurableStreams
+	if cfg.DurableStreams {
+		// Ahead of the session recorder, so it keeps recording after the browser leaves; see
+		// DurableStreams.Interceptor for what may run outside it.
+		streams = NewDurableStreams()
+		interceptors = append(interceptors, streams.Interceptor())
 	}
+	if cfg.CancelOnDisconnect {
+		interceptors = append(interceptors, NewCancelOnDisconnectInterceptor(proxy))
+	}
+	if sessions != nil {
+		interceptors = append(interceptors, NewSessionRecorder(sessions, cfg.AgentURL))
+	}
+	if tasks != nil {
+		interceptors = append(interceptors, NewTaskCacheRecorder(tasks, cfg.AgentURL))
+	}
+	var files *FileStore
+	if cfg.FilesDir != "" {
+		if files, err = OpenFileStore(cfg.FilesDir); err != nil {
+			if sessions != nil {
+				_ = sessions.Close()
+			}
+			if tasks != nil {
+				_ = tasks.Close()
+			}
+			return nil, fmt.Errorf("file store: %w", err)
+		}
+		// Inside the recorder, so sessions keep the reference rather than the file.
+		interceptors = append(interceptors, NewFileRefInterceptor(files, cfg.FileParts, s.PublicURL))
+	}
+	// The rest shape what reaches the agent and how its failures are handled, so transport
+	// comparisons go through them too.
+	agentInterceptors := func(resilience *Resilience) []connect.Interceptor {
+		var out []connect.Interceptor
+		if len(cfg.Interceptors) > 0 {
+			// Inside the recorders, so they keep what the interceptors let through.
+			out = append(out, NewCallInterceptors(cfg.Interceptors...))
+		}
+		// Injected latency counts against the deadline, and the recorder sees deadline
+		// errors.
+		out = append(out, NewDeadlineInterceptor(cfg.RPCTimeouts))
+		// Inside the deadline, so retries share it, and outside the faults, so injected
+		// errors are retried like real ones.
+		out = append(out, resilience.Interceptor())
+		// Innermost, so the rest of the BFF sees injected faults as the agent's behaviour.
+		return append(out, faults.Interceptor())
+	}
+	resilience := NewResilience(cfg.AgentURL, cfg.Retry, cfg.Breaker)
+	interceptors = append(interceptors, agentInterceptors(resilience)...)
 
-	a2aPath, a2aHandler := proxy.Handler()
+	var comparer *TransportComparer
+	if cfg.CompareURL != "" {
+		// The configured side is the BFF's own proxy, sharing its circuit breaker; the
+		// other is the same agent on the other transport.
+		other := AgentConfig{URL: cfg.AgentURL, Protocol: cfg.Protocol, CardURL: cfg.AgentCardURL}.OtherTransport(cfg.CompareURL)
+		otherProxy := NewProxy(other)
+		self := ComparedBinding{
+			URL:          cfg.AgentURL,
+			Proxy:        proxy,
+			Interceptors: append([]connect.Interceptor{NewExtensionsInterceptor(proxy, cfg.Extensions)}, agentInterceptors(resilience)...),
+		}
+		peer := ComparedBinding{
+			URL:          other.URL,
+			Proxy:        otherProxy,
+			Interceptors: append([]connect.Interceptor{NewExtensionsInterceptor(otherProxy, cfg.Extensions)}, agentInterceptors(NewResilience(other.URL, cfg.Retry, cfg.Breaker))...),
+		}
+		if files != nil {
+			// Uploaded-file references are resolved for both, as for the UI's calls.
+			ref := NewFileRefInterceptor(files, cfg.FileParts, s.PublicURL)
+			self.Interceptors = slices.Insert(self.Interceptors, 1, ref)
+			peer.Interceptors = slices.Insert(peer.Interceptors, 1, ref)
+		}
+		grpc, jsonrpc := self, peer
+		if cfg.Protocol == ProtocolJSONRPC {
+			grpc, jsonrpc = peer, self
+		}
+		if comparer, err = NewTransportComparer(grpc, jsonrpc); err != nil {
+			if sessions != nil {
+				_ = sessions.Close()
+			}
+			if tasks != nil {
+				_ = tasks.Close()
+			}
+			return nil, fmt.Errorf("transport comparer: %w", err)
+		}
+	}
 
-	// Middleware to inject X-A2A-Agent-Headers into request context for proxy forwarding
-	a2aWithHeaders := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		headers := ExtractAgentHeaders(r)
-		ctx := WithAgentHeaders(r.Context(), headers)
-		a2aHandler.ServeHTTP(w, r.WithContext(ctx))
-	})
+	a2aPath, a2aHandler := proxy.Handler(connect.WithInterceptors(interceptors...))
 
 	mux := mux.NewRouter()
-	mux.PathPrefix(a2aPath).Handler(a2aWithHeaders)
+	a2aRoute := AgentHeadersMiddleware(faults.Middleware(a2aHandler))
+	// The other A2A bindings, and v1 clients, are bridged onto the same route, so they
+	// share its pipeline.
+	s.bridge = newBridge(a2aPath, a2aRoute, cfg.PublicURL, cfg.BasePath)
+	mux.PathPrefix(a2aPath).Handler(routeVersions(a2aRoute, newV1Handler(s.bridge)))
+	registerBridgeRoutes(mux, s.bridge)
+	registerAgentCardRoutes(mux, proxy)
+	if sessions != nil {
+		registerSessionRoutes(mux, sessions)
+	}
+	if tasks != nil {
+		registerTaskCacheRoutes(mux, tasks, cfg.AgentURL)
+	}
+	if streams != nil {
+		registerStreamRoutes(mux, streams)
+	}
+	if comparer != nil {
+		registerCompareRoutes(mux, comparer)
+	}
+	registerFaultRoutes(mux, faults)
+	registerResilienceRoutes(mux, resilience)
+	registerFileRoutes(mux, files, proxy, newSafeHTTPClient(dialAddr(cfg.AgentURL)), s.PublicURL)
 	mux.PathPrefix("/").Handler(SPAHandler(fsys))
 
-	addr := fmt.Sprintf(":%d", cfg.Port)
-	srv := &http.Server{
-		Addr:    addr,
-		Handler: mux,
+	// HTTP/2 without TLS (h2c with prior knowledge) lets gRPC clients use the BFF as an
+	// A2A gateway; browsers keep using HTTP/1.1, or HTTP/2 over HTTPS.
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+	s.server = &http.Server{
+		Addr:      cfg.Listen,
+		Handler:   s.access.Handler(stripBasePath(cfg.BasePath, mux)),
+		Protocols: protocols,
 	}
+	s.sessions = sessions
+	s.tasks = tasks
+	s.comparer = comparer
+	s.streams = streams
+	s.broker = broker
+	return s, nil
+}
 
-	return &Server{
-		cfg:    cfg,
-		server: srv,
-	}, nil
+// Handler returns the server's HTTP handler, to serve the playground on another server
+// instead of calling Start. With a BasePath, it serves the paths under it.
+func (s *Server) Handler() http.Handler {
+	return s.server.Handler
 }
 
-// Addr returns the server address.
+// Addr returns the address the server listens on, such as "127.0.0.1:41923" or a socket
+// path. Before Start it is the configured address, whose port may still be 0.
 func (s *Server) Addr() string {
-	return s.server.Addr
+	if s.listener == nil {
+		return s.cfg.Listen
+	}
+	return s.listener.Addr().String()
+}
+
+// URL returns the http or https URL to open the playground at, or "" when it listens on
+// a unix socket or has not started.
+func (s *Server) URL() string {
+	if s.listener == nil {
+		return ""
+	}
+	scheme := "http"
+	if s.cfg.TLS.enabled() {
+		scheme = "https"
+	}
+	if u := httpURL(scheme, s.listener.Addr()); u != "" {
+		return u + s.cfg.BasePath
+	}
+	return ""
 }
 
-// Start starts the server in a goroutine.
+// GatewayTarget returns the gRPC target at which A2A gRPC clients reach the agent
+// through the BFF, such as "localhost:3000" or "unix:/tmp/pg.sock", or "" before Start
+// or under a BasePath, which gRPC clients cannot add. Without TLS, clients must use
+// plaintext HTTP/2.
+func (s *Server) GatewayTarget() string {
+	if s.listener == nil || s.cfg.BasePath != "" {
+		return ""
+	}
+	addr := s.listener.Addr()
+	if addr.Network() == "unix" {
+		return unixSocketPrefix + addr.String()
+	}
+	return strings.TrimPrefix(httpURL("http", addr), "http://")
+}
+
+// LoginURL returns URL with the access token when token auth is on. Opening it signs the
+// browser in.
+func (s *Server) LoginURL() string {
+	u := s.URL()
+	if token := s.access.Token(); token != "" && u != "" {
+		u += "/?" + tokenParam + "=" + token
+	}
+	return u
+}
+
+// AuthToken returns the token for "Authorization: Bearer", or "" unless token auth is on.
+func (s *Server) AuthToken() string {
+	return s.access.Token()
+}
+
+// PublicURL returns the configured PublicURL, or else the server's URL.
+func (s *Server) PublicURL() string {
+	if s.cfg.PublicURL != "" {
+		return s.cfg.PublicURL
+	}
+	return s.URL()
+}
+
+// Start loads or issues the TLS certificate and binds the listen address, returning any
+// error, and then serves in a goroutine. HTTP/2 is served over HTTPS and, for gRPC
+// clients, over plain HTTP.
 func (s *Server) Start() error {
+	if s.cfg.TLS.enabled() {
+		cert, err := s.cfg.TLS.loadCertificate(s.access.certHosts())
+		if err != nil {
+			return fmt.Errorf("tls certificate: %w", err)
+		}
+		s.server.TLSConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+			NextProtos:   []string{"h2", "http/1.1"},
+		}
+	}
+	ln, err := listen(s.cfg.Listen)
+	if err != nil {
+		return err
+	}
+	s.listener = ln
 	go func() {
-		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			panic(err)
+		var err error
+		if s.server.TLSConfig != nil {
+			err = s.server.ServeTLS(ln, "", "")
+		} else {
+			err = s.server.Serve(ln)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			log.Printf("serve on %s: %v", ln.Addr(), err)
 		}
 	}()
 	return nil
 }
 
-// Shutdown gracefully shuts down the server.
+// Shutdown gracefully shuts down the server, and closes its stores. A server that was
+// only used through Handler is shut down the same way.
 func (s *Server) Shutdown(ctx context.Context) error {
 	shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
-	return s.server.Shutdown(shutdownCtx)
+	err := s.server.Shutdown(shutdownCtx)
+	err = errors.Join(err, s.bridge.Close())
+	// Detached upstream calls still record into the stores: stop them before closing those.
+	if s.streams != nil {
+		err = errors.Join(err, s.streams.Close())
+	}
+	if s.broker != nil {
+		err = errors.Join(err, s.broker.Close())
+	}
+	if s.sessions != nil {
+		err = errors.Join(err, s.sessions.Close())
+	}
+	if s.tasks != nil {
+		err = errors.Join(err, s.tasks.Close())
+	}
+	if s.comparer != nil {
+		err = errors.Join(err, s.comparer.Close())
+	}
+	return err
 }
@@ -0,0 +1,397 @@
+package bff
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a2aproject/a2a-go/a2apb"
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ErrSessionNotFound is returned when no session exists for a contextId.
+var ErrSessionNotFound = errors.New("session not found")
+
+var (
+	sessionsBucket = []byte("sessions")
+	recordsBucket  = []byte("records")
+)
+
+// RecordKind identifies what a SessionRecord holds.
+type RecordKind string
+
+const (
+	// RecordKindMessage is a user message sent to the agent (a2a.v1.Message).
+	RecordKindMessage RecordKind = "message"
+	// RecordKindResponse is a unary SendMessage response (a2a.v1.SendMessageResponse).
+	RecordKindResponse RecordKind = "response"
+	// RecordKindEvent is a streamed event (a2a.v1.StreamResponse).
+	RecordKindEvent RecordKind = "event"
+	// RecordKindTask is a task snapshot from GetTask or CancelTask (a2a.v1.Task).
+	RecordKindTask RecordKind = "task"
+	// RecordKindCall closes an RPC with its request, headers, timing and status (CallInfo).
+	RecordKindCall RecordKind = "call"
+)
+
+// Session is the summary of a recorded conversation.
+type Session struct {
+	ContextID string    `json:"contextId"`
+	Title     string    `json:"title"`
+	AgentURL  string    `json:"agentUrl,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Records   int       `json:"records"`
+}
+
+// SessionRecord is one recorded message, event or task snapshot. Data is the protojson
+// encoding of the proto named by Kind, so the UI can decode it like a Connect response.
+// Records from the same RPC share a CallID, and the RPC's CallInfo is recorded last.
+type SessionRecord struct {
+	Seq       uint64          `json:"seq"`
+	Time      time.Time       `json:"time"`
+	Kind      RecordKind      `json:"kind"`
+	Procedure string          `json:"procedure,omitempty"`
+	CallID    string          `json:"callId,omitempty"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// CallInfo describes one proxied RPC. Secret header values are redacted before recording.
+type CallInfo struct {
+	Procedure       string              `json:"procedure"`
+	Protocol        string              `json:"protocol"`
+	StartedAt       time.Time           `json:"startedAt"`
+	DurationMs      float64             `json:"durationMs"`
+	FirstEventMs    float64             `json:"firstEventMs,omitempty"`
+	Events          int                 `json:"events,omitempty"`
+	Code            string              `json:"code"`
+	Error           string              `json:"error,omitempty"`
+	Request         json.RawMessage     `json:"request,omitempty"`
+	RequestHeaders  map[string][]string `json:"requestHeaders,omitempty"`
+	ResponseHeaders map[string][]string `json:"responseHeaders,omitempty"`
+}
+
+// SessionAppend is a record to add to the session for ContextID, with the agent URL and
+// title the session takes if it has none yet.
+type SessionAppend struct {
+	ContextID string
+	AgentURL  string
+	Title     string
+	Record    SessionRecord
+}
+
+// SessionStore persists conversations keyed by contextId in a local bbolt database.
+type SessionStore struct {
+	db *bolt.DB
+}
+
+// ErrStoreInUse is returned when another playground has a database open.
+var ErrStoreInUse = errors.New("database is in use by another playground")
+
+// OpenSessionStore opens (or creates) the session database in dir.
+func OpenSessionStore(dir string) (*SessionStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create data dir: %w", err)
+	}
+	db, err := openDB(filepath.Join(dir, "sessions.db"), false)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(sessionsBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(recordsBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &SessionStore{db: db}, nil
+}
+
+// OpenSessionStoreReadOnly opens the session database in dir for reading, alongside
+// other readers. It creates nothing, and fails if there is no database.
+func OpenSessionStoreReadOnly(dir string) (*SessionStore, error) {
+	db, err := openDB(filepath.Join(dir, "sessions.db"), true)
+	if err != nil {
+		return nil, err
+	}
+	return &SessionStore{db: db}, nil
+}
+
+// openDB opens the bbolt database at path. bbolt locks the file, exclusively for writers
+// and shared for readers; a database locked by another process fails with ErrStoreInUse.
+func openDB(path string, readOnly bool) (*bolt.DB, error) {
+	if readOnly {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("open %s: %w", path, os.ErrNotExist)
+		}
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second, ReadOnly: readOnly})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("open %s: %w", path, ErrStoreInUse)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	return db, nil
+}
+
+// Close closes the database.
+func (s *SessionStore) Close() error {
+	return s.db.Close()
+}
+
+// Append adds rec to the session for contextID, creating the session if needed.
+// title and agentURL are only used when the session has no title or agent yet.
+func (s *SessionStore) Append(contextID, agentURL, title string, rec SessionRecord) error {
+	return s.AppendAll([]SessionAppend{{ContextID: contextID, AgentURL: agentURL, Title: title, Record: rec}})
+}
+
+// AppendAll adds records in order in a single transaction, so a batch costs one sync of
+// the database file.
+func (s *SessionStore) AppendAll(appends []SessionAppend) error {
+	for _, a := range appends {
+		if a.ContextID == "" {
+			return errors.New("append session record: empty contextId")
+		}
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, a := range appends {
+			if err := appendRecord(tx, a); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func appendRecord(tx *bolt.Tx, a SessionAppend) error {
+	sessions := tx.Bucket(sessionsBucket)
+	var sess Session
+	if raw := sessions.Get([]byte(a.ContextID)); raw != nil {
+		if err := json.Unmarshal(raw, &sess); err != nil {
+			return err
+		}
+	} else {
+		sess = Session{ContextID: a.ContextID, CreatedAt: a.Record.Time}
+	}
+	if sess.Title == "" {
+		sess.Title = a.Title
+	}
+	if sess.AgentURL == "" {
+		sess.AgentURL = a.AgentURL
+	}
+
+	records, err := tx.Bucket(recordsBucket).CreateBucketIfNotExists([]byte(a.ContextID))
+	if err != nil {
+		return err
+	}
+	seq, err := records.NextSequence()
+	if err != nil {
+		return err
+	}
+	rec := a.Record
+	rec.Seq = seq
+	raw, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if err := records.Put(seqKey(seq), raw); err != nil {
+		return err
+	}
+
+	sess.Records++
+	sess.UpdatedAt = rec.Time
+	return putSession(sessions, &sess)
+}
+
+// List returns sessions, most recently updated first. When query is non-empty, only
+// sessions whose title or recorded text contains it (case-insensitive) are returned; see
+// recordText.
+func (s *SessionStore) List(query string) ([]Session, error) {
+	q := strings.ToLower(query)
+	var out []Session
+	err := s.db.View(func(tx *bolt.Tx) error {
+		records := tx.Bucket(recordsBucket)
+		return tx.Bucket(sessionsBucket).ForEach(func(k, v []byte) error {
+			var sess Session
+			if err := json.Unmarshal(v, &sess); err != nil {
+				return err
+			}
+			if q != "" && !strings.Contains(strings.ToLower(sess.Title), q) &&
+				!recordsContain(records.Bucket(k), q) {
+				return nil
+			}
+			out = append(out, sess)
+			return nil
+		})
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].UpdatedAt.After(out[j].UpdatedAt) })
+	return out, err
+}
+
+// Get returns a session and its records in the order they were recorded.
+func (s *SessionStore) Get(contextID string) (*Session, []SessionRecord, error) {
+	var sess Session
+	var recs []SessionRecord
+	err := s.db.View(func(tx *bolt.Tx) error {
+		raw := tx.Bucket(sessionsBucket).Get([]byte(contextID))
+		if raw == nil {
+			return ErrSessionNotFound
+		}
+		if err := json.Unmarshal(raw, &sess); err != nil {
+			return err
+		}
+		records := tx.Bucket(recordsBucket).Bucket([]byte(contextID))
+		if records == nil {
+			return nil
+		}
+		return records.ForEach(func(_, v []byte) error {
+			var rec SessionRecord
+			if err := json.Unmarshal(v, &rec); err != nil {
+				return err
+			}
+			recs = append(recs, rec)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &sess, recs, nil
+}
+
+// Rename sets the title of a session.
+func (s *SessionStore) Rename(contextID, title string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		sessions := tx.Bucket(sessionsBucket)
+		raw := sessions.Get([]byte(contextID))
+		if raw == nil {
+			return ErrSessionNotFound
+		}
+		var sess Session
+		if err := json.Unmarshal(raw, &sess); err != nil {
+			return err
+		}
+		sess.Title = title
+		return putSession(sessions, &sess)
+	})
+}
+
+// Delete removes a session and all of its records.
+func (s *SessionStore) Delete(contextID string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		sessions := tx.Bucket(sessionsBucket)
+		if sessions.Get([]byte(contextID)) == nil {
+			return ErrSessionNotFound
+		}
+		if err := sessions.Delete([]byte(contextID)); err != nil {
+			return err
+		}
+		err := tx.Bucket(recordsBucket).DeleteBucket([]byte(contextID))
+		if errors.Is(err, bolt.ErrBucketNotFound) {
+			return nil
+		}
+		return err
+	})
+}
+
+func putSession(b *bolt.Bucket, sess *Session) error {
+	raw, err := json.Marshal(sess)
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(sess.ContextID), raw)
+}
+
+// recordsContain reports whether the text of any record in b contains the lower-case
+// query.
+func recordsContain(b *bolt.Bucket, q string) bool {
+	if b == nil {
+		return false
+	}
+	c := b.Cursor()
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		var rec SessionRecord
+		if json.Unmarshal(v, &rec) == nil && strings.Contains(recordText(rec), q) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordText returns the lower-case text of a recorded message, response, event or task:
+// its parts, artifact names and descriptions, and metadata values. Field names, enums and
+// IDs are left out, so searching for "role" does not match every session. Call records
+// have no text.
+func recordText(rec SessionRecord) string {
+	var msg proto.Message
+	switch rec.Kind {
+	case RecordKindMessage:
+		msg = &a2apb.Message{}
+	case RecordKindResponse:
+		msg = &a2apb.SendMessageResponse{}
+	case RecordKindEvent:
+		msg = &a2apb.StreamResponse{}
+	case RecordKindTask:
+		msg = &a2apb.Task{}
+	default:
+		return ""
+	}
+	if protojson.Unmarshal(rec.Data, msg) != nil {
+		return ""
+	}
+	var sb strings.Builder
+	walkMessages(msg.ProtoReflect(), func(m protoreflect.Message) {
+		switch x := m.Interface().(type) {
+		case *a2apb.Part:
+			sb.WriteString(partText(x) + "\n")
+		case *a2apb.Artifact:
+			sb.WriteString(x.GetName() + "\n" + x.GetDescription() + "\n")
+		}
+		if fd := m.Descriptor().Fields().ByName("metadata"); fd != nil && m.Has(fd) {
+			if md, ok := m.Get(fd).Message().Interface().(*structpb.Struct); ok {
+				writeValues(&sb, structpb.NewStructValue(md))
+			}
+		}
+	})
+	return strings.ToLower(sb.String())
+}
+
+// writeValues writes the strings and numbers in v, one per line.
+func writeValues(sb *strings.Builder, v *structpb.Value) {
+	switch k := v.GetKind().(type) {
+	case *structpb.Value_StringValue:
+		sb.WriteString(k.StringValue + "\n")
+	case *structpb.Value_NumberValue:
+		sb.WriteString(strconv.FormatFloat(k.NumberValue, 'g', -1, 64) + "\n")
+	case *structpb.Value_ListValue:
+		for _, e := range k.ListValue.GetValues() {
+			writeValues(sb, e)
+		}
+	case *structpb.Value_StructValue:
+		for _, e := range k.StructValue.GetFields() {
+			writeValues(sb, e)
+		}
+	}
+}
+
+// seqKey encodes seq big-endian so records iterate in insertion order.
+func seqKey(seq uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, seq)
+	return b
+}
@@ -0,0 +1,125 @@
+package bff
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"connectrpc.com/connect"
+	"github.com/gorilla/mux"
+)
+
+// sessionsAPIPath is the prefix of the session REST API.
+const sessionsAPIPath = "/api/sessions"
+
+// sessionDetail is the restore payload: the session and every record in order.
+type sessionDetail struct {
+	Session *Session        `json:"session"`
+	Records []SessionRecord `json:"records"`
+}
+
+// registerSessionRoutes mounts the session API on r:
+//
+//	GET    /api/sessions?q=text     list sessions, optionally filtered by text
+//	GET    /api/sessions/{id}       session with all records, for restoring into the UI
+//	PATCH  /api/sessions/{id}       rename: {"title": "..."}
+//	DELETE /api/sessions/{id}       delete the session and its records
+//	GET    /api/sessions/{id}/export?format=json|markdown|har
+//	                                download the session with secrets redacted
+func registerSessionRoutes(r *mux.Router, store *SessionStore) {
+	r.HandleFunc(sessionsAPIPath, func(w http.ResponseWriter, req *http.Request) {
+		sessions, err := store.List(req.URL.Query().Get("q"))
+		if err != nil {
+			writeJSONError(w, err)
+			return
+		}
+		if sessions == nil {
+			sessions = []Session{}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"sessions": sessions})
+	}).Methods(http.MethodGet)
+
+	r.HandleFunc(sessionsAPIPath+"/{contextId}", func(w http.ResponseWriter, req *http.Request) {
+		sess, recs, err := store.Get(mux.Vars(req)["contextId"])
+		if err != nil {
+			writeJSONError(w, err)
+			return
+		}
+		if recs == nil {
+			recs = []SessionRecord{}
+		}
+		writeJSON(w, http.StatusOK, sessionDetail{Session: sess, Records: recs})
+	}).Methods(http.MethodGet)
+
+	r.HandleFunc(sessionsAPIPath+"/{contextId}", func(w http.ResponseWriter, req *http.Request) {
+		var body struct {
+			Title string `json:"title"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil || strings.TrimSpace(body.Title) == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "body must be {\"title\": \"...\"}"})
+			return
+		}
+		contextID := mux.Vars(req)["contextId"]
+		if err := store.Rename(contextID, strings.TrimSpace(body.Title)); err != nil {
+			writeJSONError(w, err)
+			return
+		}
+		sess, _, err := store.Get(contextID)
+		if err != nil {
+			writeJSONError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, sess)
+	}).Methods(http.MethodPatch)
+
+	r.HandleFunc(sessionsAPIPath+"/{contextId}", func(w http.ResponseWriter, req *http.Request) {
+		if err := store.Delete(mux.Vars(req)["contextId"]); err != nil {
+			writeJSONError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods(http.MethodDelete)
+
+	r.HandleFunc(sessionsAPIPath+"/{contextId}/export", func(w http.ResponseWriter, req *http.Request) {
+		format, err := ParseExportFormat(req.URL.Query().Get("format"))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		sess, recs, err := store.Get(mux.Vars(req)["contextId"])
+		if err != nil {
+			writeJSONError(w, err)
+			return
+		}
+		data, err := ExportSession(sess, recs, format)
+		if err != nil {
+			writeJSONError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", format.Filename(sess.ContextID)))
+		_, _ = w.Write(data)
+	}).Methods(http.MethodGet)
+}
+
+// writeJSON writes v as a JSON response with the given status.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeJSONError maps store and agent errors to HTTP status codes.
+func writeJSONError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var cerr *connect.Error
+	switch {
+	case errors.Is(err, ErrSessionNotFound), errors.Is(err, ErrFileNotFound), errors.Is(err, ErrTaskNotFound):
+		status = http.StatusNotFound
+	case errors.As(err, &cerr):
+		status = harStatus(cerr.Code().String(), false)
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,289 @@
+package bff
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/a2aproject/a2a-go/a2apb"
+	"github.com/alis-exchange/a2a-playground/gen/go/a2apbconnect"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// sessionFlushInterval is how long a call's records may wait to be written together.
+const sessionFlushInterval = 500 * time.Millisecond
+
+// sessionRecorder is a Connect interceptor that records proxied traffic into a SessionStore.
+type sessionRecorder struct {
+	store    *SessionStore
+	agentURL string
+}
+
+// NewSessionRecorder returns an interceptor that records user messages, stream events and
+// task snapshots passing through the proxy, keyed by contextId.
+func NewSessionRecorder(store *SessionStore, agentURL string) connect.Interceptor {
+	return &sessionRecorder{store: store, agentURL: agentURL}
+}
+
+// WrapUnary records SendMessage requests and responses and GetTask/CancelTask snapshots.
+func (r *sessionRecorder) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		procedure := req.Spec().Procedure
+		call := r.newCall(procedure, req.Peer().Protocol, req.Header(), req.Any())
+		resp, err := next(ctx, req)
+
+		var contextID string
+		switch procedure {
+		case a2apbconnect.A2AServiceSendMessageProcedure:
+			in, _ := req.Any().(*a2apb.SendMessageRequest)
+			contextID = in.GetRequest().GetContextId()
+			var out *a2apb.SendMessageResponse
+			if err == nil {
+				out, _ = resp.Any().(*a2apb.SendMessageResponse)
+				if contextID == "" {
+					contextID = sendResponseContextID(out)
+				}
+			}
+			r.record(contextID, call, RecordKindMessage, in.GetRequest())
+			if out != nil {
+				r.record(contextID, call, RecordKindResponse, out)
+			}
+		case a2apbconnect.A2AServiceGetTaskProcedure, a2apbconnect.A2AServiceCancelTaskProcedure:
+			if err == nil {
+				task, _ := resp.Any().(*a2apb.Task)
+				contextID = task.GetContextId()
+				r.record(contextID, call, RecordKindTask, task)
+			}
+		default:
+			return resp, err
+		}
+
+		var respHeader map[string][]string
+		if err == nil {
+			respHeader = redactHeaders(resp.Header())
+		}
+		r.recordCall(contextID, call, respHeader, err)
+		return resp, err
+	}
+}
+
+// WrapStreamingClient is a no-op; the recorder only wraps handlers.
+func (r *sessionRecorder) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler records the request message and every event of message and task streams.
+func (r *sessionRecorder) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		switch conn.Spec().Procedure {
+		case a2apbconnect.A2AServiceSendStreamingMessageProcedure, a2apbconnect.A2AServiceTaskSubscriptionProcedure:
+			rc := &recordingConn{
+				StreamingHandlerConn: conn,
+				recorder:             r,
+				call:                 r.newCall(conn.Spec().Procedure, conn.Peer().Protocol, conn.RequestHeader(), nil),
+			}
+			err := next(ctx, rc)
+			r.recordCall(rc.contextID, rc.call, redactHeaders(conn.ResponseHeader()), err)
+			return err
+		}
+		return next(ctx, conn)
+	}
+}
+
+// record stores msg; failures are logged so recording never breaks the proxied call.
+func (r *sessionRecorder) record(contextID string, call *recordedCall, kind RecordKind, msg proto.Message) {
+	if contextID == "" || msg == nil || !msg.ProtoReflect().IsValid() {
+		return
+	}
+	data, err := protojson.Marshal(msg)
+	if err != nil {
+		log.Printf("session store: marshal %s: %v", kind, err)
+		return
+	}
+	var title string
+	if m, ok := msg.(*a2apb.Message); ok && kind == RecordKindMessage {
+		title = sessionTitle(m)
+	}
+	r.append(call, contextID, title, SessionRecord{Kind: kind, Procedure: call.info.Procedure, CallID: call.id, Data: data})
+}
+
+// recordCall stores the CallInfo that closes an RPC, and writes the call's records.
+func (r *sessionRecorder) recordCall(contextID string, call *recordedCall, respHeader map[string][]string, err error) {
+	defer call.flush()
+	if contextID == "" {
+		return
+	}
+	info := call.info
+	info.DurationMs = millis(time.Since(info.StartedAt))
+	info.ResponseHeaders = respHeader
+	info.Code = "ok"
+	if err != nil {
+		info.Code = connect.CodeOf(err).String()
+		info.Error = err.Error()
+	}
+	data, merr := json.Marshal(info)
+	if merr != nil {
+		log.Printf("session store: marshal call: %v", merr)
+		return
+	}
+	r.append(call, contextID, "", SessionRecord{Kind: RecordKindCall, Procedure: info.Procedure, CallID: call.id, Data: data})
+}
+
+func (r *sessionRecorder) append(call *recordedCall, contextID, title string, rec SessionRecord) {
+	rec.Time = time.Now().UTC()
+	call.add(SessionAppend{ContextID: contextID, AgentURL: r.agentURL, Title: title, Record: rec})
+}
+
+// recordedCall tracks one RPC while it is in flight. Its records are written together
+// when it ends, and at most sessionFlushInterval apart before that, so a busy stream does
+// not sync the database for every event.
+type recordedCall struct {
+	id    string
+	info  CallInfo
+	store *SessionStore
+
+	mu      sync.Mutex
+	pending []SessionAppend
+	timer   *time.Timer
+}
+
+func (r *sessionRecorder) newCall(procedure, protocol string, header map[string][]string, req any) *recordedCall {
+	call := &recordedCall{
+		id:    uuid.NewString(),
+		store: r.store,
+		info: CallInfo{
+			Procedure:      procedure,
+			Protocol:       protocol,
+			StartedAt:      time.Now().UTC(),
+			RequestHeaders: redactHeaders(header),
+		},
+	}
+	call.setRequest(req)
+	return call
+}
+
+// add queues a record, scheduling a flush if none is due.
+func (c *recordedCall) add(a SessionAppend) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending = append(c.pending, a)
+	if c.timer == nil {
+		c.timer = time.AfterFunc(sessionFlushInterval, c.flush)
+	}
+}
+
+// flush writes the queued records. The lock is held while writing so batches land in
+// order; failures are logged so recording never breaks the proxied call.
+func (c *recordedCall) flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if len(c.pending) == 0 {
+		return
+	}
+	if err := c.store.AppendAll(c.pending); err != nil {
+		log.Printf("session store: %v", err)
+	}
+	c.pending = nil
+}
+
+// setRequest records the request body with secret fields redacted.
+func (c *recordedCall) setRequest(req any) {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return
+	}
+	if data, err := protojson.Marshal(msg); err == nil {
+		c.info.Request = redactJSON(data)
+	}
+}
+
+// recordingConn records a streaming call. The user message is held until the first event
+// reveals the contextId assigned by the agent.
+type recordingConn struct {
+	connect.StreamingHandlerConn
+	recorder  *sessionRecorder
+	call      *recordedCall
+	contextID string
+	pending   *a2apb.Message
+}
+
+func (c *recordingConn) Receive(msg any) error {
+	if err := c.StreamingHandlerConn.Receive(msg); err != nil {
+		return err
+	}
+	c.call.setRequest(msg)
+	if req, ok := msg.(*a2apb.SendMessageRequest); ok {
+		c.pending = req.GetRequest()
+		c.contextID = c.pending.GetContextId()
+	}
+	return nil
+}
+
+func (c *recordingConn) Send(msg any) error {
+	if ev, ok := msg.(*a2apb.StreamResponse); ok {
+		if c.call.info.Events == 0 {
+			c.call.info.FirstEventMs = millis(time.Since(c.call.info.StartedAt))
+		}
+		c.call.info.Events++
+		if c.contextID == "" {
+			c.contextID = streamContextID(ev)
+		}
+		if c.pending != nil && c.contextID != "" {
+			c.recorder.record(c.contextID, c.call, RecordKindMessage, c.pending)
+			c.pending = nil
+		}
+		c.recorder.record(c.contextID, c.call, RecordKindEvent, ev)
+	}
+	return c.StreamingHandlerConn.Send(msg)
+}
+
+// streamContextID returns the contextId carried by a stream event.
+func streamContextID(ev *a2apb.StreamResponse) string {
+	switch p := ev.GetPayload().(type) {
+	case *a2apb.StreamResponse_Task:
+		return p.Task.GetContextId()
+	case *a2apb.StreamResponse_Msg:
+		return p.Msg.GetContextId()
+	case *a2apb.StreamResponse_StatusUpdate:
+		return p.StatusUpdate.GetContextId()
+	case *a2apb.StreamResponse_ArtifactUpdate:
+		return p.ArtifactUpdate.GetContextId()
+	}
+	return ""
+}
+
+// sendResponseContextID returns the contextId of a unary SendMessage response.
+func sendResponseContextID(resp *a2apb.SendMessageResponse) string {
+	if t := resp.GetTask(); t != nil {
+		return t.GetContextId()
+	}
+	return resp.GetMsg().GetContextId()
+}
+
+// sessionTitle derives a title from the first text part of a user message.
+func sessionTitle(msg *a2apb.Message) string {
+	for _, p := range msg.GetParts() {
+		if t := strings.Join(strings.Fields(p.GetText()), " "); t != "" {
+			if r := []rune(t); len(r) > 60 {
+				t = string(r[:57]) + "..."
+			}
+			return t
+		}
+	}
+	return ""
+}
+
+// millis converts d to fractional milliseconds.
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
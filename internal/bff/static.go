@@ -31,6 +31,7 @@ func distFS(dev bool, rootDir string) (fs.FS, error) {
 func SPAHandler(fsys fs.FS) http.Handler {
 	fileServer := http.FileServer(http.FS(fsys))
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		setSecurityHeaders(w.Header())
 		path := r.URL.Path
 		if path == "/" {
 			path = "/index.html"
@@ -51,3 +52,18 @@ func hasExtension(path string) bool {
 	base := filepath.Base(path)
 	return strings.Contains(base, ".")
 }
+
+// contentSecurityPolicy allows the SPA's own scripts, Vuetify's injected styles and images
+// from anywhere, as agents return image URLs. It forbids framing the playground.
+const contentSecurityPolicy = "default-src 'self'; script-src 'self'; style-src 'self' 'unsafe-inline'; " +
+	"img-src * data: blob:; media-src * data: blob:; font-src 'self' data:; connect-src 'self'; " +
+	"object-src 'none'; base-uri 'self'; form-action 'self'; frame-ancestors 'none'"
+
+// setSecurityHeaders sets the headers that keep the SPA from being framed or sniffed, and
+// keep its URLs out of Referer headers sent to other sites.
+func setSecurityHeaders(h http.Header) {
+	h.Set("Content-Security-Policy", contentSecurityPolicy)
+	h.Set("X-Frame-Options", "DENY")
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Referrer-Policy", "same-origin")
+}
@@ -0,0 +1,219 @@
+package bff
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"connectrpc.com/connect"
+	"github.com/a2aproject/a2a-go/a2apb"
+	"github.com/alis-exchange/a2a-playground/gen/go/a2apbconnect"
+	"google.golang.org/protobuf/proto"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind before it is dropped.
+const subscriberBuffer = 256
+
+// SubscriptionBroker shares TaskSubscription calls between browser clients. Subscribers
+// to the same task with the same agent headers get one upstream subscription. Every
+// subscriber first receives the current task: fetched with GetTask when the upstream
+// subscription starts, and kept up to date from its events for clients joining late. The
+// upstream subscription is cancelled when its last subscriber leaves.
+type SubscriptionBroker struct {
+	client func() a2apbconnect.A2AServiceClient
+
+	mu   sync.Mutex
+	hubs map[string]*taskHub
+	// running counts the upstream subscriptions, so Close can wait for them.
+	running sync.WaitGroup
+}
+
+// NewSubscriptionBroker returns a broker that fetches task snapshots with the client
+// returns. The client should call the BFF's own handler, so the fetch gets the deadline,
+// retries and interceptors of any other GetTask; it is looked up when a subscription
+// starts, as the handler is built after the broker.
+func NewSubscriptionBroker(client func() a2apbconnect.A2AServiceClient) *SubscriptionBroker {
+	return &SubscriptionBroker{client: client, hubs: map[string]*taskHub{}}
+}
+
+// Close cancels every upstream subscription and waits for them to return.
+func (b *SubscriptionBroker) Close() error {
+	b.mu.Lock()
+	for _, h := range b.hubs {
+		h.cancel()
+	}
+	b.mu.Unlock()
+	b.running.Wait()
+	return nil
+}
+
+// Interceptor returns the Connect interceptor that routes TaskSubscription through the
+// broker. It should run outside the session recorder so a shared subscription is
+// recorded once.
+func (b *SubscriptionBroker) Interceptor() connect.Interceptor {
+	return brokerInterceptor{b}
+}
+
+// join adds a subscriber for key, starting the upstream subscription if there is none.
+func (b *SubscriptionBroker) join(ctx context.Context, key string, conn connect.StreamingHandlerConn, req *a2apb.TaskSubscriptionRequest, next connect.StreamingHandlerFunc) (*taskHub, *subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if h := b.hubs[key]; h != nil {
+		if sub := h.subscribe(); sub != nil {
+			return h, sub
+		}
+	}
+
+	upstreamCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	h := &taskHub{cancel: cancel, subs: map[*subscriber]struct{}{}}
+	sub := h.subscribe()
+	b.hubs[key] = h
+	b.running.Add(1)
+	dc := newDetachedConn(conn, req, func(ev *a2apb.StreamResponse) error {
+		h.broadcast(ev)
+		return nil
+	})
+	go func() {
+		defer b.running.Done()
+		defer cancel()
+		// Best effort: if the task cannot be fetched, the subscription reports why. The
+		// fetch carries the subscriber's agent headers and extensions.
+		getCtx := context.WithValue(upstreamCtx, bridgeCallKey{}, &bridgeCall{header: conn.RequestHeader()})
+		if resp, err := b.client().GetTask(getCtx, connect.NewRequest(&a2apb.GetTaskRequest{Name: req.GetName()})); err == nil {
+			h.broadcast(&a2apb.StreamResponse{Payload: &a2apb.StreamResponse_Task{Task: resp.Msg}})
+		}
+		h.finish(next(upstreamCtx, dc))
+		b.mu.Lock()
+		if b.hubs[key] == h {
+			delete(b.hubs, key)
+		}
+		b.mu.Unlock()
+	}()
+	return h, sub
+}
+
+// taskHub is one upstream subscription and its local subscribers.
+type taskHub struct {
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	subs     map[*subscriber]struct{}
+	snapshot *a2apb.Task
+	closing  bool // finished, or cancelled after its last subscriber left
+}
+
+// subscriber receives a hub's events. err is set before events is closed.
+type subscriber struct {
+	events chan *a2apb.StreamResponse
+	err    error
+}
+
+// subscribe adds a subscriber, queueing the current snapshot first. It returns nil when
+// the hub is shutting down and a new upstream subscription is needed.
+func (h *taskHub) subscribe() *subscriber {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closing {
+		return nil
+	}
+	sub := &subscriber{events: make(chan *a2apb.StreamResponse, subscriberBuffer)}
+	if h.snapshot != nil {
+		sub.events <- &a2apb.StreamResponse{Payload: &a2apb.StreamResponse_Task{Task: proto.Clone(h.snapshot).(*a2apb.Task)}}
+	}
+	h.subs[sub] = struct{}{}
+	return sub
+}
+
+// unsubscribe removes sub and cancels the upstream subscription if nobody is left.
+func (h *taskHub) unsubscribe(sub *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[sub]; !ok {
+		return
+	}
+	h.remove(sub)
+}
+
+// remove deletes sub and cancels the upstream subscription if it was the last one. The
+// caller holds h.mu.
+func (h *taskHub) remove(sub *subscriber) {
+	delete(h.subs, sub)
+	if len(h.subs) == 0 && !h.closing {
+		h.closing = true
+		h.cancel()
+	}
+}
+
+// broadcast folds ev into the snapshot and queues it for every subscriber. A subscriber
+// whose buffer is full is dropped rather than holding up the others.
+func (h *taskHub) broadcast(ev *a2apb.StreamResponse) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.snapshot = foldEvent(h.snapshot, ev)
+	for sub := range h.subs {
+		select {
+		case sub.events <- ev:
+		default:
+			sub.err = connect.NewError(connect.CodeResourceExhausted, errors.New("subscriber fell too far behind the task stream"))
+			close(sub.events)
+			h.remove(sub)
+		}
+	}
+}
+
+// finish ends every subscriber with the upstream result.
+func (h *taskHub) finish(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closing = true
+	for sub := range h.subs {
+		sub.err = err
+		close(sub.events)
+		delete(h.subs, sub)
+	}
+}
+
+// brokerInterceptor sends TaskSubscription calls through the broker.
+type brokerInterceptor struct {
+	broker *SubscriptionBroker
+}
+
+func (i brokerInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return next
+}
+
+func (i brokerInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler attaches the caller to the shared subscription for the task.
+// Subscriptions are keyed by task name and agent headers, so callers presenting
+// different credentials never share an upstream call.
+func (i brokerInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		if conn.Spec().Procedure != a2apbconnect.A2AServiceTaskSubscriptionProcedure {
+			return next(ctx, conn)
+		}
+		req := &a2apb.TaskSubscriptionRequest{}
+		if err := conn.Receive(req); err != nil {
+			return err
+		}
+		key := req.GetName() + "\x00" + conn.RequestHeader().Get(agentHeadersHeader)
+		h, sub := i.broker.join(ctx, key, conn, req, next)
+		defer h.unsubscribe(sub)
+
+		for {
+			select {
+			case ev, ok := <-sub.events:
+				if !ok {
+					return sub.err
+				}
+				if err := conn.Send(ev); err != nil {
+					return err
+				}
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
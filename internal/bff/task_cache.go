@@ -0,0 +1,454 @@
+package bff
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/a2aproject/a2a-go/a2apb"
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrTaskNotFound is returned when the task cache has no task with an ID.
+var ErrTaskNotFound = errors.New("task not found")
+
+var tasksBucket = []byte("tasks")
+
+// taskKey is the key of a task in tasksBucket. Task IDs are only unique within an agent,
+// so the key is the agent URL and the ID, separated by a NUL byte.
+func taskKey(agentURL, id string) []byte {
+	return []byte(agentURL + "\x00" + id)
+}
+
+// taskCacheGCInterval is how often the task cache applies its retention policy.
+const taskCacheGCInterval = 10 * time.Minute
+
+// TaskRetention bounds the task cache. A zero field sets no bound.
+type TaskRetention struct {
+	// MaxAge drops tasks that have not been updated for this long.
+	MaxAge time.Duration
+	// MaxTasks keeps only this many tasks, the most recently updated.
+	MaxTasks int
+}
+
+// DefaultTaskRetention keeps tasks for 30 days, and at most 10000 of them.
+var DefaultTaskRetention = TaskRetention{MaxAge: 30 * 24 * time.Hour, MaxTasks: 10000}
+
+// CachedTask is the latest snapshot of a task the BFF has seen.
+type CachedTask struct {
+	ID        string `json:"id"`
+	ContextID string `json:"contextId"`
+	// State is the TaskState name, e.g. TASK_STATE_FAILED.
+	State string `json:"state"`
+	// Skill is the "skillId" or "skill" metadata of the task or of its first message.
+	Skill     string    `json:"skill,omitempty"`
+	AgentURL  string    `json:"agentUrl,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	// Task is the protojson form of the a2a.v1.Task.
+	Task json.RawMessage `json:"task"`
+}
+
+// cachedTaskEntry is how a CachedTask is stored, with its text for searching.
+type cachedTaskEntry struct {
+	CachedTask
+	Text string `json:"text,omitempty"`
+}
+
+// TaskQuery selects cached tasks. Zero fields match every task.
+type TaskQuery struct {
+	AgentURL  string
+	ContextID string
+	States    []a2apb.TaskState
+	Skill     string
+	// Since and Until select tasks that were alive at some point between them: updated
+	// at or after Since, and created at or before Until.
+	Since, Until time.Time
+	// Text is searched for, case-insensitively, in the messages and artifacts.
+	Text string
+	// Limit caps the number of tasks returned; 0 returns all of them.
+	Limit int
+}
+
+// TaskCache keeps the latest snapshot of every task the BFF has seen, across sessions and
+// agents, in a local bbolt database, so tasks can be found without the agent's ListTasks.
+type TaskCache struct {
+	db        *bolt.DB
+	retention TaskRetention
+	stop      chan struct{}
+	stopOnce  sync.Once
+}
+
+// OpenTaskCache opens (or creates) the task database in dir, drops the tasks retention
+// no longer allows, and keeps doing so while it is open.
+func OpenTaskCache(dir string, retention TaskRetention) (*TaskCache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create data dir: %w", err)
+	}
+	db, err := openDB(filepath.Join(dir, "tasks.db"), false)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(tasksBucket)
+		if err != nil {
+			return err
+		}
+		return rekeyTasks(b)
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	c := &TaskCache{db: db, retention: retention, stop: make(chan struct{})}
+	if _, err := c.GC(time.Now()); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	go c.janitor()
+	return c, nil
+}
+
+// rekeyTasks moves tasks stored under their bare ID, as earlier versions did, to their
+// taskKey.
+func rekeyTasks(b *bolt.Bucket) error {
+	var old [][]byte
+	err := b.ForEach(func(k, _ []byte) error {
+		if !bytes.Contains(k, []byte{0}) {
+			old = append(old, slices.Clone(k))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range old {
+		v := b.Get(k)
+		var t CachedTask
+		if err := json.Unmarshal(v, &t); err != nil {
+			return err
+		}
+		if err := b.Put(taskKey(t.AgentURL, t.ID), slices.Clone(v)); err != nil {
+			return err
+		}
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OpenTaskCacheReadOnly opens the task database in dir for searching, alongside other
+// readers. It creates nothing, applies no retention, and fails if there is no database.
+func OpenTaskCacheReadOnly(dir string) (*TaskCache, error) {
+	db, err := openDB(filepath.Join(dir, "tasks.db"), true)
+	if err != nil {
+		return nil, err
+	}
+	return &TaskCache{db: db, stop: make(chan struct{})}, nil
+}
+
+// Close stops the retention loop and closes the database.
+func (c *TaskCache) Close() error {
+	c.stopOnce.Do(func() { close(c.stop) })
+	return c.db.Close()
+}
+
+func (c *TaskCache) janitor() {
+	ticker := time.NewTicker(taskCacheGCInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case now := <-ticker.C:
+			if _, err := c.GC(now); err != nil && !errors.Is(err, bolt.ErrDatabaseNotOpen) {
+				fmt.Fprintf(os.Stderr, "task cache: gc: %v\n", err)
+			}
+		}
+	}
+}
+
+// GC drops the tasks the retention policy no longer allows at now, and returns how many.
+func (c *TaskCache) GC(now time.Time) (int, error) {
+	if c.retention == (TaskRetention{}) {
+		return 0, nil
+	}
+	dropped := 0
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(tasksBucket)
+		type kept struct {
+			id      []byte
+			updated time.Time
+		}
+		var keep []kept
+		var drop [][]byte
+		err := b.ForEach(func(k, v []byte) error {
+			var t CachedTask
+			if err := json.Unmarshal(v, &t); err != nil {
+				return err
+			}
+			if c.retention.MaxAge > 0 && now.Sub(t.UpdatedAt) > c.retention.MaxAge {
+				drop = append(drop, slices.Clone(k))
+			} else {
+				keep = append(keep, kept{slices.Clone(k), t.UpdatedAt})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if c.retention.MaxTasks > 0 && len(keep) > c.retention.MaxTasks {
+			sort.Slice(keep, func(i, j int) bool { return keep[i].updated.After(keep[j].updated) })
+			for _, k := range keep[c.retention.MaxTasks:] {
+				drop = append(drop, k.id)
+			}
+		}
+		for _, k := range drop {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		dropped = len(drop)
+		return nil
+	})
+	return dropped, err
+}
+
+// Get returns the cached task with id from the agent at agentURL.
+func (c *TaskCache) Get(agentURL, id string) (*CachedTask, error) {
+	var entry cachedTaskEntry
+	err := c.db.View(func(tx *bolt.Tx) error {
+		raw := tx.Bucket(tasksBucket).Get(taskKey(agentURL, id))
+		if raw == nil {
+			return ErrTaskNotFound
+		}
+		return json.Unmarshal(raw, &entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &entry.CachedTask, nil
+}
+
+// Delete removes the cached task with id from the agent at agentURL.
+func (c *TaskCache) Delete(agentURL, id string) error {
+	key := taskKey(agentURL, id)
+	return c.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(tasksBucket)
+		if b.Get(key) == nil {
+			return ErrTaskNotFound
+		}
+		return b.Delete(key)
+	})
+}
+
+// Search returns the cached tasks q selects, most recently updated first.
+func (c *TaskCache) Search(q TaskQuery) ([]CachedTask, error) {
+	text := strings.ToLower(q.Text)
+	var states []string
+	for _, s := range q.States {
+		states = append(states, s.String())
+	}
+	var out []CachedTask
+	err := c.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(tasksBucket).ForEach(func(_, v []byte) error {
+			var entry cachedTaskEntry
+			if err := json.Unmarshal(v, &entry); err != nil {
+				return err
+			}
+			t := entry.CachedTask
+			switch {
+			case q.AgentURL != "" && t.AgentURL != q.AgentURL,
+				q.ContextID != "" && t.ContextID != q.ContextID,
+				len(states) > 0 && !slices.Contains(states, t.State),
+				q.Skill != "" && !strings.EqualFold(t.Skill, q.Skill),
+				!q.Since.IsZero() && t.UpdatedAt.Before(q.Since),
+				!q.Until.IsZero() && t.CreatedAt.After(q.Until),
+				text != "" && !strings.Contains(entry.Text, text):
+				return nil
+			}
+			out = append(out, t)
+			return nil
+		})
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].UpdatedAt.After(out[j].UpdatedAt) })
+	if q.Limit > 0 && len(out) > q.Limit {
+		out = out[:q.Limit]
+	}
+	return out, err
+}
+
+// taskUpdate is a change to the cached snapshot of a task, made at Time.
+type taskUpdate struct {
+	ID   string
+	Time time.Time
+	Fn   func(task *a2apb.Task)
+}
+
+// updateAll applies updates to the snapshots of the tasks of the agent at agentURL, in
+// order, and stores the results in one transaction. A snapshot is empty the first time
+// its task is seen, and is decoded and encoded once however many updates it gets.
+func (c *TaskCache) updateAll(agentURL string, updates []taskUpdate) error {
+	type snapshot struct {
+		entry cachedTaskEntry
+		task  *a2apb.Task
+	}
+	return c.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(tasksBucket)
+		snapshots := map[string]*snapshot{}
+		var order []string
+		for _, u := range updates {
+			if u.ID == "" {
+				return errors.New("update cached task: empty id")
+			}
+			s := snapshots[u.ID]
+			if s == nil {
+				s = &snapshot{
+					entry: cachedTaskEntry{CachedTask: CachedTask{ID: u.ID, AgentURL: agentURL, CreatedAt: u.Time}},
+					task:  &a2apb.Task{Id: u.ID},
+				}
+				if raw := b.Get(taskKey(agentURL, u.ID)); raw != nil {
+					if err := json.Unmarshal(raw, &s.entry); err != nil {
+						return err
+					}
+					if err := protojson.Unmarshal(s.entry.Task, s.task); err != nil {
+						return err
+					}
+				}
+				snapshots[u.ID] = s
+				order = append(order, u.ID)
+			}
+			u.Fn(s.task)
+			s.entry.UpdatedAt = u.Time
+		}
+		for _, id := range order {
+			s := snapshots[id]
+			data, err := protojson.Marshal(s.task)
+			if err != nil {
+				return err
+			}
+			s.entry.ContextID = s.task.GetContextId()
+			s.entry.State = s.task.GetStatus().GetState().String()
+			s.entry.Skill = taskSkill(s.task)
+			s.entry.Task = data
+			s.entry.Text = taskText(s.task)
+			raw, err := json.Marshal(s.entry)
+			if err != nil {
+				return err
+			}
+			if err := b.Put(taskKey(agentURL, id), raw); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// mergeTask replaces the snapshot dst with src, keeping the history and artifacts of dst
+// when src has none, as when a task is fetched with a history length of 0.
+func mergeTask(dst, src *a2apb.Task) {
+	history, artifacts, metadata := dst.GetHistory(), dst.GetArtifacts(), dst.GetMetadata()
+	proto.Reset(dst)
+	proto.Merge(dst, src)
+	if len(dst.History) == 0 {
+		dst.History = history
+	}
+	if len(dst.Artifacts) == 0 {
+		dst.Artifacts = artifacts
+	}
+	if dst.Metadata == nil {
+		dst.Metadata = metadata
+	}
+}
+
+// addMessage adds msg to the history of task unless it is there already.
+func addMessage(task *a2apb.Task, msg *a2apb.Message) {
+	if task.ContextId == "" {
+		task.ContextId = msg.GetContextId()
+	}
+	if msg.GetMessageId() != "" && slices.ContainsFunc(task.History, func(m *a2apb.Message) bool { return m.GetMessageId() == msg.GetMessageId() }) {
+		return
+	}
+	task.History = append(task.History, msg)
+}
+
+// taskSkill returns the "skillId" or "skill" metadata of task, or else of its first
+// message that has one. A2A does not say which skill a task uses, but clients and agents
+// often record it there.
+func taskSkill(task *a2apb.Task) string {
+	metadata := []map[string]any{task.GetMetadata().AsMap()}
+	for _, m := range task.GetHistory() {
+		metadata = append(metadata, m.GetMetadata().AsMap())
+	}
+	for _, md := range metadata {
+		for _, key := range []string{"skillId", "skill"} {
+			if s, ok := md[key].(string); ok && s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// taskText returns the lower-case text of the messages and artifacts of task: text
+// parts, file names and URIs, and data parts as JSON.
+func taskText(task *a2apb.Task) string {
+	var sb strings.Builder
+	addParts := func(parts []*a2apb.Part) {
+		for _, p := range parts {
+			sb.WriteString(partText(p) + "\n")
+		}
+	}
+	for _, m := range task.GetHistory() {
+		addParts(m.GetParts())
+	}
+	addParts(task.GetStatus().GetUpdate().GetParts())
+	for _, a := range task.GetArtifacts() {
+		sb.WriteString(a.GetName() + "\n" + a.GetDescription() + "\n")
+		addParts(a.GetParts())
+	}
+	return strings.ToLower(sb.String())
+}
+
+// partText returns the text of p, the name and URI of a file, or the JSON of data.
+func partText(p *a2apb.Part) string {
+	switch {
+	case p.GetText() != "":
+		return p.GetText()
+	case p.GetFile() != nil:
+		return p.GetFile().GetName() + " " + p.GetFile().GetFileWithUri()
+	case p.GetData() != nil:
+		if b, err := protojson.Marshal(p.GetData().GetData()); err == nil {
+			return string(b)
+		}
+	}
+	return ""
+}
+
+// ParseTaskState accepts "failed", "input-required" or "TASK_STATE_FAILED", and
+// "canceled", as A2A JSON spells it, for TASK_STATE_CANCELLED.
+func ParseTaskState(s string) (a2apb.TaskState, error) {
+	name := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(s), "-", "_"))
+	if !strings.HasPrefix(name, "TASK_STATE_") {
+		name = "TASK_STATE_" + name
+	}
+	if name == "TASK_STATE_CANCELED" {
+		name = "TASK_STATE_CANCELLED"
+	}
+	v, ok := a2apb.TaskState_value[name]
+	if !ok {
+		return a2apb.TaskState_TASK_STATE_UNSPECIFIED, fmt.Errorf("unknown task state %q", s)
+	}
+	return a2apb.TaskState(v), nil
+}
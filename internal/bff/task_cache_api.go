@@ -0,0 +1,121 @@
+package bff
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// tasksAPIPath is the prefix of the task cache API.
+const tasksAPIPath = "/api/tasks"
+
+// defaultTaskSearchLimit caps a search that sets no limit.
+const defaultTaskSearchLimit = 100
+
+// registerTaskCacheRoutes mounts the task cache API on r:
+//
+//	GET    /api/tasks?agentUrl=&contextId=&state=failed,canceled&skill=&since=24h&until=&q=text&limit=100
+//	                             search cached tasks, most recently updated first
+//	GET    /api/tasks/{taskId}?agentUrl=   the latest snapshot of a task
+//	DELETE /api/tasks/{taskId}?agentUrl=   forget a task
+//
+// since and until are RFC 3339 times or durations before now. A task is looked up among
+// the tasks of agentUrl, which defaults to agentURL, the agent the BFF proxies.
+func registerTaskCacheRoutes(r *mux.Router, cache *TaskCache, agentURL string) {
+	taskAgent := func(req *http.Request) string {
+		if u := req.URL.Query().Get("agentUrl"); u != "" {
+			return u
+		}
+		return agentURL
+	}
+
+	r.HandleFunc(tasksAPIPath, func(w http.ResponseWriter, req *http.Request) {
+		q, err := ParseTaskQuery(req.URL.Query(), time.Now())
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		tasks, err := cache.Search(q)
+		if err != nil {
+			writeJSONError(w, err)
+			return
+		}
+		if tasks == nil {
+			tasks = []CachedTask{}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"tasks": tasks})
+	}).Methods(http.MethodGet)
+
+	r.HandleFunc(tasksAPIPath+"/{taskId}", func(w http.ResponseWriter, req *http.Request) {
+		task, err := cache.Get(taskAgent(req), mux.Vars(req)["taskId"])
+		if err != nil {
+			writeJSONError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, task)
+	}).Methods(http.MethodGet)
+
+	r.HandleFunc(tasksAPIPath+"/{taskId}", func(w http.ResponseWriter, req *http.Request) {
+		if err := cache.Delete(taskAgent(req), mux.Vars(req)["taskId"]); err != nil {
+			writeJSONError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods(http.MethodDelete)
+}
+
+// ParseTaskQuery reads a TaskQuery from the parameters of /api/tasks: agentUrl, contextId,
+// state (comma-separated, repeatable), skill, since, until, q and limit. Durations in since
+// and until are counted back from now. The limit defaults to 100.
+func ParseTaskQuery(v url.Values, now time.Time) (TaskQuery, error) {
+	q := TaskQuery{
+		AgentURL:  v.Get("agentUrl"),
+		ContextID: v.Get("contextId"),
+		Skill:     v.Get("skill"),
+		Text:      v.Get("q"),
+		Limit:     defaultTaskSearchLimit,
+	}
+	for _, s := range strings.Split(strings.Join(v["state"], ","), ",") {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		state, err := ParseTaskState(s)
+		if err != nil {
+			return q, err
+		}
+		q.States = append(q.States, state)
+	}
+	var err error
+	if q.Since, err = parseTaskTime(v.Get("since"), now); err != nil {
+		return q, fmt.Errorf("since: %w", err)
+	}
+	if q.Until, err = parseTaskTime(v.Get("until"), now); err != nil {
+		return q, fmt.Errorf("until: %w", err)
+	}
+	if s := v.Get("limit"); s != "" {
+		if q.Limit, err = strconv.Atoi(s); err != nil || q.Limit < 0 {
+			return q, fmt.Errorf("limit: %q is not a count", s)
+		}
+	}
+	return q, nil
+}
+
+// parseTaskTime reads an RFC 3339 time, or a duration before now, such as 24h.
+func parseTaskTime(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither an RFC 3339 time nor a duration", s)
+	}
+	return t, nil
+}
@@ -0,0 +1,201 @@
+package bff
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/a2aproject/a2a-go/a2apb"
+	"github.com/alis-exchange/a2a-playground/gen/go/a2apbconnect"
+)
+
+// taskCacheFlushInterval is how long the updates of a stream may wait to be written together.
+const taskCacheFlushInterval = 500 * time.Millisecond
+
+// taskCacheRecorder is a Connect interceptor that keeps a TaskCache up to date.
+type taskCacheRecorder struct {
+	cache    *TaskCache
+	agentURL string
+}
+
+// NewTaskCacheRecorder returns an interceptor that stores every task passing through the
+// proxy in cache: the tasks SendMessage, GetTask, CancelTask and ListTasks return, and the
+// events of message and task streams.
+func NewTaskCacheRecorder(cache *TaskCache, agentURL string) connect.Interceptor {
+	return &taskCacheRecorder{cache: cache, agentURL: agentURL}
+}
+
+// WrapUnary caches the tasks unary calls return.
+func (r *taskCacheRecorder) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		resp, err := next(ctx, req)
+		if err != nil {
+			return resp, err
+		}
+		batch := r.newBatch()
+		defer batch.flush()
+		switch out := resp.Any().(type) {
+		case *a2apb.SendMessageResponse:
+			in, _ := req.Any().(*a2apb.SendMessageRequest)
+			if task := out.GetTask(); task != nil {
+				batch.add(task.GetId(), func(t *a2apb.Task) {
+					mergeTask(t, task)
+					addMessage(t, in.GetRequest())
+				})
+			} else if msg := out.GetMsg(); msg.GetTaskId() != "" {
+				batch.add(msg.GetTaskId(), func(t *a2apb.Task) {
+					addMessage(t, in.GetRequest())
+					addMessage(t, msg)
+				})
+			}
+		case *a2apb.Task:
+			batch.add(out.GetId(), func(t *a2apb.Task) { mergeTask(t, out) })
+		case *a2apb.ListTasksResponse:
+			for _, task := range out.GetTasks() {
+				batch.add(task.GetId(), func(t *a2apb.Task) { mergeTask(t, task) })
+			}
+		}
+		return resp, err
+	}
+}
+
+// WrapStreamingClient is a no-op; the recorder only wraps handlers.
+func (r *taskCacheRecorder) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler applies every event of message and task streams to the cache.
+func (r *taskCacheRecorder) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		switch conn.Spec().Procedure {
+		case a2apbconnect.A2AServiceSendStreamingMessageProcedure, a2apbconnect.A2AServiceTaskSubscriptionProcedure:
+			batch := r.newBatch()
+			defer batch.flush()
+			return next(ctx, &taskCacheConn{StreamingHandlerConn: conn, batch: batch})
+		}
+		return next(ctx, conn)
+	}
+}
+
+func (r *taskCacheRecorder) newBatch() *taskCacheBatch {
+	return &taskCacheBatch{cache: r.cache, agentURL: r.agentURL}
+}
+
+// taskCacheBatch queues the task updates of one call. They are written together when the
+// call ends, and every taskCacheFlushInterval while a stream runs, so that a stream costs
+// a few transactions rather than one per event.
+type taskCacheBatch struct {
+	cache    *TaskCache
+	agentURL string
+
+	mu      sync.Mutex
+	pending []taskUpdate
+	timer   *time.Timer
+
+	// writeMu keeps batches in order without holding mu, so events are never held up
+	// behind a write.
+	writeMu sync.Mutex
+}
+
+// add queues an update of the cached task with id, scheduling a flush if none is due.
+func (b *taskCacheBatch) add(id string, fn func(task *a2apb.Task)) {
+	if id == "" {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending = append(b.pending, taskUpdate{ID: id, Time: time.Now().UTC(), Fn: fn})
+	if b.timer == nil {
+		b.timer = time.AfterFunc(taskCacheFlushInterval, b.flush)
+	}
+}
+
+// flush writes the queued updates; failures are logged so caching never breaks the
+// proxied call.
+func (b *taskCacheBatch) flush() {
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+	b.mu.Lock()
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	pending := b.pending
+	b.pending = nil
+	b.mu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+	if err := b.cache.updateAll(b.agentURL, pending); err != nil {
+		log.Printf("task cache: %v", err)
+	}
+}
+
+// taskCacheConn caches a streaming call. The user message is held until the first event
+// reveals the task it belongs to.
+type taskCacheConn struct {
+	connect.StreamingHandlerConn
+	batch   *taskCacheBatch
+	pending *a2apb.Message
+}
+
+func (c *taskCacheConn) Receive(msg any) error {
+	if err := c.StreamingHandlerConn.Receive(msg); err != nil {
+		return err
+	}
+	if req, ok := msg.(*a2apb.SendMessageRequest); ok {
+		c.pending = req.GetRequest()
+	}
+	return nil
+}
+
+func (c *taskCacheConn) Send(msg any) error {
+	if ev, ok := msg.(*a2apb.StreamResponse); ok {
+		c.apply(ev)
+	}
+	return c.StreamingHandlerConn.Send(msg)
+}
+
+func (c *taskCacheConn) apply(ev *a2apb.StreamResponse) {
+	var id string
+	var fn func(t *a2apb.Task)
+	switch p := ev.GetPayload().(type) {
+	case *a2apb.StreamResponse_Task:
+		id = p.Task.GetId()
+		fn = func(t *a2apb.Task) { mergeTask(t, p.Task) }
+	case *a2apb.StreamResponse_Msg:
+		id = p.Msg.GetTaskId()
+		fn = func(t *a2apb.Task) { addMessage(t, p.Msg) }
+	case *a2apb.StreamResponse_StatusUpdate:
+		id = p.StatusUpdate.GetTaskId()
+		fn = func(t *a2apb.Task) {
+			if t.ContextId == "" {
+				t.ContextId = p.StatusUpdate.GetContextId()
+			}
+			t.Status = p.StatusUpdate.GetStatus()
+		}
+	case *a2apb.StreamResponse_ArtifactUpdate:
+		id = p.ArtifactUpdate.GetTaskId()
+		fn = func(t *a2apb.Task) {
+			if t.ContextId == "" {
+				t.ContextId = p.ArtifactUpdate.GetContextId()
+			}
+			applyArtifactUpdate(t, p.ArtifactUpdate)
+		}
+	default:
+		return
+	}
+	if id == "" {
+		return
+	}
+	pending := c.pending
+	c.pending = nil
+	c.batch.add(id, func(t *a2apb.Task) {
+		fn(t)
+		if pending != nil {
+			addMessage(t, pending)
+		}
+	})
+}
@@ -0,0 +1,184 @@
+package bff
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+const (
+	caValidity   = 10 * 365 * 24 * time.Hour
+	leafValidity = 365 * 24 * time.Hour
+	// leafRenewal is how long before expiry a cached certificate is replaced.
+	leafRenewal = 30 * 24 * time.Hour
+)
+
+// TLSConfig turns on HTTPS, with HTTP/2, using either the given certificate or one the BFF
+// issues itself from a local CA. Nothing is enabled by the zero value.
+type TLSConfig struct {
+	CertFile, KeyFile string
+	// SelfSigned issues a certificate for the names the BFF answers to and Hosts, signed
+	// by a local CA that the browser can be told to trust.
+	SelfSigned bool
+	Hosts      []string
+	// Dir keeps the local CA and issued certificate between runs.
+	Dir string
+}
+
+func (c TLSConfig) enabled() bool {
+	return c.SelfSigned || c.CertFile != "" || c.KeyFile != ""
+}
+
+// validate reports incomplete or conflicting settings.
+func (c TLSConfig) validate() error {
+	switch {
+	case c.SelfSigned && (c.CertFile != "" || c.KeyFile != ""):
+		return errors.New("use either a certificate and key or a self-signed certificate, not both")
+	case (c.CertFile == "") != (c.KeyFile == ""):
+		return errors.New("a certificate needs both a cert file and a key file")
+	case c.SelfSigned && c.Dir == "":
+		return errors.New("a self-signed certificate needs a directory to keep its CA in")
+	}
+	return nil
+}
+
+// CAFile is the local CA certificate, to be trusted by browsers, in dir.
+func CAFile(dir string) string {
+	return filepath.Join(dir, "ca.pem")
+}
+
+// loadCertificate returns the configured certificate, issuing one for hosts in
+// self-signed mode.
+func (c TLSConfig) loadCertificate(hosts []string) (tls.Certificate, error) {
+	if !c.SelfSigned {
+		return tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	}
+	if err := os.MkdirAll(c.Dir, 0o700); err != nil {
+		return tls.Certificate{}, err
+	}
+	ca, caKey, err := loadOrCreateCA(c.Dir)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("local CA: %w", err)
+	}
+	for _, h := range c.Hosts {
+		if !slices.Contains(hosts, h) {
+			hosts = append(hosts, h)
+		}
+	}
+	certFile, keyFile := filepath.Join(c.Dir, "cert.pem"), filepath.Join(c.Dir, "key.pem")
+	if cert, err := tls.LoadX509KeyPair(certFile, keyFile); err == nil && certUsable(cert.Leaf, ca, hosts) {
+		return cert, nil
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: randomSerial(),
+		Subject:      pkix.Name{CommonName: hosts[0], Organization: []string{"a2a-playground"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(leafValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	if err := writePEM(certFile, "CERTIFICATE", der, 0o644); err != nil {
+		return tls.Certificate{}, err
+	}
+	if err := writeKey(keyFile, key); err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.LoadX509KeyPair(certFile, keyFile)
+}
+
+// certUsable reports whether a cached certificate was issued by ca, is not about to
+// expire and covers every host.
+func certUsable(cert, ca *x509.Certificate, hosts []string) bool {
+	if cert == nil || cert.CheckSignatureFrom(ca) != nil || time.Until(cert.NotAfter) < leafRenewal {
+		return false
+	}
+	for _, h := range hosts {
+		if cert.VerifyHostname(h) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// loadOrCreateCA returns the local CA in dir, creating it on first use.
+func loadOrCreateCA(dir string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	certFile, keyFile := CAFile(dir), filepath.Join(dir, "ca-key.pem")
+	if pair, err := tls.LoadX509KeyPair(certFile, keyFile); err == nil {
+		if key, ok := pair.PrivateKey.(*ecdsa.PrivateKey); ok && time.Until(pair.Leaf.NotAfter) > leafValidity {
+			return pair.Leaf, key, nil
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, err
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	host, _ := os.Hostname()
+	tmpl := &x509.Certificate{
+		SerialNumber:          randomSerial(),
+		Subject:               pkix.Name{CommonName: "a2a-playground local CA (" + host + ")", Organization: []string{"a2a-playground"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := writeKey(keyFile, key); err != nil {
+		return nil, nil, err
+	}
+	if err := writePEM(certFile, "CERTIFICATE", der, 0o644); err != nil {
+		return nil, nil, err
+	}
+	ca, err := x509.ParseCertificate(der)
+	return ca, key, err
+}
+
+func randomSerial() *big.Int {
+	n, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	return n
+}
+
+func writeKey(path string, key *ecdsa.PrivateKey) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	return writePEM(path, "PRIVATE KEY", der, 0o600)
+}
+
+func writePEM(path, blockType string, der []byte, perm os.FileMode) error {
+	return os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), perm)
+}
@@ -0,0 +1,203 @@
+package bff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"connectrpc.com/connect"
+	"github.com/a2aproject/a2a-go/a2apb"
+	"github.com/alis-exchange/a2a-playground/gen/go/a2apbconnect"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// ProtocolVersion is a version of the A2A protocol.
+type ProtocolVersion string
+
+const (
+	// VersionAuto asks the agent: one without GetAgentCard, which v1 removed, that
+	// knows GetExtendedAgentCard speaks v1.
+	VersionAuto ProtocolVersion = "auto"
+	// Version03 is the protocol the generated service implements.
+	Version03 ProtocolVersion = "0.3"
+	// Version1 renames TaskSubscription to SubscribeToTask and GetAgentCard to
+	// GetExtendedAgentCard, and changes some messages; see v1Codec.
+	Version1 ProtocolVersion = "1.0"
+)
+
+// ParseProtocolVersion validates an --a2a-version value; "" means Version03.
+func ParseProtocolVersion(s string) (ProtocolVersion, error) {
+	switch s = strings.ToLower(strings.TrimSpace(s)); s {
+	case string(VersionAuto):
+		return VersionAuto, nil
+	case "", "0.3", "0.3.0":
+		return Version03, nil
+	case "1", "1.0", "1.0.0":
+		return Version1, nil
+	}
+	return "", fmt.Errorf("unknown A2A version %q: want auto, 0.3 or 1.0", s)
+}
+
+// versionHeader names the A2A version a client speaks. Its absence means 0.3.
+const versionHeader = "A2A-Version"
+
+// Procedures v1 renamed. The others kept their names.
+const (
+	v1SubscribeToTaskProcedure      = "/" + a2apbconnect.A2AServiceName + "/SubscribeToTask"
+	v1GetExtendedAgentCardProcedure = "/" + a2apbconnect.A2AServiceName + "/GetExtendedAgentCard"
+)
+
+// requestVersion returns the A2A version r was made with: v1 for the v1 method names or an
+// A2A-Version of 1.x, and 0.3 otherwise. It returns "" for versions the BFF does not speak.
+func requestVersion(r *http.Request) ProtocolVersion {
+	if r.URL.Path == v1SubscribeToTaskProcedure || r.URL.Path == v1GetExtendedAgentCardProcedure {
+		return Version1
+	}
+	v := r.Header.Get(versionHeader)
+	if v == "" {
+		return Version03
+	}
+	switch major, _, _ := strings.Cut(strings.TrimSpace(v), "."); major {
+	case "0":
+		return Version03
+	case "1":
+		return Version1
+	}
+	return ""
+}
+
+// routeVersions sends calls from v1 clients to v1 and the others to next.
+func routeVersions(next, v1 http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch requestVersion(r) {
+		case Version1:
+			v1.ServeHTTP(w, r)
+		case Version03:
+			next.ServeHTTP(w, r)
+		default:
+			msg := fmt.Sprintf("A2A version %q is not supported: want 0.3 or 1.0", r.Header.Get(versionHeader))
+			_ = grpcErrors.Write(w, r, connect.NewError(connect.CodeUnimplemented, errors.New(msg)))
+		}
+	})
+}
+
+// newV1Handler serves the A2A service to v1 clients, under both method names, by
+// translating their calls with v1Codec and passing them on through the bridge. Calls are
+// recorded and intercepted under their v0.3 names.
+func newV1Handler(b *bridge) http.Handler {
+	svc := &v1Service{client: b.client}
+	codec := connect.WithCodec(v1Codec{})
+	mux := http.NewServeMux()
+	mux.Handle(a2apbconnect.NewA2AServiceHandler(svc, codec))
+	mux.Handle(v1SubscribeToTaskProcedure, connect.NewServerStreamHandler(v1SubscribeToTaskProcedure, svc.TaskSubscription, codec))
+	mux.Handle(v1GetExtendedAgentCardProcedure, connect.NewUnaryHandler(v1GetExtendedAgentCardProcedure, svc.GetAgentCard, codec))
+	return b.middleware(mux)
+}
+
+// v1Service implements the A2A service for v1 clients on top of the BFF's own.
+type v1Service struct {
+	client a2apbconnect.A2AServiceClient
+}
+
+// Ensure v1Service implements a2apbconnect.A2AServiceHandler.
+var _ a2apbconnect.A2AServiceHandler = (*v1Service)(nil)
+
+// forwardUnary makes an in-process unary call for a v1 client.
+func forwardUnary[Req, Resp any](ctx context.Context, call func(context.Context, *connect.Request[Req]) (*connect.Response[Resp], error), req *connect.Request[Req]) (*connect.Response[Resp], error) {
+	msg, err := callUnary(ctx, call, req.Msg)
+	if err != nil {
+		return nil, relayError(err)
+	}
+	return connect.NewResponse(msg), nil
+}
+
+// forwardStream makes an in-process streaming call for a v1 client.
+func forwardStream[Req any](ctx context.Context, call func(context.Context, *connect.Request[Req]) (*connect.ServerStreamForClient[a2apb.StreamResponse], error), req *connect.Request[Req], stream *connect.ServerStream[a2apb.StreamResponse]) error {
+	events, err := call(ctx, connect.NewRequest(req.Msg))
+	if err != nil {
+		return relayError(err)
+	}
+	defer events.Close()
+	// Headers arrive with the first event, and must be copied before it is sent on.
+	copied := false
+	for events.Receive() {
+		if !copied {
+			bridgeCallFromContext(ctx).copyResponse(events.ResponseHeader(), nil)
+			copied = true
+		}
+		if err := stream.Send(events.Msg()); err != nil {
+			return err
+		}
+	}
+	if !copied {
+		bridgeCallFromContext(ctx).copyResponse(events.ResponseHeader(), nil)
+	}
+	for name, values := range events.ResponseTrailer() {
+		if !matchesAny(framingHeaders, name) && !matchesAny(unbridgedResponseHeaders, name) {
+			stream.ResponseTrailer()[name] = values
+		}
+	}
+	return relayError(events.Err())
+}
+
+// relayError drops the metadata of an in-process call's error, which forwardUnary and
+// forwardStream copy themselves.
+func relayError(err error) error {
+	var cerr *connect.Error
+	if !errors.As(err, &cerr) {
+		return err
+	}
+	out := connect.NewError(cerr.Code(), errors.New(cerr.Message()))
+	for _, d := range cerr.Details() {
+		out.AddDetail(d)
+	}
+	return out
+}
+
+func (s *v1Service) SendMessage(ctx context.Context, req *connect.Request[a2apb.SendMessageRequest]) (*connect.Response[a2apb.SendMessageResponse], error) {
+	return forwardUnary(ctx, s.client.SendMessage, req)
+}
+
+func (s *v1Service) SendStreamingMessage(ctx context.Context, req *connect.Request[a2apb.SendMessageRequest], stream *connect.ServerStream[a2apb.StreamResponse]) error {
+	return forwardStream(ctx, s.client.SendStreamingMessage, req, stream)
+}
+
+func (s *v1Service) GetTask(ctx context.Context, req *connect.Request[a2apb.GetTaskRequest]) (*connect.Response[a2apb.Task], error) {
+	return forwardUnary(ctx, s.client.GetTask, req)
+}
+
+func (s *v1Service) ListTasks(ctx context.Context, req *connect.Request[a2apb.ListTasksRequest]) (*connect.Response[a2apb.ListTasksResponse], error) {
+	return forwardUnary(ctx, s.client.ListTasks, req)
+}
+
+func (s *v1Service) CancelTask(ctx context.Context, req *connect.Request[a2apb.CancelTaskRequest]) (*connect.Response[a2apb.Task], error) {
+	return forwardUnary(ctx, s.client.CancelTask, req)
+}
+
+// TaskSubscription serves SubscribeToTask, and TaskSubscription for v1 clients that use the old name.
+func (s *v1Service) TaskSubscription(ctx context.Context, req *connect.Request[a2apb.TaskSubscriptionRequest], stream *connect.ServerStream[a2apb.StreamResponse]) error {
+	return forwardStream(ctx, s.client.TaskSubscription, req, stream)
+}
+
+func (s *v1Service) CreateTaskPushNotificationConfig(ctx context.Context, req *connect.Request[a2apb.CreateTaskPushNotificationConfigRequest]) (*connect.Response[a2apb.TaskPushNotificationConfig], error) {
+	return forwardUnary(ctx, s.client.CreateTaskPushNotificationConfig, req)
+}
+
+func (s *v1Service) GetTaskPushNotificationConfig(ctx context.Context, req *connect.Request[a2apb.GetTaskPushNotificationConfigRequest]) (*connect.Response[a2apb.TaskPushNotificationConfig], error) {
+	return forwardUnary(ctx, s.client.GetTaskPushNotificationConfig, req)
+}
+
+func (s *v1Service) ListTaskPushNotificationConfig(ctx context.Context, req *connect.Request[a2apb.ListTaskPushNotificationConfigRequest]) (*connect.Response[a2apb.ListTaskPushNotificationConfigResponse], error) {
+	return forwardUnary(ctx, s.client.ListTaskPushNotificationConfig, req)
+}
+
+func (s *v1Service) DeleteTaskPushNotificationConfig(ctx context.Context, req *connect.Request[a2apb.DeleteTaskPushNotificationConfigRequest]) (*connect.Response[emptypb.Empty], error) {
+	return forwardUnary(ctx, s.client.DeleteTaskPushNotificationConfig, req)
+}
+
+// GetAgentCard serves GetExtendedAgentCard, and GetAgentCard for v1 clients that use the old name.
+func (s *v1Service) GetAgentCard(ctx context.Context, req *connect.Request[a2apb.GetAgentCardRequest]) (*connect.Response[a2apb.AgentCard], error) {
+	return forwardUnary(ctx, s.client.GetAgentCard, req)
+}
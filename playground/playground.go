@@ -0,0 +1,248 @@
+// Package playground serves the A2A playground from an agent's own HTTP server, so an
+// agent binary can offer it without running a2a-playground beside it:
+//
+//	handler := a2asrv.NewHandler(executor, a2asrv.WithExtendedAgentCard(card))
+//	mux := http.NewServeMux()
+//	pg, err := playground.Mount(mux, playground.Options{Handler: handler})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer pg.Close()
+//	log.Fatal(http.ListenAndServe("localhost:8080", mux))
+//
+// The playground is then at http://localhost:8080/playground/.
+package playground
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+
+	"github.com/a2aproject/a2a-go/a2agrpc"
+	"github.com/a2aproject/a2a-go/a2apb"
+	"github.com/a2aproject/a2a-go/a2asrv"
+	"github.com/alis-exchange/a2a-playground/internal/bff"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// DefaultPrefix is the path the playground is served under by default.
+const DefaultPrefix = "/playground"
+
+// inProcessAgent names an agent given as a Handler, Client or Conn in sessions and the
+// task cache.
+const inProcessAgent = "in-process"
+
+// Protocol is the transport of an agent at Options.AgentURL.
+type Protocol = bff.Protocol
+
+const (
+	ProtocolGRPC    = bff.ProtocolGRPC
+	ProtocolJSONRPC = bff.ProtocolJSONRPC
+)
+
+// AccessConfig controls who may use the playground; see Options.Access.
+type AccessConfig = bff.AccessConfig
+
+// CallInterceptor runs around every call to the agent and every event it streams; see
+// Options.Interceptors.
+type CallInterceptor = bff.CallInterceptor
+
+// Call describes the call a CallInterceptor runs around.
+type Call = bff.Call
+
+// CallInterceptorFuncs is a CallInterceptor made of functions, any of which may be nil.
+type CallInterceptorFuncs = bff.CallInterceptorFuncs
+
+// AuthMode selects how the playground authenticates browsers.
+type AuthMode = bff.AuthMode
+
+const (
+	AuthNone  = bff.AuthNone
+	AuthToken = bff.AuthToken
+	AuthBasic = bff.AuthBasic
+	AuthProxy = bff.AuthProxy
+)
+
+// Options configure an embedded playground. Exactly one of Handler, Client, Conn and
+// AgentURL says which agent it talks to.
+type Options struct {
+	// Prefix is the path the playground is served under. It defaults to DefaultPrefix;
+	// "/" serves it at the root.
+	Prefix string
+
+	// Handler is an agent in the same process, served to the playground over an
+	// in-memory gRPC connection.
+	Handler a2asrv.RequestHandler
+	// Client is a gRPC client of the agent. It speaks A2A 0.3.
+	Client a2apb.A2AServiceClient
+	// Conn is a gRPC connection to the agent.
+	Conn grpc.ClientConnInterface
+	// AgentURL is the address of an agent in another process, spoken to with Protocol.
+	AgentURL string
+	Protocol Protocol
+
+	// AgentCardURL is the URL of the agent's public card. Without it, the card is the
+	// extended card, which an a2asrv handler has with a2asrv.WithExtendedAgentCard.
+	AgentCardURL string
+	// Extensions are A2A extension URIs activated on every call.
+	Extensions []string
+	// DataDir keeps sessions, the task cache and uploaded files. None of them are kept
+	// when it is empty.
+	DataDir string
+	// PublicURL is the playground's URL as the agent can reach it, prefix included, such
+	// as "http://localhost:8080/playground". It is needed to pass uploads by URI.
+	PublicURL string
+	// Access authenticates users and guards against DNS rebinding and cross-site
+	// requests. Only loopback names are accepted as the Host unless AllowedHosts adds
+	// others.
+	Access AccessConfig
+	// Interceptors customize the calls to the agent, first to last on requests and last
+	// to first on responses and events.
+	Interceptors []CallInterceptor
+}
+
+// Playground serves the playground SPA, and the BFF that proxies it to the agent, under
+// its prefix.
+type Playground struct {
+	server *bff.Server
+	prefix string
+	// closers release the in-process agent connection.
+	closers []func() error
+}
+
+// Handler returns a playground for opts. It serves the paths under opts.Prefix, and is
+// meant to be registered on a mux for them; Mount does both.
+func Handler(opts Options) (*Playground, error) {
+	prefix, err := bff.NormalizeBasePath(opts.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Prefix == "" {
+		prefix = DefaultPrefix
+	}
+	p := &Playground{prefix: prefix}
+	proxy, agentURL, err := p.proxy(opts)
+	if err != nil {
+		return nil, errors.Join(err, p.closeAgent())
+	}
+	cfg := bff.ServerConfig{
+		BasePath:       prefix,
+		AgentURL:       agentURL,
+		Proxy:          proxy,
+		AgentCardURL:   opts.AgentCardURL,
+		Extensions:     opts.Extensions,
+		PublicURL:      opts.PublicURL,
+		Access:         opts.Access,
+		Interceptors:   opts.Interceptors,
+		FileParts:      bff.FilePartsBytes,
+		ForwardHeaders: bff.ResponseHeaderPolicy{Prefix: bff.DefaultForwardHeaderPrefix, Allow: bff.DefaultForwardHeaders},
+	}
+	if opts.DataDir != "" {
+		cfg.DataDir = opts.DataDir
+		cfg.TasksDir = opts.DataDir
+		cfg.TaskRetention = bff.DefaultTaskRetention
+		cfg.FilesDir = filepath.Join(opts.DataDir, "files")
+	}
+	if p.server, err = bff.NewServer(context.Background(), cfg); err != nil {
+		return nil, errors.Join(err, p.closeAgent())
+	}
+	return p, nil
+}
+
+// Mount serves a playground for opts on mux, under opts.Prefix.
+func Mount(mux *http.ServeMux, opts Options) (*Playground, error) {
+	p, err := Handler(opts)
+	if err != nil {
+		return nil, err
+	}
+	mux.Handle(p.Pattern(), p)
+	return p, nil
+}
+
+// Pattern is the http.ServeMux pattern of the paths the playground serves, such as
+// "/playground/".
+func (p *Playground) Pattern() string {
+	return p.prefix + "/"
+}
+
+// ServeHTTP serves the playground.
+func (p *Playground) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.server.Handler().ServeHTTP(w, r)
+}
+
+// Close ends the playground's streams and closes its stores and the in-process agent
+// connection.
+func (p *Playground) Close() error {
+	return errors.Join(p.server.Shutdown(context.Background()), p.closeAgent())
+}
+
+// proxy returns the BFF proxy for the agent in opts, and the URL that names it.
+func (p *Playground) proxy(opts Options) (bff.A2AServiceHandler, string, error) {
+	agent := bff.AgentConfig{URL: inProcessAgent, CardURL: opts.AgentCardURL}
+	given := 0
+	for _, set := range []bool{opts.Handler != nil, opts.Client != nil, opts.Conn != nil, opts.AgentURL != ""} {
+		if set {
+			given++
+		}
+	}
+	if given != 1 {
+		return nil, "", errors.New("playground: set exactly one of Handler, Client, Conn and AgentURL")
+	}
+	switch {
+	case opts.Handler != nil:
+		conn, err := p.serveInProcess(opts.Handler)
+		if err != nil {
+			return nil, "", err
+		}
+		agent.Conn = conn
+		agent.Version = bff.Version03
+	case opts.Client != nil:
+		agent.Client = opts.Client
+	case opts.Conn != nil:
+		agent.Conn = opts.Conn
+	default:
+		agent.URL = opts.AgentURL
+		agent.Protocol = opts.Protocol
+	}
+	return bff.NewProxy(agent), agent.URL, nil
+}
+
+// serveInProcess serves h over gRPC on an in-memory listener and connects to it.
+func (p *Playground) serveInProcess(h a2asrv.RequestHandler) (*grpc.ClientConn, error) {
+	ln := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	a2agrpc.NewHandler(h).RegisterWith(srv)
+	go func() {
+		_ = srv.Serve(ln)
+	}()
+	p.closers = append(p.closers, func() error {
+		srv.Stop()
+		return nil
+	})
+	conn, err := grpc.NewClient("passthrough:///"+inProcessAgent,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return ln.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("playground: connect to the in-process agent: %w", err)
+	}
+	p.closers = append(p.closers, conn.Close)
+	return conn, nil
+}
+
+// closeAgent closes the in-process agent connection and server, last opened first.
+func (p *Playground) closeAgent() error {
+	var err error
+	for i := len(p.closers) - 1; i >= 0; i-- {
+		err = errors.Join(err, p.closers[i]())
+	}
+	p.closers = nil
+	return err
+}